@@ -0,0 +1,66 @@
+package gomongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Backend is the storage a Client works against. NewClient uses a MongoDB server. The memdb package
+// has an in-memory backend, so code written against gomongo can be tested without a database.
+type Backend interface {
+	Collection(database string, name string) (Collection, error)
+}
+
+// Collection holds the collection operations used by gomongo. The CRUD methods have the signatures of *mongo.Collection.
+type Collection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error)
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error)
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+
+	CreateIndex(ctx context.Context, model mongo.IndexModel) (string, error)
+	DropIndex(ctx context.Context, name string, opts ...*options.DropIndexesOptions) (bson.Raw, error)
+	DropAllIndexes(ctx context.Context, opts ...*options.DropIndexesOptions) (bson.Raw, error)
+	ListIndexes(ctx context.Context, opts ...*options.ListIndexesOptions) (*mongo.Cursor, error)
+}
+
+// NewClientWithBackend return a client that runs its operations on backend instead of a MongoDB server.
+// RunCommandSync and the explain functions need a server and fail with ErrNotSupported on such a client.
+func NewClientWithBackend(database string, backend Backend, connTimeout ...time.Duration) *Client {
+	ret := NewClient("", database, connTimeout...)
+	ret.backend = backend
+	return ret
+}
+
+// driverCollection adapts *mongo.Collection to Collection
+type driverCollection struct {
+	*mongo.Collection
+}
+
+func (dc driverCollection) CreateIndex(ctx context.Context, model mongo.IndexModel) (string, error) {
+	return dc.Indexes().CreateOne(ctx, model)
+}
+
+func (dc driverCollection) DropIndex(ctx context.Context, name string, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	return dc.Indexes().DropOne(ctx, name, opts...)
+}
+
+func (dc driverCollection) DropAllIndexes(ctx context.Context, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	return dc.Indexes().DropAll(ctx, opts...)
+}
+
+func (dc driverCollection) ListIndexes(ctx context.Context, opts ...*options.ListIndexesOptions) (*mongo.Cursor, error) {
+	return dc.Indexes().List(ctx, opts...)
+}
@@ -0,0 +1,226 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Batch queue operations to run together and collect their results later, instead of draining the channels of
+// several async calls by hand. Generic operations are added with the Batch functions, the others with the methods.
+//
+//	b := gomongo.NewBatch(gmc)
+//	user := gomongo.BatchFindOne[User](b, "users", bson.M{"_id": id})
+//	orders := b.CountDocuments("orders", bson.M{"user_id": id})
+//	if res := b.Run(ctx, 4); res.Err != nil {
+//		return res.Err
+//	}
+//	fmt.Println(user.Result().Document.Name, orders.Result().Count)
+type Batch struct {
+	c   *Client
+	mu  sync.Mutex
+	ops []batchOp
+}
+
+type batchOp struct {
+	name       string
+	collection string
+	// run execute the operation with c and return its error
+	run func(c *Client) error
+	// fail set the result of an operation that was not started
+	fail func(err error)
+}
+
+// Handle is the result of an operation added to a Batch. It is set once Run returns.
+type Handle[T any] struct {
+	value T
+}
+
+// Result return the result of the operation. It is the zero value before Run.
+func (h *Handle[T]) Result() T {
+	return h.value
+}
+
+// BatchError is an operation of a Batch that failed
+type BatchError struct {
+	// Index is the position of the operation in the batch
+	Index      int
+	Op         string
+	Collection string
+	Err        error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("%s %s #%d: %v", e.Op, e.Collection, e.Index, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchResult is the combined report of Run
+type BatchResult struct {
+	Succeeded int
+	// Errors are the failed operations, in the order they were added
+	Errors []BatchError
+	// Err joins Errors, nil when every operation succeeded
+	Err error
+}
+
+// NewBatch return an empty batch of operations on c
+func NewBatch(c *Client) *Batch {
+	return &Batch{c: c}
+}
+
+// Len return the number of operations waiting for Run
+func (b *Batch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.ops)
+}
+
+// Run execute the queued operations under ctx, at most concurrency at a time (all at once when it is 0 or less), and
+// wait for them to end. Operations not started when ctx is done fail with its error. The limits of SetMaxInFlight
+// apply. The batch is empty after Run and can be reused.
+func (b *Batch) Run(ctx context.Context, concurrency int) BatchResult {
+	b.mu.Lock()
+	ops := b.ops
+	b.ops = nil
+	b.mu.Unlock()
+
+	if concurrency <= 0 || concurrency > len(ops) {
+		concurrency = len(ops)
+	}
+	c := b.c.WithContext(ctx)
+	errs := make([]error, len(ops))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, op := range ops {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			errs[i] = ctx.Err()
+			op.fail(errs[i])
+			continue
+		}
+		release, err := c.acquire(op.name)
+		if err != nil {
+			<-slots
+			errs[i] = err
+			op.fail(err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer release()
+			errs[i] = op.run(c)
+		}()
+	}
+	wg.Wait()
+
+	var ret BatchResult
+	var joined []error
+	for i, err := range errs {
+		if err == nil {
+			ret.Succeeded++
+			continue
+		}
+		be := BatchError{Index: i, Op: ops[i].name, Collection: ops[i].collection, Err: err}
+		ret.Errors = append(ret.Errors, be)
+		joined = append(joined, be)
+	}
+	ret.Err = errors.Join(joined...)
+	return ret
+}
+
+// addBatch queue fn as an opName operation and return the handle of its result
+func addBatch[R any, P result[R]](b *Batch, opName string, collName string, fn func(c *Client) R) *Handle[R] {
+	h := &Handle[R]{}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ops = append(b.ops, batchOp{
+		name:       opName,
+		collection: collName,
+		run: func(c *Client) error {
+			h.value = fn(c)
+			return P(&h.value).err()
+		},
+		fail: func(err error) {
+			h.value = withErr[R, P](err)
+		},
+	})
+	return h
+}
+
+// BatchFindOne add a FindOne to b
+func BatchFindOne[T any](b *Batch, collName string, filter interface{}, opts ...*options.FindOneOptions) *Handle[ReadOneResult[T]] {
+	return addBatch(b, OpFindOne, collName, func(c *Client) ReadOneResult[T] { return FindOneSync[T](c, collName, filter, opts...) })
+}
+
+// BatchFind add a Find to b
+func BatchFind[T any](b *Batch, collName string, filter interface{}, opts ...*options.FindOptions) *Handle[ReadManyResult[T]] {
+	return addBatch(b, OpFind, collName, func(c *Client) ReadManyResult[T] { return FindSync[T](c, collName, filter, opts...) })
+}
+
+// BatchDistinct add a Distinct to b
+func BatchDistinct[T any](b *Batch, collName string, fieldName string, filter interface{}, opts ...*options.DistinctOptions) *Handle[DistinctResult[T]] {
+	return addBatch(b, OpDistinct, collName, func(c *Client) DistinctResult[T] { return DistinctSync[T](c, collName, fieldName, filter, opts...) })
+}
+
+// BatchInsertMany add an InsertMany to b
+func BatchInsertMany[T any](b *Batch, collName string, documents []T, opts ...*options.InsertManyOptions) *Handle[WriteManyResult] {
+	return addBatch(b, OpInsertMany, collName, func(c *Client) WriteManyResult { return InsertManySync(c, collName, documents, opts...) })
+}
+
+// CountDocuments add a CountDocuments to b
+func (b *Batch) CountDocuments(collName string, filter interface{}, opts ...*options.CountOptions) *Handle[CountResult] {
+	return addBatch(b, OpCountDocuments, collName, func(c *Client) CountResult { return CountDocumentsSync(c, collName, filter, opts...) })
+}
+
+// InsertOne add an InsertOne to b
+func (b *Batch) InsertOne(collName string, document interface{}, opts ...*options.InsertOneOptions) *Handle[WriteOneResult] {
+	return addBatch(b, OpInsertOne, collName, func(c *Client) WriteOneResult { return InsertOneSync(c, collName, document, opts...) })
+}
+
+// UpdateOne add an UpdateOne to b
+func (b *Batch) UpdateOne(collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) *Handle[UpdateResult] {
+	return addBatch(b, OpUpdateOne, collName, func(c *Client) UpdateResult { return UpdateOneSync(c, collName, filter, instruction, opts...) })
+}
+
+// UpdateMany add an UpdateMany to b
+func (b *Batch) UpdateMany(collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) *Handle[UpdateResult] {
+	return addBatch(b, OpUpdateMany, collName, func(c *Client) UpdateResult { return UpdateManySync(c, collName, filter, instruction, opts...) })
+}
+
+// ReplaceOne add a ReplaceOne to b
+func (b *Batch) ReplaceOne(collName string, filter interface{}, document interface{}, opts ...*options.ReplaceOptions) *Handle[UpdateResult] {
+	return addBatch(b, OpReplaceOne, collName, func(c *Client) UpdateResult { return ReplaceOneSync(c, collName, filter, document, opts...) })
+}
+
+// BulkWrite add a BulkWrite to b
+func (b *Batch) BulkWrite(collName string, writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) *Handle[BulkWriteResult] {
+	return addBatch(b, OpBulkWrite, collName, func(c *Client) BulkWriteResult { return BulkWriteSync(c, collName, writeModels, opts...) })
+}
+
+// DeleteOne add a DeleteOne to b
+func (b *Batch) DeleteOne(collName string, filter interface{}, opts ...*options.DeleteOptions) *Handle[DeleteResult] {
+	return addBatch(b, OpDeleteOne, collName, func(c *Client) DeleteResult { return DeleteOneSync(c, collName, filter, opts...) })
+}
+
+// DeleteMany add a DeleteMany to b
+func (b *Batch) DeleteMany(collName string, filter interface{}, opts ...*options.DeleteOptions) *Handle[DeleteResult] {
+	return addBatch(b, OpDeleteMany, collName, func(c *Client) DeleteResult { return DeleteManySync(c, collName, filter, opts...) })
+}
+
+// RunCommand add a RunCommand to b
+func (b *Batch) RunCommand(cmd interface{}, opts ...*options.RunCmdOptions) *Handle[CommandResult] {
+	return addBatch(b, OpRunCommand, "", func(c *Client) CommandResult { return RunCommandSync(c, cmd, opts...) })
+}
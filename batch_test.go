@@ -0,0 +1,54 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGomongoBatch(t *testing.T) {
+	c := cursorClient(t, 10)
+	ctx := context.Background()
+
+	b := gomongo.NewBatch(c)
+	one := gomongo.BatchFindOne[cursorDoc](b, "docs", bson.M{"_id": 3})
+	many := gomongo.BatchFind[cursorDoc](b, "docs", bson.M{"_id": bson.M{"$lt": 4}})
+	count := b.CountDocuments("docs", bson.M{})
+	dup := b.InsertOne("docs", bson.M{"_id": 1})
+	inserted := b.InsertOne("docs", bson.M{"_id": 100})
+	if b.Len() != 5 {
+		t.Fatalf("expected 5 queued operations, got %d", b.Len())
+	}
+
+	res := b.Run(ctx, 2)
+	if res.Succeeded != 4 || len(res.Errors) != 1 || b.Len() != 0 {
+		t.Fatalf("unexpected report %+v", res)
+	}
+	if e := res.Errors[0]; e.Index != 3 || e.Op != gomongo.OpInsertOne || e.Collection != "docs" || !errors.Is(res.Err, gomongo.ErrDuplicateKey) {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	if !errors.Is(dup.Result().Err, gomongo.ErrDuplicateKey) || inserted.Result().Err != nil {
+		t.Fatalf("unexpected insert results %+v %+v", dup.Result(), inserted.Result())
+	}
+	if one.Result().Document.ID != 3 || len(many.Result().Documents) != 4 {
+		t.Fatalf("unexpected find results %+v %+v", one.Result(), many.Result())
+	}
+	if n := count.Result().Count; n != 10 && n != 11 {
+		t.Fatalf("unexpected count %d", n)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	late := b.CountDocuments("docs", bson.M{})
+	b.DeleteMany("docs", bson.M{})
+	res = b.Run(cancelled, 1)
+	if res.Succeeded != 0 || len(res.Errors) != 2 || !errors.Is(late.Result().Err, context.Canceled) {
+		t.Fatalf("expected the operations not to start, got %+v", res)
+	}
+	if r := gomongo.CountDocumentsSync(c, "docs", bson.M{}); r.Count != 11 {
+		t.Fatalf("expected the documents to stay, got %d", r.Count)
+	}
+}
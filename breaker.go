@@ -0,0 +1,251 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitWindow      = 10 * time.Second
+	DefaultCircuitMinCalls    = 20
+	DefaultCircuitErrorRate   = 0.5
+	DefaultCircuitSlowRate    = 0.5
+	DefaultCircuitOpenTimeout = 5 * time.Second
+	DefaultCircuitProbes      = 1
+)
+
+// ErrCircuitOpen is the error of the operations rejected while the circuit breaker is open or half open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed let operations run
+	CircuitClosed CircuitState = iota
+	// CircuitOpen reject operations with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen reject operations while probes check the database is back
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerOptions of SetCircuitBreaker
+type CircuitBreakerOptions struct {
+	// Window is the period over which calls are counted. Default is DefaultCircuitWindow.
+	Window time.Duration
+	// MinCalls is the number of calls in a window before the circuit can trip. Default is DefaultCircuitMinCalls.
+	MinCalls int
+	// ErrorRate trip the circuit when this part of the calls of a window fail. Default is DefaultCircuitErrorRate, a
+	// negative value disables it.
+	ErrorRate float64
+	// SlowCall is the duration above which a call is slow. Zero does not measure latency.
+	SlowCall time.Duration
+	// SlowRate trip the circuit when this part of the calls of a window are slow. Default is DefaultCircuitSlowRate.
+	SlowRate float64
+	// OpenTimeout is how long the circuit stays open before it is half open and probes run. Default is
+	// DefaultCircuitOpenTimeout.
+	OpenTimeout time.Duration
+	// Probes is the number of probes that must succeed in a row to close the circuit. Default is DefaultCircuitProbes.
+	Probes int
+	// Probe check the database is back. Default pings the server. It must not use the operations of the client, they
+	// are rejected until the circuit closes. ctx is done after OpenTimeout, or when the client disconnects.
+	Probe func(ctx context.Context) error
+	// IsFailure tell if the error of an operation counts as a failure. Default is IsRetryable, so errors caused by
+	// the request, such as a duplicate key, do not trip the circuit.
+	IsFailure func(err error) bool
+	// PerCollection keep a circuit for each collection instead of one for the client
+	PerCollection bool
+	// OnStateChange is called when a circuit changes state. collName is empty for the circuit of the client.
+	OnStateChange func(collName string, from CircuitState, to CircuitState)
+}
+
+type circuitBreaker struct {
+	opt CircuitBreakerOptions
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+	// ctx is canceled by reset, to end the probes scheduled before
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type circuit struct {
+	state       CircuitState
+	windowStart time.Time
+	calls       int
+	failures    int
+	slow        int
+}
+
+// SetCircuitBreaker make the operations of the client fail fast with ErrCircuitOpen once too many of them fail or
+// are slow, until probes find the database is back
+func (c *Client) SetCircuitBreaker(opts CircuitBreakerOptions) *Client {
+	if opts.Window <= 0 {
+		opts.Window = DefaultCircuitWindow
+	}
+	if opts.MinCalls <= 0 {
+		opts.MinCalls = DefaultCircuitMinCalls
+	}
+	if opts.ErrorRate == 0 {
+		opts.ErrorRate = DefaultCircuitErrorRate
+	}
+	if opts.SlowRate <= 0 {
+		opts.SlowRate = DefaultCircuitSlowRate
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = DefaultCircuitOpenTimeout
+	}
+	if opts.Probes <= 0 {
+		opts.Probes = DefaultCircuitProbes
+	}
+	if opts.Probe == nil {
+		opts.Probe = c.ping
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = IsRetryable
+	}
+	if c.breaker != nil {
+		c.breaker.reset()
+	}
+	c.breaker = &circuitBreaker{opt: opts, circuits: map[string]*circuit{}}
+	c.breaker.ctx, c.breaker.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+// CircuitState return the state of the circuit breaker of collName, or of the client when it is not per collection.
+// It is CircuitClosed when the client has no circuit breaker.
+func (c *Client) CircuitState(collName string) CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	b := c.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cir, ok := b.circuits[b.key(collName)]; ok {
+		return cir.state
+	}
+	return CircuitClosed
+}
+
+func (b *circuitBreaker) key(collName string) string {
+	if b.opt.PerCollection {
+		return collName
+	}
+	return ""
+}
+
+// allow tell if an operation on collName can run
+func (b *circuitBreaker) allow(collName string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cir, ok := b.circuits[b.key(collName)]
+	return !ok || cir.state == CircuitClosed
+}
+
+// record count an operation on collName, and trip its circuit when the thresholds are reached
+func (b *circuitBreaker) record(collName string, d time.Duration, err error) {
+	key := b.key(collName)
+	now := time.Now()
+
+	b.mu.Lock()
+	cir, ok := b.circuits[key]
+	if !ok {
+		cir = &circuit{windowStart: now}
+		b.circuits[key] = cir
+	}
+	if cir.state != CircuitClosed {
+		b.mu.Unlock()
+		return
+	}
+	if now.Sub(cir.windowStart) > b.opt.Window {
+		cir.windowStart, cir.calls, cir.failures, cir.slow = now, 0, 0, 0
+	}
+	cir.calls++
+	if err != nil && b.opt.IsFailure(err) {
+		cir.failures++
+	}
+	if b.opt.SlowCall > 0 && d >= b.opt.SlowCall {
+		cir.slow++
+	}
+	ctx := b.ctx
+	trip := cir.calls >= b.opt.MinCalls &&
+		((b.opt.ErrorRate > 0 && float64(cir.failures) >= b.opt.ErrorRate*float64(cir.calls)) ||
+			(b.opt.SlowCall > 0 && float64(cir.slow) >= b.opt.SlowRate*float64(cir.calls)))
+	if trip {
+		cir.state = CircuitOpen
+	}
+	b.mu.Unlock()
+
+	if trip {
+		b.changed(key, CircuitClosed, CircuitOpen)
+		time.AfterFunc(b.opt.OpenTimeout, func() { b.probe(ctx, key) })
+	}
+}
+
+// probe move the circuit of key to half open and run the probes, to close it or open it again. It stops once ctx
+// is done.
+func (b *circuitBreaker) probe(ctx context.Context, key string) {
+	if !b.set(ctx, key, CircuitHalfOpen) {
+		return
+	}
+	for i := 0; i < b.opt.Probes; i++ {
+		probeCtx, cancel := context.WithTimeout(ctx, b.opt.OpenTimeout)
+		err := b.opt.Probe(probeCtx)
+		cancel()
+		if err != nil {
+			if b.set(ctx, key, CircuitOpen) {
+				time.AfterFunc(b.opt.OpenTimeout, func() { b.probe(ctx, key) })
+			}
+			return
+		}
+	}
+	b.set(ctx, key, CircuitClosed)
+}
+
+// reset cancel the scheduled probes and forget the circuits, so they are closed
+func (b *circuitBreaker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cancel()
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	b.circuits = map[string]*circuit{}
+}
+
+// set the state of the circuit of key, unless ctx is done because the breaker was reset since the probe was
+// scheduled. It tell if the state was set.
+func (b *circuitBreaker) set(ctx context.Context, key string, state CircuitState) bool {
+	b.mu.Lock()
+	if ctx.Err() != nil {
+		b.mu.Unlock()
+		return false
+	}
+	cir := b.circuits[key]
+	from := cir.state
+	cir.state = state
+	if state == CircuitClosed {
+		cir.windowStart, cir.calls, cir.failures, cir.slow = time.Now(), 0, 0, 0
+	}
+	b.mu.Unlock()
+	b.changed(key, from, state)
+	return true
+}
+
+func (b *circuitBreaker) changed(key string, from CircuitState, to CircuitState) {
+	if b.opt.OnStateChange != nil && from != to {
+		b.opt.OnStateChange(key, from, to)
+	}
+}
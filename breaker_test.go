@@ -0,0 +1,128 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func runResult(c *Client, collName string, err error, d time.Duration) error {
+	return c.run(&Operation{Name: OpFind, Collection: collName}, func(ctx context.Context) error {
+		time.Sleep(d)
+		if err != nil {
+			return NewError(MsgGomongoFailedFindError, err)
+		}
+		return nil
+	})
+}
+
+func waitState(t *testing.T, c *Client, collName string, state CircuitState) {
+	deadline := time.Now().Add(time.Second)
+	for c.CircuitState(collName) != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected circuit of %q to be %s, got %s", collName, state, c.CircuitState(collName))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGomongoCircuitBreaker(t *testing.T) {
+	var mu sync.Mutex
+	var changes []string
+	var healthy atomic.Bool
+	c := NewClient("localhost", "test_breaker").SetCircuitBreaker(CircuitBreakerOptions{
+		MinCalls:    4,
+		OpenTimeout: 20 * time.Millisecond,
+		Probe: func(ctx context.Context) error {
+			if !healthy.Load() {
+				return errors.New("down")
+			}
+			return nil
+		},
+		OnStateChange: func(collName string, from CircuitState, to CircuitState) {
+			mu.Lock()
+			changes = append(changes, from.String()+">"+to.String())
+			mu.Unlock()
+		},
+	})
+	netErr := mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}}
+
+	runResult(c, "users", nil, 0)
+	runResult(c, "users", errors.New("bad request"), 0)
+	runResult(c, "users", netErr, 0)
+	if c.CircuitState("users") != CircuitClosed {
+		t.Fatalf("circuit should stay closed below MinCalls")
+	}
+	runResult(c, "orders", netErr, 0)
+	if c.CircuitState("users") != CircuitOpen {
+		t.Fatalf("circuit should open at half the calls failing")
+	}
+	if err := runResult(c, "users", nil, 0); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if c.CircuitState("users") == CircuitClosed {
+		t.Fatalf("circuit should not close while probes fail")
+	}
+	healthy.Store(true)
+	waitState(t, c, "users", CircuitClosed)
+	if err := runResult(c, "users", nil, 0); err != nil {
+		t.Fatalf("expected the operation to run once closed, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) < 4 || changes[0] != "closed>open" || changes[1] != "open>half-open" || changes[len(changes)-1] != "half-open>closed" {
+		t.Fatalf("unexpected state changes %v", changes)
+	}
+}
+
+func TestGomongoCircuitBreakerPerCollection(t *testing.T) {
+	c := NewClient("localhost", "test_breaker").SetCircuitBreaker(CircuitBreakerOptions{
+		MinCalls:      2,
+		ErrorRate:     -1,
+		SlowCall:      5 * time.Millisecond,
+		OpenTimeout:   time.Hour,
+		PerCollection: true,
+	})
+	runResult(c, "reports", nil, 10*time.Millisecond)
+	runResult(c, "reports", nil, 10*time.Millisecond)
+	if c.CircuitState("reports") != CircuitOpen {
+		t.Fatalf("slow calls should open the circuit of reports")
+	}
+	if err := runResult(c, "users", nil, 0); err != nil || c.CircuitState("users") != CircuitClosed {
+		t.Fatalf("the circuit of users should stay closed, got %v", err)
+	}
+}
+
+func TestGomongoCircuitBreakerDisconnect(t *testing.T) {
+	var probes atomic.Int64
+	c := NewClient("localhost", "test_breaker").SetCircuitBreaker(CircuitBreakerOptions{
+		MinCalls:    1,
+		OpenTimeout: 5 * time.Millisecond,
+		Probe: func(ctx context.Context) error {
+			probes.Add(1)
+			return errors.New("down")
+		},
+	})
+	runResult(c, "users", mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}}, 0)
+	for probes.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Disconnect()
+	if c.CircuitState("users") != CircuitClosed {
+		t.Fatalf("expected the circuit to be closed by Disconnect")
+	}
+	n := probes.Load()
+	time.Sleep(30 * time.Millisecond)
+	if got := probes.Load(); got > n+1 {
+		t.Fatalf("expected the probes to stop on Disconnect, got %d more", got-n)
+	}
+}
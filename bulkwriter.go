@@ -0,0 +1,311 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultBulkWriterMaxCount = 1000
+	DefaultBulkWriterMaxBytes = 8 * 1024 * 1024
+	DefaultBulkWriterInterval = time.Second
+)
+
+// ErrBulkWriterClosed is returned by Add after Close
+var ErrBulkWriterClosed = errors.New("bulk writer is closed")
+
+// BulkWriterOptions of NewBulkWriter
+type BulkWriterOptions struct {
+	// MaxCount flush when this many models are buffered. Default is DefaultBulkWriterMaxCount.
+	MaxCount int
+	// MaxBytes flush when the buffered models reach this size in BSON. Default is DefaultBulkWriterMaxBytes.
+	MaxBytes int
+	// Interval flush the buffered models at least this often. Default is DefaultBulkWriterInterval, a negative value
+	// flushes only on MaxCount and MaxBytes.
+	Interval time.Duration
+	// MaxInFlight is the number of flushes running at once. Add blocks while it is reached. Default is 1.
+	MaxInFlight int
+	// OnError is called from the flushing goroutine with each model that failed and its error
+	OnError func(model mongo.WriteModel, err error)
+}
+
+// BulkWriterStats count the models of a BulkWriter
+type BulkWriterStats struct {
+	Added   int64
+	Written int64
+	Failed  int64
+	Flushes int64
+}
+
+// BulkWriter buffer write models and write them with BulkWriteSync, in unordered batches
+type BulkWriter struct {
+	c        *Client
+	collName string
+	opt      BulkWriterOptions
+
+	mu     sync.Mutex
+	models []mongo.WriteModel
+	size   int
+	closed bool
+	// running are the batches taken and not written yet
+	running map[*bulkBatch]struct{}
+
+	inFlight chan struct{}
+	stop     chan struct{}
+
+	added, written, failed, flushes atomic.Int64
+}
+
+// bulkBatch is a batch of models taken from the buffer. done is closed when it is written.
+type bulkBatch struct {
+	models []mongo.WriteModel
+	done   chan struct{}
+	// wcErr is the write concern error of the batch, set before done is closed
+	wcErr error
+}
+
+// NewBulkWriter return a BulkWriter on collName. Close it to write the last buffered models.
+func NewBulkWriter(c *Client, collName string, opts ...BulkWriterOptions) *BulkWriter {
+	var opt BulkWriterOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.MaxCount <= 0 {
+		opt.MaxCount = DefaultBulkWriterMaxCount
+	}
+	if opt.MaxBytes <= 0 {
+		opt.MaxBytes = DefaultBulkWriterMaxBytes
+	}
+	if opt.Interval == 0 {
+		opt.Interval = DefaultBulkWriterInterval
+	}
+	if opt.MaxInFlight <= 0 {
+		opt.MaxInFlight = 1
+	}
+
+	w := &BulkWriter{
+		c:        c,
+		collName: collName,
+		opt:      opt,
+		running:  map[*bulkBatch]struct{}{},
+		inFlight: make(chan struct{}, opt.MaxInFlight),
+		stop:     make(chan struct{}),
+	}
+	if opt.Interval > 0 {
+		go w.tick()
+	}
+	return w
+}
+
+func (w *BulkWriter) tick() {
+	ticker := time.NewTicker(w.opt.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			batch := w.take()
+			w.mu.Unlock()
+			w.dispatch(batch)
+		}
+	}
+}
+
+// Add buffer a model. Insert, update, replace and delete models are supported.
+func (w *BulkWriter) Add(model mongo.WriteModel) error {
+	size, err := modelSize(model)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrBulkWriterClosed
+	}
+	var full *bulkBatch
+	if len(w.models) > 0 && w.size+size > w.opt.MaxBytes {
+		full = w.take()
+	}
+	w.models = append(w.models, model)
+	w.size += size
+	w.added.Add(1)
+	var next *bulkBatch
+	if len(w.models) >= w.opt.MaxCount || w.size >= w.opt.MaxBytes {
+		next = w.take()
+	}
+	w.mu.Unlock()
+
+	w.dispatch(full)
+	w.dispatch(next)
+	return nil
+}
+
+// take the buffered models, to be given to dispatch, or nil when there are none. The caller must hold w.mu.
+func (w *BulkWriter) take() *bulkBatch {
+	if len(w.models) == 0 {
+		return nil
+	}
+	b := &bulkBatch{models: w.models, done: make(chan struct{})}
+	w.models, w.size = nil, 0
+	w.running[b] = struct{}{}
+	return b
+}
+
+// dispatch write b in a new goroutine, waiting while MaxInFlight flushes run
+func (w *BulkWriter) dispatch(b *bulkBatch) {
+	if b == nil {
+		return
+	}
+	w.inFlight <- struct{}{}
+	go func() {
+		b.wcErr = w.write(b.models)
+		<-w.inFlight
+
+		w.mu.Lock()
+		delete(w.running, b)
+		w.mu.Unlock()
+		close(b.done)
+	}()
+}
+
+// write batch and return its write concern error. A write concern error does not fail the models: they were
+// applied, so they are counted as written and not given to OnError.
+func (w *BulkWriter) write(batch []mongo.WriteModel) error {
+	w.flushes.Add(1)
+	res := BulkWriteSync(w.c, w.collName, batch, options.BulkWrite().SetOrdered(false))
+	if res.Err == nil {
+		w.written.Add(int64(len(batch)))
+		return nil
+	}
+
+	var wcErr error
+	modelErrs := make([]error, len(batch))
+	var bwe mongo.BulkWriteException
+	if errors.As(res.Err, &bwe) {
+		for _, we := range bwe.WriteErrors {
+			if we.Index >= 0 && we.Index < len(batch) {
+				modelErrs[we.Index] = we.WriteError
+			}
+		}
+		if bwe.WriteConcernError != nil {
+			wcErr = NewError(MsgGomongoBulkWriteError, bwe.WriteConcernError).setOp(OpBulkWrite, w.collName)
+		}
+	} else {
+		for i := range modelErrs {
+			modelErrs[i] = res.Err
+		}
+	}
+
+	for i, err := range modelErrs {
+		if err == nil {
+			w.written.Add(1)
+			continue
+		}
+		w.failed.Add(1)
+		if w.opt.OnError != nil {
+			w.opt.OnError(batch[i], err)
+		}
+	}
+	return wcErr
+}
+
+// Flush write the buffered models and wait for the flushes running when it is called, or until ctx is done. The
+// batches taken after the call are not waited for. The error joins the write concern errors of the flushes waited
+// for: their models were applied but may not be acknowledged by enough members.
+func (w *BulkWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	batch := w.take()
+	running := make([]*bulkBatch, 0, len(w.running))
+	for b := range w.running {
+		running = append(running, b)
+	}
+	w.mu.Unlock()
+	if len(running) == 0 {
+		return nil
+	}
+	go w.dispatch(batch)
+
+	var errs []error
+	for _, b := range running {
+		select {
+		case <-b.done:
+			if b.wcErr != nil {
+				errs = append(errs, b.wcErr)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stop accepting models and flush the buffered ones. It can be called more than once.
+func (w *BulkWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.stop)
+	}
+	w.mu.Unlock()
+	return w.Flush(ctx)
+}
+
+// Stats return the counts of the models added, written and failed so far
+func (w *BulkWriter) Stats() BulkWriterStats {
+	return BulkWriterStats{
+		Added:   w.added.Load(),
+		Written: w.written.Load(),
+		Failed:  w.failed.Load(),
+		Flushes: w.flushes.Load(),
+	}
+}
+
+// modelSize estimate the size of model in a bulk write
+func modelSize(model mongo.WriteModel) (int, error) {
+	var parts []interface{}
+	switch m := model.(type) {
+	case *mongo.InsertOneModel:
+		parts = []interface{}{m.Document}
+	case *mongo.UpdateOneModel:
+		parts = []interface{}{m.Filter, m.Update}
+	case *mongo.UpdateManyModel:
+		parts = []interface{}{m.Filter, m.Update}
+	case *mongo.ReplaceOneModel:
+		parts = []interface{}{m.Filter, m.Replacement}
+	case *mongo.DeleteOneModel:
+		parts = []interface{}{m.Filter}
+	case *mongo.DeleteManyModel:
+		parts = []interface{}{m.Filter}
+	default:
+		return 0, fmt.Errorf("unsupported write model %T", model)
+	}
+	size := 0
+	for _, p := range parts {
+		if p == nil {
+			continue
+		}
+		if a, ok := p.(bson.A); ok {
+			p = bson.D{{Key: "pipeline", Value: a}}
+		}
+		if pipeline, ok := p.([]bson.D); ok {
+			p = bson.D{{Key: "pipeline", Value: pipeline}}
+		}
+		raw, err := bson.Marshal(p)
+		if err != nil {
+			return 0, err
+		}
+		size += len(raw)
+	}
+	return size, nil
+}
@@ -0,0 +1,143 @@
+package gomongo_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestGomongoBulkWriter(t *testing.T) {
+	c := memdb.NewClient("test_bulk")
+	gomongo.CreateIndexSync(c, "events", bson.D{{Key: "seq", Value: 1}}, options.Index().SetUnique(true))
+
+	var mu sync.Mutex
+	var failed []mongo.WriteModel
+	w := gomongo.NewBulkWriter(c, "events", gomongo.BulkWriterOptions{
+		MaxCount:    10,
+		Interval:    -1,
+		MaxInFlight: 2,
+		OnError: func(model mongo.WriteModel, err error) {
+			if !mongo.IsDuplicateKeyError(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+			mu.Lock()
+			failed = append(failed, model)
+			mu.Unlock()
+		},
+	})
+
+	for i := 0; i < 25; i++ {
+		if err := w.Add(mongo.NewInsertOneModel().SetDocument(bson.M{"seq": i})); err != nil {
+			t.Fatalf("add failed: %s", err)
+		}
+	}
+	dup := mongo.NewInsertOneModel().SetDocument(bson.M{"seq": 3})
+	w.Add(dup)
+	w.Add(mongo.NewUpdateOneModel().SetFilter(bson.M{"seq": 0}).SetUpdate(bson.M{"$set": bson.M{"seen": true}}))
+	w.Add(mongo.NewDeleteOneModel().SetFilter(bson.M{"seq": 1}))
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %s", err)
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("second close failed: %s", err)
+	}
+	if err := w.Add(mongo.NewInsertOneModel().SetDocument(bson.M{"seq": 100})); err != gomongo.ErrBulkWriterClosed {
+		t.Fatalf("expected ErrBulkWriterClosed, got %v", err)
+	}
+
+	stats := w.Stats()
+	if stats.Added != 28 || stats.Written != 27 || stats.Failed != 1 || stats.Flushes != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(failed) != 1 || failed[0] != dup {
+		t.Fatalf("expected the duplicate model to be reported, got %v", failed)
+	}
+	if count := gomongo.CountDocumentsSync(c, "events", bson.M{}); count.Count != 24 {
+		t.Fatalf("expected 24 events, got %d", count.Count)
+	}
+}
+
+// stepHook hold each operation until its gate is closed
+type stepHook struct {
+	started chan chan struct{}
+}
+
+func (h stepHook) BeforeOperation(ctx context.Context, op *gomongo.Operation) context.Context {
+	gate := make(chan struct{})
+	h.started <- gate
+	<-gate
+	return ctx
+}
+
+func (h stepHook) AfterOperation(ctx context.Context, op *gomongo.Operation) {}
+
+func TestGomongoBulkWriterFlush(t *testing.T) {
+	hook := stepHook{started: make(chan chan struct{}, 2)}
+	c := memdb.NewClient("test_bulk").AddHook(hook)
+	w := gomongo.NewBulkWriter(c, "events", gomongo.BulkWriterOptions{MaxCount: 1, Interval: -1, MaxInFlight: 2})
+
+	w.Add(mongo.NewInsertOneModel().SetDocument(bson.M{"seq": 1}))
+	first := <-hook.started
+	flushed := make(chan error, 1)
+	go func() { flushed <- w.Flush(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+
+	// a batch taken after the call is not waited for
+	w.Add(mongo.NewInsertOneModel().SetDocument(bson.M{"seq": 2}))
+	second := <-hook.started
+	close(first)
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Fatalf("flush failed: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("flush waited for a batch taken after it was called")
+	}
+
+	close(second)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %s", err)
+	}
+	if stats := w.Stats(); stats.Written != 2 {
+		t.Fatalf("expected 2 written models, got %+v", stats)
+	}
+}
+
+func TestGomongoBulkWriterLimits(t *testing.T) {
+	c := memdb.NewClient("test_bulk")
+
+	w := gomongo.NewBulkWriter(c, "events", gomongo.BulkWriterOptions{MaxBytes: 100, Interval: 20 * time.Millisecond})
+	defer w.Close(context.Background())
+
+	for i := 0; i < 3; i++ {
+		w.Add(mongo.NewInsertOneModel().SetDocument(bson.M{"seq": i, "payload": "0123456789012345678901234567890123456789"}))
+	}
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+	if stats := w.Stats(); stats.Flushes != 3 || stats.Written != 3 {
+		t.Fatalf("expected a flush per model over MaxBytes, got %+v", stats)
+	}
+
+	w.Add(mongo.NewInsertOneModel().SetDocument(bson.M{"seq": 3}))
+	deadline := time.Now().Add(time.Second)
+	for w.Stats().Written != 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the interval to flush, got %+v", w.Stats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := w.Add(mongo.NewInsertOneModel().SetDocument(make(chan int))); err == nil {
+		t.Fatalf("expected an error for a document that can not be marshaled")
+	}
+}
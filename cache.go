@@ -0,0 +1,441 @@
+package gomongo
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultCacheSize is the number of entries of the LRU cache used when CacheOptions.Cache is not set
+	DefaultCacheSize = 10000
+	// DefaultCacheTTL is how long a document is served from the cache when CacheOptions.TTL is not set
+	DefaultCacheTTL = time.Minute
+)
+
+// Cache stores the documents of the read-through cache set by SetCache. NewLRUCache returns the in-process
+// implementation. Implementations must be safe for concurrent use, and may drop entries at any time.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+// CacheOptions of SetCache
+type CacheOptions struct {
+	// Cache holds the entries. Default is an LRU cache of DefaultCacheSize entries.
+	Cache Cache
+	// TTL is how long an entry is served. Default is DefaultCacheTTL.
+	TTL time.Duration
+	// Collections are the cached collections, with the fields of their unique indexes. FindOneSync by _id, or by
+	// one of these fields, is served from the cache.
+	Collections map[string][]string
+}
+
+// CacheStats counts the lookups of FindOneSync that could be served from the cache
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CacheMetricsRecorder is implemented by the MetricsRecorder implementations that count cache hits and misses
+type CacheMetricsRecorder interface {
+	RecordCache(database string, collName string, hit bool)
+}
+
+// SetCache put a read-through cache in front of FindOneSync, and the functions built on it, for lookups by _id
+// or by a unique field of opts.Collections. Queries with a projection, skip or collation are not cached.
+//
+// Updates, replaces and deletes of the client invalidate the documents they touch: a single one when the filter
+// is on _id, the whole collection otherwise. Writes by other clients are seen once the entries expire, or at once
+// when WatchCache runs.
+func (c *Client) SetCache(opts CacheOptions) *Client {
+	if opts.Cache == nil {
+		opts.Cache = NewLRUCache(DefaultCacheSize)
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	rc := &readCache{cache: opts.Cache, database: c.database, ttl: opts.TTL, collections: map[string]*cachedCollection{}}
+	for name, keys := range opts.Collections {
+		cc := &cachedCollection{uniqueKeys: map[string]bool{}}
+		for _, k := range keys {
+			cc.uniqueKeys[k] = true
+		}
+		rc.collections[name] = cc
+	}
+	c.cache = rc
+	return c
+}
+
+// CacheStats return the hits and misses of the cache set by SetCache
+func (c *Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return CacheStats{Hits: c.cache.hits.Load(), Misses: c.cache.misses.Load()}
+}
+
+// WatchCache invalidate the cached documents from a change stream on the cached collections, so writes of other
+// clients are seen before the entries expire. It blocks until ctx is done or the stream fails, and needs a replica
+// set. Run it in a goroutine and restart it when it fails.
+func (c *Client) WatchCache(ctx context.Context) error {
+	if c.cache == nil {
+		return nil
+	}
+	mc, err := c.GetMongoClient()
+	if err != nil {
+		return NewError(MsgGomongoChangeStreamError, err).setOp("watch", "")
+	}
+	names := bson.A{}
+	for name := range c.cache.collections {
+		names = append(names, name)
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: names}}}}}}}
+	stream, err := mc.Database(c.database).Watch(ctx, pipeline)
+	if err != nil {
+		return NewError(MsgGomongoChangeStreamError, err).setOp("watch", "")
+	}
+	defer stream.Close(context.Background())
+
+	// changes made before the stream opened, or after it ends, are not seen
+	c.cache.invalidateAll()
+	defer c.cache.invalidateAll()
+	for stream.Next(ctx) {
+		c.cache.applyChange(stream.Current)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return NewError(MsgGomongoChangeStreamError, stream.Err()).setOp("watch", "")
+}
+
+type readCache struct {
+	cache       Cache
+	database    string
+	ttl         time.Duration
+	collections map[string]*cachedCollection
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+}
+
+type cachedCollection struct {
+	mu         sync.Mutex
+	uniqueKeys map[string]bool
+	// gen is part of every key, it is moved to invalidate the whole collection
+	gen uint64
+	// epoch moves on every invalidation. A document read before it moved is not stored.
+	epoch uint64
+}
+
+// cacheLookup is a FindOne that can be served from the cache
+type cacheLookup struct {
+	coll  string
+	field string
+	value string
+	gen   uint64
+	epoch uint64
+}
+
+// lookup return the cache lookup of a FindOne, or nil when it can not be cached
+func (rc *readCache) lookup(collName string, filter interface{}, opts []*options.FindOneOptions) *cacheLookup {
+	cc := rc.collections[collName]
+	if cc == nil {
+		return nil
+	}
+	if len(opts) > 0 {
+		o := options.MergeFindOneOptions(opts...)
+		if o.Projection != nil || o.Skip != nil || o.Collation != nil || o.ReturnKey != nil || o.ShowRecordID != nil {
+			return nil
+		}
+	}
+	field, value, ok := singleCondition(filter)
+	if !ok || (field != "_id" && !cc.uniqueKeys[field]) {
+		return nil
+	}
+	key, ok := valueKey(value)
+	if !ok {
+		return nil
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return &cacheLookup{coll: collName, field: field, value: key, gen: cc.gen, epoch: cc.epoch}
+}
+
+func (rc *readCache) key(collName string, gen uint64, field string, value string) string {
+	return rc.database + "\x00" + collName + "\x00" + strconv.FormatUint(gen, 10) + "\x00" + field + "\x00" + value
+}
+
+// get return the cached document of l
+func (rc *readCache) get(l *cacheLookup) (bson.Raw, bool) {
+	id := l.value
+	if l.field != "_id" {
+		v, ok := rc.cache.Get(rc.key(l.coll, l.gen, l.field, l.value))
+		if !ok {
+			return nil, false
+		}
+		id = string(v)
+	}
+	doc, ok := rc.cache.Get(rc.key(l.coll, l.gen, "_id", id))
+	if !ok {
+		return nil, false
+	}
+	if l.field != "_id" {
+		// the field may have changed since the entry was set
+		v, err := bson.Raw(doc).LookupErr(strings.Split(l.field, ".")...)
+		if err != nil {
+			return nil, false
+		}
+		if k, ok := valueKey(v); !ok || k != l.value {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// put store the document read for l, unless it was invalidated since l was made
+func (rc *readCache) put(l *cacheLookup, doc bson.Raw) {
+	v, err := doc.LookupErr("_id")
+	if err != nil {
+		return
+	}
+	id, ok := valueKey(v)
+	if !ok {
+		return
+	}
+	cc := rc.collections[l.coll]
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.epoch != l.epoch {
+		return
+	}
+	rc.cache.Set(rc.key(l.coll, l.gen, "_id", id), append([]byte(nil), doc...), rc.ttl)
+	if l.field != "_id" {
+		rc.cache.Set(rc.key(l.coll, l.gen, l.field, l.value), []byte(id), rc.ttl)
+	}
+}
+
+// afterWrite invalidate the documents that op may have changed
+func (rc *readCache) afterWrite(op *Operation) {
+	switch op.Name {
+	case OpUpdateOne, OpUpdateMany, OpReplaceOne, OpDeleteOne, OpDeleteMany:
+		rc.invalidate(op.Collection, filterIDs(op.Filter))
+	case OpBulkWrite:
+		rc.invalidate(op.Collection, nil)
+	}
+}
+
+// invalidate the documents of collName with the given _id keys, or all of them when ids is nil
+func (rc *readCache) invalidate(collName string, ids []string) {
+	cc := rc.collections[collName]
+	if cc == nil {
+		return
+	}
+	cc.mu.Lock()
+	cc.epoch++
+	if ids == nil {
+		cc.gen++
+	}
+	gen := cc.gen
+	cc.mu.Unlock()
+	for _, id := range ids {
+		rc.cache.Delete(rc.key(collName, gen, "_id", id))
+	}
+}
+
+func (rc *readCache) invalidateAll() {
+	for name := range rc.collections {
+		rc.invalidate(name, nil)
+	}
+}
+
+// applyChange invalidate the documents touched by a change stream event
+func (rc *readCache) applyChange(event bson.Raw) {
+	var ev struct {
+		OperationType string `bson:"operationType"`
+		NS            struct {
+			Coll string `bson:"coll"`
+		} `bson:"ns"`
+		DocumentKey bson.Raw `bson:"documentKey"`
+	}
+	if err := bson.Unmarshal(event, &ev); err != nil {
+		rc.invalidateAll()
+		return
+	}
+	switch ev.OperationType {
+	case "insert":
+	case "update", "replace", "delete":
+		var ids []string
+		if v, err := ev.DocumentKey.LookupErr("_id"); err == nil {
+			if id, ok := valueKey(v); ok {
+				ids = []string{id}
+			}
+		}
+		rc.invalidate(ev.NS.Coll, ids)
+	case "dropDatabase":
+		rc.invalidateAll()
+	default:
+		rc.invalidate(ev.NS.Coll, nil)
+	}
+}
+
+// record count a lookup in the stats of the cache and in the metrics of c
+func (rc *readCache) record(c *Client, collName string, hit bool) {
+	if hit {
+		rc.hits.Add(1)
+	} else {
+		rc.misses.Add(1)
+	}
+	if r, ok := c.metrics.(CacheMetricsRecorder); ok {
+		r.RecordCache(c.database, collName, hit)
+	}
+}
+
+// singleCondition return the field and value of a filter made of a single condition
+func singleCondition(filter interface{}) (string, bson.RawValue, bool) {
+	if filter == nil {
+		return "", bson.RawValue{}, false
+	}
+	raw, err := bson.Marshal(filter)
+	if err != nil {
+		return "", bson.RawValue{}, false
+	}
+	elems, err := bson.Raw(raw).Elements()
+	if err != nil || len(elems) != 1 {
+		return "", bson.RawValue{}, false
+	}
+	return elems[0].Key(), elems[0].Value(), true
+}
+
+// filterIDs return the _id keys selected by a filter on _id, either a single value or $in, or nil for any other filter
+func filterIDs(filter interface{}) []string {
+	field, value, ok := singleCondition(filter)
+	if !ok || field != "_id" {
+		return nil
+	}
+	if id, ok := valueKey(value); ok {
+		return []string{id}
+	}
+	doc, ok := value.DocumentOK()
+	if !ok {
+		return nil
+	}
+	elems, err := doc.Elements()
+	if err != nil || len(elems) != 1 || elems[0].Key() != "$in" {
+		return nil
+	}
+	values, err := elems[0].Value().Array().Values()
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		id, ok := valueKey(v)
+		if !ok {
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// valueKey encode a scalar the way it is compared by the server, so 3, int32(3) and 3.0 have the same key. It is
+// false for values that match more than themselves, as documents, arrays and regular expressions.
+func valueKey(v bson.RawValue) (string, bool) {
+	switch v.Type {
+	case bson.TypeInt32:
+		return "n" + strconv.FormatInt(int64(v.Int32()), 10), true
+	case bson.TypeInt64:
+		return "n" + strconv.FormatInt(v.Int64(), 10), true
+	case bson.TypeDouble:
+		f := v.Double()
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return "n" + strconv.FormatInt(int64(f), 10), true
+		}
+		return "d" + strconv.FormatFloat(f, 'g', -1, 64), true
+	case bson.TypeString, bson.TypeObjectID, bson.TypeBoolean, bson.TypeDateTime, bson.TypeBinary:
+		return string(rune(v.Type)) + string(v.Value), true
+	}
+	return "", false
+}
+
+// LRUCache is a Cache in process memory that drops the least recently used entries beyond its size
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache return an LRU cache of up to size entries
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &LRUCache{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (l *LRUCache) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		l.order.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return e.value, true
+}
+
+func (l *LRUCache) Set(key string, value []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expires = value, time.Now().Add(ttl)
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruEntry{key: key, value: value, expires: time.Now().Add(ttl)})
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *LRUCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.order.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+// Len return the number of entries, including expired ones not dropped yet
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
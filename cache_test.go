@@ -0,0 +1,112 @@
+package gomongo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type cachedUser struct {
+	ID    int    `bson:"_id"`
+	Email string `bson:"email"`
+	Name  string `bson:"name"`
+}
+
+// countHook count the operations that reach the database
+type countHook struct {
+	ops *int
+}
+
+func (h countHook) BeforeOperation(ctx context.Context, op *gomongo.Operation) context.Context {
+	*h.ops++
+	return ctx
+}
+
+func (h countHook) AfterOperation(ctx context.Context, op *gomongo.Operation) {}
+
+func TestGomongoCache(t *testing.T) {
+	ops := 0
+	c := memdb.NewClient("db").AddHook(countHook{&ops}).SetCache(gomongo.CacheOptions{
+		TTL:         50 * time.Millisecond,
+		Collections: map[string][]string{"users": {"email"}},
+	})
+	gomongo.InsertManySync(c, "users", []cachedUser{{1, "a@x", "a"}, {2, "b@x", "b"}})
+
+	find := func(filter bson.M) cachedUser {
+		t.Helper()
+		res := gomongo.FindOneSync[cachedUser](c, "users", filter)
+		if res.Err != nil || !res.Found {
+			t.Fatalf("expected to find %v, got %+v", filter, res)
+		}
+		return res.Document
+	}
+	ops = 0
+	find(bson.M{"_id": 1})
+	if u := find(bson.M{"_id": int32(1)}); u.Name != "a" || ops != 1 {
+		t.Fatalf("expected the second lookup from the cache, %d operations", ops)
+	}
+	find(bson.M{"email": "b@x"})
+	find(bson.M{"email": "b@x"})
+	find(bson.M{"_id": 2.0})
+	if ops != 2 {
+		t.Fatalf("expected the lookups by email and _id to share the document, %d operations", ops)
+	}
+	if s := c.CacheStats(); s.Hits != 3 || s.Misses != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// a local write invalidates the document
+	gomongo.UpdateOneSync(c, "users", bson.M{"_id": 2}, bson.M{"$set": bson.M{"email": "c@x"}})
+	ops = 0
+	if u := find(bson.M{"email": "c@x"}); u.ID != 2 || ops != 1 {
+		t.Fatalf("expected the updated document from the database, got %+v", u)
+	}
+	if res := gomongo.FindOneSync[cachedUser](c, "users", bson.M{"email": "b@x"}); res.Found {
+		t.Fatalf("expected the old email not to be found")
+	}
+
+	// a write with another filter invalidates the whole collection
+	ops = 0
+	gomongo.UpdateManySync(c, "users", bson.M{"name": "a"}, bson.M{"$set": bson.M{"name": "z"}})
+	if u := find(bson.M{"_id": 1}); u.Name != "z" || ops != 2 {
+		t.Fatalf("expected the updated document from the database, got %+v", u)
+	}
+
+	// uncached queries go to the database
+	ops = 0
+	find(bson.M{"name": "z"})
+	gomongo.FindOneSync[cachedUser](c, "users", bson.M{"_id": 1}, options.FindOne().SetProjection(bson.M{"name": 1}))
+	if ops != 2 {
+		t.Fatalf("expected 2 operations, got %d", ops)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	ops = 0
+	find(bson.M{"_id": 1})
+	if ops != 1 {
+		t.Fatalf("expected the entry to expire")
+	}
+}
+
+func TestGomongoLRUCache(t *testing.T) {
+	lru := gomongo.NewLRUCache(2)
+	lru.Set("a", []byte("1"), time.Minute)
+	lru.Set("b", []byte("2"), time.Minute)
+	lru.Get("a")
+	lru.Set("c", []byte("3"), time.Minute)
+	if _, ok := lru.Get("b"); ok || lru.Len() != 2 {
+		t.Fatalf("expected the least recently used entry to be dropped")
+	}
+	if v, ok := lru.Get("a"); !ok || string(v) != "1" {
+		t.Fatalf("expected a to be kept")
+	}
+	lru.Delete("a")
+	if _, ok := lru.Get("a"); ok {
+		t.Fatalf("expected a to be deleted")
+	}
+}
@@ -0,0 +1,409 @@
+package gomongo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultMaxWriteBatchSize and defaultMaxMessageSizeBytes are used when the server limits are unknown
+	defaultMaxWriteBatchSize   = 100000
+	defaultMaxMessageSizeBytes = 48000000
+	// messageOverhead is the part of a message kept for the fields of the command
+	messageOverhead = 16 * 1024
+)
+
+// writeLimits are the largest chunk of InsertManySync and BulkWriteSync
+type writeLimits struct {
+	count int
+	bytes int
+}
+
+// SetChunkConcurrency set how many chunks of an unordered InsertManySync or BulkWriteSync are written at once. Default is 1.
+func (c *Client) SetChunkConcurrency(n int) *Client {
+	c.chunkConcurrency = n
+	return c
+}
+
+// SetMaxWriteBatch limit the chunks of InsertManySync and BulkWriteSync to count documents and bytes of BSON, when
+// these are below the maxWriteBatchSize and maxMessageSizeBytes of the server. Zero keeps the server limit.
+func (c *Client) SetMaxWriteBatch(count int, bytes int) *Client {
+	c.maxBatchCount = count
+	c.maxBatchBytes = bytes
+	return c
+}
+
+func (c *Client) writeLimits() writeLimits {
+	limits := c.serverWriteLimits()
+	if c.maxBatchCount > 0 && c.maxBatchCount < limits.count {
+		limits.count = c.maxBatchCount
+	}
+	if c.maxBatchBytes > 0 && c.maxBatchBytes < limits.bytes {
+		limits.bytes = c.maxBatchBytes
+	} else {
+		limits.bytes -= messageOverhead
+	}
+	return limits
+}
+
+// serverWriteLimits return the limits given by the hello command, asked once per connection
+func (c *Client) serverWriteLimits() writeLimits {
+	limits := writeLimits{count: defaultMaxWriteBatchSize, bytes: defaultMaxMessageSizeBytes}
+	client, err := c.GetMongoClient()
+	if err != nil {
+		return limits
+	}
+	c.conn.mu.Lock()
+	cached := c.conn.limits
+	c.conn.mu.Unlock()
+	if cached != nil {
+		return *cached
+	}
+
+	ctx, cancel := c.ctx()
+	defer cancel()
+	var hello struct {
+		MaxWriteBatchSize   int `bson:"maxWriteBatchSize"`
+		MaxMessageSizeBytes int `bson:"maxMessageSizeBytes"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return limits
+	}
+	if hello.MaxWriteBatchSize > 0 {
+		limits.count = hello.MaxWriteBatchSize
+	}
+	if hello.MaxMessageSizeBytes > 0 {
+		limits.bytes = hello.MaxMessageSizeBytes
+	}
+	c.conn.mu.Lock()
+	c.conn.limits = &limits
+	c.conn.mu.Unlock()
+	return limits
+}
+
+// chunkRanges split items of the given sizes in [start, end) ranges within limits. There is always one range.
+func chunkRanges(sizes []int, limits writeLimits) [][2]int {
+	var ret [][2]int
+	start, total := 0, 0
+	for i, size := range sizes {
+		if i > start && (i-start >= limits.count || total+size > limits.bytes) {
+			ret = append(ret, [2]int{start, i})
+			start, total = i, 0
+		}
+		total += size
+	}
+	return append(ret, [2]int{start, len(sizes)})
+}
+
+// runChunks call fn for each of n chunks. Ordered chunks run one after the other and stop at the first that fails,
+// the others run concurrency at a time.
+func runChunks(n int, ordered bool, concurrency int, fn func(i int) bool) {
+	if ordered || concurrency <= 1 || n == 1 {
+		for i := 0; i < n; i++ {
+			if !fn(i) && ordered {
+				return
+			}
+		}
+		return
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+type insertChunkResult struct {
+	ran      bool
+	attempts int
+	res      *mongo.InsertManyResult
+	err      error
+}
+
+func (c *Client) insertMany(collName string, documents []interface{}, opts []*options.InsertManyOptions) WriteManyResult {
+	ordered := true
+	for _, opt := range opts {
+		if opt != nil && opt.Ordered != nil {
+			ordered = *opt.Ordered
+		}
+	}
+
+	ranges := [][2]int{{0, len(documents)}}
+	if len(documents) > 1 {
+		sizes := make([]int, len(documents))
+		for i, doc := range documents {
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				return WriteManyResult{Err: NewError(MsgGomongoInsertManyError, err).setOp(OpInsertMany, collName)}
+			}
+			sizes[i] = len(raw)
+		}
+		ranges = chunkRanges(sizes, c.writeLimits())
+	}
+
+	results := make([]insertChunkResult, len(ranges))
+	runChunks(len(ranges), ordered, c.chunkConcurrency, func(i int) bool {
+		r := ranges[i]
+		res, attempts, err := c.insertChunk(collName, documents[r[0]:r[1]], ordered, opts)
+		results[i] = insertChunkResult{ran: true, attempts: attempts, res: res, err: err}
+		return err == nil
+	})
+
+	ret := WriteManyResult{DbRes: &mongo.InsertManyResult{InsertedIDs: []interface{}{}}}
+	var firstErr error
+	var bulkErrs []mongo.BulkWriteError
+	var wcErr *mongo.WriteConcernError
+	for i, r := range ranges {
+		chunk := results[i]
+		if !chunk.ran {
+			break
+		}
+		ret.Attempts = max(ret.Attempts, chunk.attempts)
+		var ids []interface{}
+		if chunk.res != nil {
+			ids = chunk.res.InsertedIDs
+		}
+		idAt := func(j int) interface{} {
+			if j < len(ids) {
+				return ids[j]
+			}
+			return nil
+		}
+
+		failed := map[int]bool{}
+		inserted := len(ids)
+		var bwe mongo.BulkWriteException
+		switch {
+		case chunk.err == nil:
+		case errors.As(chunk.err, &bwe):
+			for _, we := range bwe.WriteErrors {
+				failed[we.Index] = true
+				reindexed := we
+				reindexed.Index += r[0]
+				bulkErrs = append(bulkErrs, reindexed)
+				ret.WriteErrors = append(ret.WriteErrors, WriteError{Index: r[0] + we.Index, Code: we.Code, Message: we.Message, ID: idAt(we.Index), Retryable: retryableCodes[we.Code]})
+			}
+			if bwe.WriteConcernError != nil && wcErr == nil {
+				wcErr = bwe.WriteConcernError
+			}
+			// an ordered insert stops at its first failure
+			if ordered && len(bwe.WriteErrors) > 0 {
+				inserted = bwe.WriteErrors[0].Index
+			}
+		default:
+			if firstErr == nil {
+				firstErr = chunk.err
+			}
+			for j := 0; j < r[1]-r[0]; j++ {
+				ret.WriteErrors = append(ret.WriteErrors, chunkWriteError(r[0]+j, idAt(j), chunk.err))
+			}
+			inserted = 0
+		}
+		for j := 0; j < inserted && j < len(ids); j++ {
+			if !failed[j] {
+				ret.DbRes.InsertedIDs = append(ret.DbRes.InsertedIDs, ids[j])
+			}
+		}
+	}
+
+	ret.WriteConcernError = wcErr
+	switch {
+	case len(ranges) == 1:
+		ret.Err = results[0].err
+	case firstErr != nil:
+		ret.Err = firstErr
+	case len(bulkErrs) > 0 || wcErr != nil:
+		ret.Err = NewError(MsgGomongoInsertManyError, mongo.BulkWriteException{WriteErrors: bulkErrs, WriteConcernError: wcErr}).setOp(OpInsertMany, collName)
+	}
+	return ret
+}
+
+type bulkChunkResult struct {
+	ran      bool
+	attempts int
+	res      *mongo.BulkWriteResult
+	err      error
+}
+
+func (c *Client) bulkWrite(collName string, models []mongo.WriteModel, opts []*options.BulkWriteOptions) BulkWriteResult {
+	ordered := true
+	for _, opt := range opts {
+		if opt != nil && opt.Ordered != nil {
+			ordered = *opt.Ordered
+		}
+	}
+
+	ranges := [][2]int{{0, len(models)}}
+	if len(models) > 1 {
+		sizes := make([]int, len(models))
+		for i, model := range models {
+			// models that can not be marshaled are reported by the driver
+			sizes[i], _ = modelSize(model)
+		}
+		ranges = chunkRanges(sizes, c.writeLimits())
+	}
+
+	results := make([]bulkChunkResult, len(ranges))
+	runChunks(len(ranges), ordered, c.chunkConcurrency, func(i int) bool {
+		r := ranges[i]
+		res, attempts, err := c.bulkWriteChunk(collName, models[r[0]:r[1]], opts)
+		results[i] = bulkChunkResult{ran: true, attempts: attempts, res: res, err: err}
+		return err == nil
+	})
+
+	var ret BulkWriteResult
+	agg := &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}
+	var firstErr error
+	var bulkErrs []mongo.BulkWriteError
+	for i, r := range ranges {
+		chunk := results[i]
+		if !chunk.ran {
+			break
+		}
+		ret.Attempts = max(ret.Attempts, chunk.attempts)
+		if res := chunk.res; res != nil {
+			agg.InsertedCount += res.InsertedCount
+			agg.MatchedCount += res.MatchedCount
+			agg.ModifiedCount += res.ModifiedCount
+			agg.DeletedCount += res.DeletedCount
+			agg.UpsertedCount += res.UpsertedCount
+			for idx, id := range res.UpsertedIDs {
+				agg.UpsertedIDs[idx+int64(r[0])] = id
+			}
+		}
+		var bwe mongo.BulkWriteException
+		switch {
+		case chunk.err == nil:
+		case errors.As(chunk.err, &bwe):
+			for _, we := range bwe.WriteErrors {
+				we.Index += r[0]
+				bulkErrs = append(bulkErrs, we)
+				ret.WriteErrors = append(ret.WriteErrors, WriteError{Index: we.Index, Code: we.Code, Message: we.Message, ID: modelID(models[we.Index]), Retryable: retryableCodes[we.Code]})
+			}
+			if bwe.WriteConcernError != nil && ret.WriteConcernError == nil {
+				ret.WriteConcernError = bwe.WriteConcernError
+			}
+		default:
+			if firstErr == nil {
+				firstErr = chunk.err
+			}
+			for j := r[0]; j < r[1]; j++ {
+				ret.WriteErrors = append(ret.WriteErrors, chunkWriteError(j, modelID(models[j]), chunk.err))
+			}
+		}
+	}
+
+	switch {
+	case len(ranges) == 1:
+		ret.DbRes, ret.Err = results[0].res, results[0].err
+	case firstErr != nil:
+		ret.DbRes, ret.Err = agg, firstErr
+	case len(bulkErrs) > 0 || ret.WriteConcernError != nil:
+		ret.DbRes = agg
+		ret.Err = NewError(MsgGomongoBulkWriteError, mongo.BulkWriteException{WriteErrors: bulkErrs, WriteConcernError: ret.WriteConcernError}).setOp(OpBulkWrite, collName)
+	default:
+		ret.DbRes = agg
+	}
+	return ret
+}
+
+// chunkWriteError is the WriteError of a document of a chunk that failed as a whole
+func chunkWriteError(index int, id interface{}, err error) WriteError {
+	code, _ := serverErrorCode(err)
+	return WriteError{Index: index, Code: code, Message: err.Error(), ID: id, Retryable: IsRetryable(err)}
+}
+
+// modelID return the _id of the document of an insert model, or the _id in the filter of other models, when known
+func modelID(model mongo.WriteModel) interface{} {
+	var doc interface{}
+	switch m := model.(type) {
+	case *mongo.InsertOneModel:
+		doc = m.Document
+	case *mongo.UpdateOneModel:
+		doc = m.Filter
+	case *mongo.UpdateManyModel:
+		doc = m.Filter
+	case *mongo.ReplaceOneModel:
+		doc = m.Filter
+	case *mongo.DeleteOneModel:
+		doc = m.Filter
+	case *mongo.DeleteManyModel:
+		doc = m.Filter
+	}
+	return docID(doc)
+}
+
+// docID return the _id of a document or the _id a filter matches, nil when there is none
+func docID(doc interface{}) interface{} {
+	if doc == nil {
+		return nil
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+	var id struct {
+		ID interface{} `bson:"_id"`
+	}
+	if bson.Unmarshal(raw, &id) != nil {
+		return nil
+	}
+	// a filter such as {_id: {$in: [...]}} does not name one document
+	if d, ok := id.ID.(bson.D); ok && len(d) > 0 && strings.HasPrefix(d[0].Key, "$") {
+		return nil
+	}
+	return id.ID
+}
+
+// RetryFailedModelsSync write again the models of a previous BulkWriteSync of models that failed with a retryable
+// error. The indexes of the WriteErrors and UpsertedIDs of the result are positions in models, as in res. It returns an
+// empty result when there is nothing to retry.
+func RetryFailedModelsSync(c *Client, collName string, models []mongo.WriteModel, res BulkWriteResult, opts ...*options.BulkWriteOptions) BulkWriteResult {
+	var retry []mongo.WriteModel
+	var indexes []int
+	for _, we := range res.WriteErrors {
+		if we.Retryable && we.Index >= 0 && we.Index < len(models) {
+			retry = append(retry, models[we.Index])
+			indexes = append(indexes, we.Index)
+		}
+	}
+	if len(retry) == 0 {
+		return BulkWriteResult{DbRes: &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}}
+	}
+
+	ret := BulkWriteSync(c, collName, retry, opts...)
+	for i := range ret.WriteErrors {
+		ret.WriteErrors[i].Index = indexes[ret.WriteErrors[i].Index]
+	}
+	// the exception of the error is read by errors.As, so its indexes are remapped too
+	var bwe mongo.BulkWriteException
+	if errors.As(ret.Err, &bwe) {
+		bulkErrs := make([]mongo.BulkWriteError, len(bwe.WriteErrors))
+		for i, we := range bwe.WriteErrors {
+			we.Index = indexes[we.Index]
+			bulkErrs[i] = we
+		}
+		bwe.WriteErrors = bulkErrs
+		ret.Err = NewError(MsgGomongoBulkWriteError, bwe).setOp(OpBulkWrite, collName)
+	}
+	if ret.DbRes != nil && len(ret.DbRes.UpsertedIDs) > 0 {
+		upserted := make(map[int64]interface{}, len(ret.DbRes.UpsertedIDs))
+		for idx, id := range ret.DbRes.UpsertedIDs {
+			upserted[int64(indexes[idx])] = id
+		}
+		ret.DbRes.UpsertedIDs = upserted
+	}
+	return ret
+}
@@ -0,0 +1,114 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func chunkDocs() []bson.D {
+	docs := make([]bson.D, 10)
+	for i := range docs {
+		docs[i] = bson.D{{Key: "_id", Value: i}}
+	}
+	docs[7] = bson.D{{Key: "_id", Value: 6}}
+	return docs
+}
+
+// insertedHook sum the documents inserted by the operations
+type insertedHook struct {
+	inserted *int64
+}
+
+func (h insertedHook) BeforeOperation(ctx context.Context, op *gomongo.Operation) context.Context {
+	return ctx
+}
+
+func (h insertedHook) AfterOperation(ctx context.Context, op *gomongo.Operation) {
+	*h.inserted += op.Inserted
+}
+
+func TestGomongoChunkedInsertMany(t *testing.T) {
+	c := memdb.NewClient("test_chunk").SetMaxWriteBatch(3, 0).SetChunkConcurrency(3)
+
+	res := gomongo.InsertManySync(c, "items", chunkDocs(), options.InsertMany().SetOrdered(false))
+	if len(res.DbRes.InsertedIDs) != 9 {
+		t.Fatalf("expected 9 inserted ids, got %v", res.DbRes.InsertedIDs)
+	}
+	if len(res.WriteErrors) != 1 || res.WriteErrors[0].Index != 7 || res.WriteErrors[0].ID != int32(6) || res.WriteErrors[0].Code != 11000 {
+		t.Fatalf("expected the duplicate at index 7, got %+v", res.WriteErrors)
+	}
+	var bwe mongo.BulkWriteException
+	if !errors.As(res.Err, &bwe) || len(bwe.WriteErrors) != 1 || bwe.WriteErrors[0].Index != 7 {
+		t.Fatalf("expected a bulk write exception on index 7, got %v", res.Err)
+	}
+
+	var inserted int64
+	ordered := gomongo.InsertManySync(memdb.NewClient("test_chunk").SetMaxWriteBatch(3, 0).AddHook(insertedHook{&inserted}), "items", chunkDocs())
+	if len(ordered.DbRes.InsertedIDs) != 7 || len(ordered.WriteErrors) != 1 || ordered.WriteErrors[0].Index != 7 {
+		t.Fatalf("expected an ordered insert to stop at index 7, got %v %+v", ordered.DbRes.InsertedIDs, ordered.WriteErrors)
+	}
+	if inserted != 7 {
+		t.Fatalf("expected the operations to report 7 inserted documents, got %d", inserted)
+	}
+}
+
+func TestGomongoChunkedBulkWrite(t *testing.T) {
+	c := memdb.NewClient("test_chunk").SetMaxWriteBatch(2, 0)
+
+	models := []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 1}),
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 2}),
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 1}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 3}).SetUpdate(bson.M{"$set": bson.M{"n": 1}}).SetUpsert(true),
+		mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": 2}),
+	}
+	res := gomongo.BulkWriteSync(c, "items", models, options.BulkWrite().SetOrdered(false))
+	var bwe mongo.BulkWriteException
+	if !errors.As(res.Err, &bwe) || len(bwe.WriteErrors) != 1 || bwe.WriteErrors[0].Index != 2 {
+		t.Fatalf("expected a failure on model 2, got %v", res.Err)
+	}
+	r := res.DbRes
+	if r.InsertedCount != 2 || r.UpsertedCount != 1 || r.DeletedCount != 1 || r.UpsertedIDs[3] != int32(3) {
+		t.Fatalf("unexpected aggregated result %+v", r)
+	}
+}
+
+func TestGomongoRetryFailedModels(t *testing.T) {
+	c := memdb.NewClient("test_chunk")
+	gomongo.InsertOneSync(c, "items", bson.M{"_id": 1})
+
+	models := []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 1}),
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 2}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 3}).SetUpdate(bson.M{"$set": bson.M{"n": 1}}).SetUpsert(true),
+	}
+	res := gomongo.BulkWriteSync(c, "items", models[:1])
+	if len(res.WriteErrors) != 1 || res.WriteErrors[0].ID != int32(1) || res.WriteErrors[0].Code != 11000 || res.WriteErrors[0].Retryable {
+		t.Fatalf("expected a duplicate key error on _id 1, got %+v", res.WriteErrors)
+	}
+
+	// as if the last two models failed on a primary step down
+	res.WriteErrors = append(res.WriteErrors,
+		gomongo.WriteError{Index: 1, Code: 189, Retryable: true},
+		gomongo.WriteError{Index: 2, Code: 189, Retryable: true})
+	retried := gomongo.RetryFailedModelsSync(c, "items", models, res)
+	if retried.Err != nil || retried.DbRes.InsertedCount != 1 || retried.DbRes.UpsertedIDs[2] != int32(3) {
+		t.Fatalf("expected models 1 and 2 to be written, got %+v %v", retried.DbRes, retried.Err)
+	}
+
+	again := gomongo.RetryFailedModelsSync(c, "items", models, gomongo.BulkWriteResult{WriteErrors: []gomongo.WriteError{{Index: 1, Retryable: true}}})
+	if len(again.WriteErrors) != 1 || again.WriteErrors[0].Index != 1 || again.WriteErrors[0].ID != int32(2) {
+		t.Fatalf("expected the retry failure on model 1, got %+v", again.WriteErrors)
+	}
+	var bwe mongo.BulkWriteException
+	if !errors.As(again.Err, &bwe) || len(bwe.WriteErrors) != 1 || bwe.WriteErrors[0].Index != 1 {
+		t.Fatalf("expected the error to report model 1, got %v", again.Err)
+	}
+}
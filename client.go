@@ -2,9 +2,13 @@ package gomongo
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"math"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -21,42 +25,141 @@ type Client struct {
 	host              string
 	database          string
 	connectionTimeout time.Duration
+	parent            context.Context
+	hooks             []Hook
+	metrics           MetricsRecorder
+	logger            *slog.Logger
+	slowThreshold     time.Duration
+	redactFields      map[string]bool
+	scanGuard         *ScanGuard
+	backend           Backend
+	cache             *readCache
+	chunkConcurrency  int
+	maxBatchCount     int
+	maxBatchBytes     int
+	retry             *RetryPolicy
+	breaker           *circuitBreaker
+	limiter           *inFlightLimiter
+	conn              *connection
+}
+
+// connection is the driver client shared by a Client and the copies made by WithContext
+type connection struct {
+	mu     sync.Mutex
+	client *mongo.Client
+	limits *writeLimits
 }
 
 func (c *Client) ctx() (context.Context, context.CancelFunc) {
-	parent := context.Background()
-	return context.WithTimeout(parent, c.connectionTimeout)
+	return context.WithTimeout(c.parentCtx(), c.connectionTimeout)
 }
 
-func (c *Client) coll(collectionName string) (*mongo.Collection, error) {
+// parentCtx return the context set by WithContext, or the background context
+func (c *Client) parentCtx() context.Context {
+	if c.parent == nil {
+		return context.Background()
+	}
+	return c.parent
+}
+
+// WithContext returns a copy of the client whose operations run under ctx.
+// Cancelling ctx cancels the operations, and values on it (such as the current trace span) reach the hooks.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	ret := *c
+	ret.parent = ctx
+	return &ret
+}
+
+// SetMetrics set the recorder that receives operation and connection pool metrics.
+// It must be set before the first operation, since the pool monitor is installed when the client connects.
+func (c *Client) SetMetrics(m MetricsRecorder) *Client {
+	c.metrics = m
+	return c
+}
+
+// AddHook register hooks that observe every operation of the client. Hooks should be added before the client is in use.
+func (c *Client) AddHook(hooks ...Hook) *Client {
+	c.hooks = append(c.hooks, hooks...)
+	return c
+}
+
+func (c *Client) coll(collectionName string) (Collection, error) {
+	if c.backend != nil {
+		return c.backend.Collection(c.database, collectionName)
+	}
 	conn, err := c.GetMongoClient()
 	if err != nil {
 		return nil, err
 	}
 
-	return conn.Database(c.database).Collection(collectionName), nil
+	return driverCollection{conn.Database(c.database).Collection(collectionName)}, nil
 }
 
+// GetMongoClient return the driver client used by all operations. It is connected on first use and kept until Disconnect.
+// It fails with ErrNotSupported when the client was created with NewClientWithBackend.
 func (c *Client) GetMongoClient() (*mongo.Client, error) {
+	if c.backend != nil {
+		return nil, ErrNotSupported
+	}
+	c.conn.mu.Lock()
+	defer c.conn.mu.Unlock()
+	if c.conn.client != nil {
+		return c.conn.client, nil
+	}
+
 	ctx, cancelFunc := c.ctx()
 	defer cancelFunc()
+	client, err := mongo.Connect(ctx, c.clientOptions())
+	if err != nil {
+		return nil, err
+	}
+	c.conn.client = client
+	return client, nil
+}
+
+func (c *Client) clientOptions() *options.ClientOptions {
 	opt := options.Client().ApplyURI(c.host)
-	return mongo.Connect(ctx, opt)
+	if c.metrics != nil {
+		opt.SetPoolMonitor(newPoolTracker(c.metrics).monitor())
+	}
+	return opt
+}
+
+// Disconnect close the connections to the database. A later operation connects again. The circuits of the circuit
+// breaker are closed and their probes stop.
+func (c *Client) Disconnect() error {
+	if c.breaker != nil {
+		c.breaker.reset()
+	}
+	c.conn.mu.Lock()
+	defer c.conn.mu.Unlock()
+	if c.conn.client == nil {
+		return nil
+	}
+	ctx, cancelFunc := c.ctx()
+	defer cancelFunc()
+	err := c.conn.client.Disconnect(ctx)
+	c.conn.client = nil
+	c.conn.limits = nil
+	return err
 }
 
 func (c *Client) Ping() bool {
+	ctx, cncl := c.ctx()
+	defer cncl()
+	return c.ping(ctx) == nil
+}
+
+// ping the server within ctx
+func (c *Client) ping(ctx context.Context) error {
+	if c.backend != nil {
+		return nil
+	}
 	client, err := c.GetMongoClient()
 	if err != nil {
-		return false
+		return err
 	}
-	defer func() {
-		if client != nil {
-			client.Disconnect(context.TODO())
-		}
-	}()
-	ctx, cncl := c.ctx()
-	defer cncl()
-	return client.Ping(ctx, nil) == nil
+	return client.Ping(ctx, nil)
 }
 
 /*
@@ -70,223 +173,361 @@ func (c *Client) Ping() bool {
 ****************************************************************************************************************
 ******************************************************************************************************************
 */
+// InsertManySync insert documents to collection. Documents beyond the batch limits of the server are inserted in
+// chunks, in parallel when the insert is unordered and SetChunkConcurrency allows it. InsertedIDs holds the ids of the
+// documents that were inserted, and WriteErrors the documents that failed, by their index in documents.
 func InsertManySync[T any](c *Client, collName string, documents []T, opts ...*options.InsertManyOptions) WriteManyResult {
+	return c.insertMany(collName, toInterfaceArr(documents), opts)
+}
+
+// insertChunk insert one chunk of the documents of InsertManySync
+func (c *Client) insertChunk(collName string, documents []interface{}, ordered bool, opts []*options.InsertManyOptions) (*mongo.InsertManyResult, int, error) {
+	// chunks can run in parallel, so appending the comment must not write to the shared opts
+	opts = opts[:len(opts):len(opts)]
+	op := &Operation{Name: OpInsertMany, Collection: collName, idempotent: c.retriesWrites() && idempotentDocs(documents)}
+	var insertRes *mongo.InsertManyResult
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts, options.InsertMany().SetComment(op.Comment))
+		}
 
-	intrArr := toInterfaceArr(documents)
-
-	coll, err := c.coll(collName)
-	if err != nil {
-		return WriteManyResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
-
-	insertRes, err := coll.InsertMany(ctx, intrArr, opts...)
-	if err != nil {
-		return WriteManyResult{Err: NewError(MsgGomongoInsertManyError, err), DbRes: insertRes}
-	}
-	return WriteManyResult{Err: nil, DbRes: insertRes}
+		insertRes, err = coll.InsertMany(ctx, documents, opts...)
+		if insertRes != nil {
+			op.Inserted = int64(len(insertRes.InsertedIDs))
+			var bwe mongo.BulkWriteException
+			switch {
+			case !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0:
+			case ordered:
+				// an ordered insert stops at its first failure
+				op.Inserted = int64(bwe.WriteErrors[0].Index)
+			default:
+				op.Inserted -= int64(len(bwe.WriteErrors))
+			}
+		}
+		if err != nil {
+			return NewError(MsgGomongoInsertManyError, err)
+		}
+		return nil
+	})
+	return insertRes, op.Attempts, err
 }
 
 // InsertOneSync insert one document to collection.
 func InsertOneSync(c *Client, collName string, document interface{}, opts ...*options.InsertOneOptions) WriteOneResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return WriteOneResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
+	op := &Operation{Name: OpInsertOne, Collection: collName, idempotent: c.retriesWrites() && docID(document) != nil}
+	var insertRes *mongo.InsertOneResult
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.InsertOne().SetComment(op.Comment))
+		}
 
-	insertRes, err := coll.InsertOne(ctx, document, opts...)
+		insertRes, err = coll.InsertOne(ctx, document, opts...)
+		if err != nil {
+			return NewError(MsgGomongoInsertError, err)
+		}
+		op.Inserted = 1
+		return nil
+	})
 	if err != nil {
-		return WriteOneResult{Err: NewError(MsgGomongoInsertManyError, err), DbRes: nil}
+		return WriteOneResult{Attempts: op.Attempts, Err: err, DbRes: nil}
 	}
-	return WriteOneResult{Err: nil, DbRes: insertRes}
+	return WriteOneResult{Attempts: op.Attempts, Err: nil, DbRes: insertRes}
 }
 
 func UpdateOneSync(c *Client, collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) UpdateResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return UpdateResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
+	op := &Operation{Name: OpUpdateOne, Collection: collName, Filter: filter}
+	var dbUpdateRes *mongo.UpdateResult
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Update().SetComment(op.Comment))
+		}
 
-	dbUpdateRes, err := coll.UpdateOne(ctx, filter, instruction, opts...)
+		dbUpdateRes, err = coll.UpdateOne(ctx, filter, instruction, opts...)
+		if err != nil {
+			return NewError(MsgGomongoUpdateError, err)
+		}
+		op.setUpdateCounts(dbUpdateRes)
+		return nil
+	})
 	if err != nil {
-		return UpdateResult{Err: NewError(MsgGomongoInsertManyError, err)}
+		return UpdateResult{Attempts: op.Attempts, Err: err}
 	}
 
-	return UpdateResult{Err: nil, DbRes: dbUpdateRes}
+	return UpdateResult{Attempts: op.Attempts, Err: nil, DbRes: dbUpdateRes}
 }
 
 func UpdateManySync(c *Client, collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) UpdateResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return UpdateResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
+	c.guardScan(OpUpdateMany, collName, filter, func(c *Client) ExplainResult {
+		return ExplainUpdateManySync(c, collName, filter, instruction, ExplainExecutionStats, opts...)
+	})
+	op := &Operation{Name: OpUpdateMany, Collection: collName, Filter: filter}
+	var dbUpdateRes *mongo.UpdateResult
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Update().SetComment(op.Comment))
+		}
 
-	dbUpdateRes, err := coll.UpdateMany(ctx, filter, instruction, opts...)
+		dbUpdateRes, err = coll.UpdateMany(ctx, filter, instruction, opts...)
+		if err != nil {
+			return NewError(MsgGomongoUpdateError, err)
+		}
+		op.setUpdateCounts(dbUpdateRes)
+		return nil
+	})
 	if err != nil {
-		return UpdateResult{Err: NewError(MsgGomongoInsertManyError, err)}
+		return UpdateResult{Attempts: op.Attempts, Err: err}
 	}
 
-	return UpdateResult{Err: nil, DbRes: dbUpdateRes}
+	return UpdateResult{Attempts: op.Attempts, Err: nil, DbRes: dbUpdateRes}
 }
 
+// BulkWriteSync run writeModels on collection. Models beyond the batch limits of the server are written in chunks,
+// in parallel when the write is unordered and SetChunkConcurrency allows it. The indexes of the write errors and of
+// UpsertedIDs are positions in writeModels.
 func BulkWriteSync(c *Client, collName string, writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) BulkWriteResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return BulkWriteResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-	ctx, cancel := c.ctx()
-	defer cancel()
-
-	db_res, err := coll.BulkWrite(ctx, writeModels, opts...)
-	if err != nil {
-		return BulkWriteResult{Err: NewError("failed on bulk write", err)}
-	}
-	return BulkWriteResult{DbRes: db_res}
+	return c.bulkWrite(collName, writeModels, opts)
+}
+
+// bulkWriteChunk write one chunk of the models of BulkWriteSync
+func (c *Client) bulkWriteChunk(collName string, writeModels []mongo.WriteModel, opts []*options.BulkWriteOptions) (*mongo.BulkWriteResult, int, error) {
+	opts = opts[:len(opts):len(opts)]
+	op := &Operation{Name: OpBulkWrite, Collection: collName, idempotent: c.retriesWrites() && idempotentModels(writeModels)}
+	var db_res *mongo.BulkWriteResult
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts, options.BulkWrite().SetComment(op.Comment))
+		}
 
+		db_res, err = coll.BulkWrite(ctx, writeModels, opts...)
+		if db_res != nil {
+			op.Inserted = db_res.InsertedCount
+			op.Matched = db_res.MatchedCount
+			op.Modified = db_res.ModifiedCount
+			op.Upserted = db_res.UpsertedCount
+			op.Deleted = db_res.DeletedCount
+		}
+		if err != nil {
+			return NewError(MsgGomongoBulkWriteError, err)
+		}
+		return nil
+	})
+	// on write errors db_res holds the result of the writes that succeeded
+	return db_res, op.Attempts, err
 }
 
 func ReplaceOneSync(c *Client, collName string, filter interface{}, document interface{}, opts ...*options.ReplaceOptions) UpdateResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return UpdateResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
+	op := &Operation{Name: OpReplaceOne, Collection: collName, Filter: filter, idempotent: c.retriesWrites() && docID(filter) != nil}
+	var dbUpdateRes *mongo.UpdateResult
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Replace().SetComment(op.Comment))
+		}
 
-	dbUpdateRes, err := coll.ReplaceOne(ctx, filter, document, opts...)
+		dbUpdateRes, err = coll.ReplaceOne(ctx, filter, document, opts...)
+		if err != nil {
+			return NewError(MsgGomongoReplaceError, err)
+		}
+		op.setUpdateCounts(dbUpdateRes)
+		return nil
+	})
 	if err != nil {
-		return UpdateResult{Err: NewError(MsgGomongoInsertManyError, err)}
+		return UpdateResult{Attempts: op.Attempts, Err: err}
 	}
 
-	return UpdateResult{Err: nil, DbRes: dbUpdateRes}
+	return UpdateResult{Attempts: op.Attempts, Err: nil, DbRes: dbUpdateRes}
 }
 
 // FindOneSync sync version of searching for a single document in a collection
 func FindOneSync[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOneOptions) ReadOneResult[T] {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return ReadOneResult[T]{Err: NewError(MsgGomongoConnectionError, err)}
+	var lookup *cacheLookup
+	if c.cache != nil {
+		if lookup = c.cache.lookup(collName, filter, opts); lookup != nil {
+			doc, hit := c.cache.get(lookup)
+			var data T
+			if hit && bson.Unmarshal(doc, &data) == nil {
+				c.cache.record(c, collName, true)
+				return ReadOneResult[T]{Document: data, Found: true}
+			}
+			c.cache.record(c, collName, false)
+		}
 	}
+	c.guardScan(OpFindOne, collName, filter, func(c *Client) ExplainResult {
+		return ExplainFindSync(c, collName, filter, ExplainExecutionStats, findOptionsFromFindOne(opts))
+	})
+	op := &Operation{Name: OpFindOne, Collection: collName, Filter: filter, retryable: true}
+	var singleRes *mongo.SingleResult
+	var data T
+	found := false
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.FindOne().SetComment(op.Comment))
+		}
 
-	ctx, cancel := c.ctx()
-	defer cancel()
-	singleRes := coll.FindOne(ctx, filter, opts...)
-	if singleRes.Err() != nil {
-		if singleRes.Err() == mongo.ErrNoDocuments {
-			return ReadOneResult[T]{Found: false, Err: nil}
+		singleRes = coll.FindOne(ctx, filter, opts...)
+		if singleRes.Err() != nil {
+			if singleRes.Err() == mongo.ErrNoDocuments {
+				return nil
+			}
+			return NewError(MsgGomongoFailedFindError, singleRes.Err())
 		}
-		return ReadOneResult[T]{Err: NewError(MsgGomongoFailedFindError, singleRes.Err())}
-	}
 
-	var data T
-	err = singleRes.Decode(&data)
+		err = singleRes.Decode(&data)
+		if err != nil {
+			return NewError(MsgGomongoUnmarshalError, err)
+		}
+		found = true
+		op.Returned = 1
+		if lookup != nil {
+			if raw, err := singleRes.Raw(); err == nil {
+				c.cache.put(lookup, raw)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return ReadOneResult[T]{Err: NewError(MsgGomongoUnmarshalError, singleRes.Err())}
+		return ReadOneResult[T]{Attempts: op.Attempts, Err: err}
+	}
+	if !found {
+		return ReadOneResult[T]{Attempts: op.Attempts, Found: false, Err: nil}
 	}
-	return ReadOneResult[T]{Document: data, Found: true, DbRes: singleRes}
+	return ReadOneResult[T]{Attempts: op.Attempts, Document: data, Found: true, DbRes: singleRes}
 }
 
 // FindSync query for documents in a sync way
 func FindSync[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOptions) ReadManyResult[T] {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return ReadManyResult[T]{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
-	cursor, cur_err := coll.Find(ctx, filter, opts...)
-	if cur_err != nil {
-		return ReadManyResult[T]{Err: NewError(MsgGomongoCursorError, cur_err)}
-	}
-	defer cursor.Close(context.TODO())
+	c.guardScan(OpFind, collName, filter, func(c *Client) ExplainResult {
+		return ExplainFindSync(c, collName, filter, ExplainExecutionStats, opts...)
+	})
+	op := &Operation{Name: OpFind, Collection: collName, Filter: filter, retryable: true}
 	var resultDocs []T
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Find().SetComment(op.Comment))
+		}
 
-	fetchCtx, fetchCancelFunc := c.ctx()
-	defer fetchCancelFunc()
+		cursor, cur_err := coll.Find(ctx, filter, opts...)
+		if cur_err != nil {
+			return NewError(MsgGomongoCursorError, cur_err)
+		}
+		defer cursor.Close(context.TODO())
 
-	err = cursor.All(fetchCtx, &resultDocs)
+		fetchCtx, fetchCancelFunc := c.ctx()
+		defer fetchCancelFunc()
 
+		err = cursor.All(fetchCtx, &resultDocs)
+		if err != nil {
+			return NewError(MsgGomongoFetchError, err)
+		}
+		op.Returned = int64(len(resultDocs))
+		return nil
+	})
 	if err != nil {
-		return ReadManyResult[T]{Documents: nil, Err: NewError(MsgGomongoFetchError, err)}
+		return ReadManyResult[T]{Attempts: op.Attempts, Documents: nil, Err: err}
 	}
 
-	return ReadManyResult[T]{Documents: resultDocs, Err: nil}
+	return ReadManyResult[T]{Attempts: op.Attempts, Documents: resultDocs, Err: nil}
 
 }
 
 func DistinctSync[T any](c *Client, collName string, fieldName string, filter interface{}, opts ...*options.DistinctOptions) DistinctResult[T] {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return DistinctResult[T]{Err: NewError(MsgGomongoConnectionError, err)}
-	}
+	op := &Operation{Name: OpDistinct, Collection: collName, Filter: filter, retryable: true}
+	var values []interface{}
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Distinct().SetComment(op.Comment))
+		}
 
-	ctx, cancel := c.ctx()
-	defer cancel()
-	values, err := coll.Distinct(ctx, fieldName, filter, opts...)
+		values, err = coll.Distinct(ctx, fieldName, filter, opts...)
+		if err != nil {
+			return NewError(MsgGomongoFetchError, err)
+		}
+		op.Returned = int64(len(values))
+		return nil
+	})
 	if err != nil {
-		return DistinctResult[T]{Err: NewError(MsgGomongoFetchError, err)}
+		return DistinctResult[T]{Attempts: op.Attempts, Err: err}
 	}
 	ret_vals := make([]T, len(values))
 	for i, v := range values {
 		ret_vals[i] = v.(T)
 	}
-	return DistinctResult[T]{Values: ret_vals}
+	return DistinctResult[T]{Attempts: op.Attempts, Values: ret_vals}
 }
 
-func FindStreamSync[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOptions) ReadStreamResult[T] {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return ReadStreamResult[T]{DocumentStream: nil, Err: NewError(MsgGomongoConnectionError, err)}
-	}
-
-	ctx, cancel := c.ctx()
-	defer cancel()
-	cursor, cur_err := coll.Find(ctx, filter, opts...)
-	if cur_err != nil {
-		return ReadStreamResult[T]{DocumentStream: nil, Err: NewError(MsgGomongoCursorError, cur_err)}
+// FindStreamSync query for documents and send them on DocumentStream, which is closed after the last document. The
+// goroutine filling the stream ends when ctx is done, so cancel ctx when stopping before the end of the stream.
+func FindStreamSync[T any](ctx context.Context, c *Client, collName string, filter interface{}, opts ...*options.FindOptions) ReadStreamResult[T] {
+	res := openCursor[T](c.WithContext(ctx), OpFindStream, collName, filter, opts)
+	if res.Err != nil {
+		return ReadStreamResult[T]{Attempts: res.Attempts, DocumentStream: nil, Err: res.Err}
 	}
 
 	channel_buffer_size := 200
-	if len(opts) > 1 {
-		opt := opts[0]
-		if opt.BatchSize != nil {
+	for _, opt := range opts {
+		if opt != nil && opt.BatchSize != nil {
 			channel_buffer_size = int(math.Max(200, float64(*opt.BatchSize*2)))
 		}
-
 	}
 	docCh := make(chan ReadOneResult[T], channel_buffer_size)
-	ret := ReadStreamResult[T]{DocumentStream: docCh}
+	ret := ReadStreamResult[T]{Attempts: res.Attempts, DocumentStream: docCh}
+	send := func(doc ReadOneResult[T]) bool {
+		select {
+		case docCh <- doc:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
 	go func() {
-		defer cursor.Close(context.TODO())
+		cursor := res.Cursor
+		defer cursor.Close(context.WithoutCancel(ctx))
 		defer close(docCh)
-		for cursor.Next(context.TODO()) {
-			var fetchedDoc T
-			parseErr := cursor.Decode(&fetchedDoc)
-			if parseErr != nil {
-				docCh <- ReadOneResult[T]{Found: parseErr == nil, Err: NewError(MsgGomongoFailedFindError, parseErr)}
-			} else {
-				docCh <- ReadOneResult[T]{Found: parseErr == nil, Document: fetchedDoc, Err: nil}
+		for cursor.Next(ctx) {
+			fetchedDoc, parseErr := cursor.Decode()
+			if !send(ReadOneResult[T]{Found: parseErr == nil, Document: fetchedDoc, Err: parseErr}) {
+				return
 			}
-
 		}
 
-		if cursor.Err() != nil {
-			docCh <- ReadOneResult[T]{Found: false, Err: NewError(MsgGomongoFetchError, cursor.Err())}
+		if err := cursor.Err(); err != nil && ctx.Err() == nil {
+			send(ReadOneResult[T]{Found: false, Err: err})
 		}
-
 	}()
 
 	return ret
@@ -295,139 +536,202 @@ func FindStreamSync[T any](c *Client, collName string, filter interface{}, opts
 // DeleteOneSync delete on document base on the filter string
 // for options and details mongo deleteon command see: [https://www.mongodb.com/docs/drivers/go/current/usage-examples/deleteOne/]
 func DeleteOneSync(c *Client, collName string, filter interface{}, opts ...*options.DeleteOptions) DeleteResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return DeleteResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
+	op := &Operation{Name: OpDeleteOne, Collection: collName, Filter: filter, idempotent: c.retriesWrites() && docID(filter) != nil}
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Delete().SetComment(op.Comment))
+		}
 
-	ctx, cancel := c.ctx()
-	defer cancel()
-	delRes, err := coll.DeleteOne(ctx, filter, opts...)
+		delRes, err := coll.DeleteOne(ctx, filter, opts...)
+		if err != nil {
+			return NewError(MsgGomongoDeleteError, err)
+		}
+		op.Deleted = delRes.DeletedCount
+		return nil
+	})
 	if err != nil {
-		return DeleteResult{Err: NewError(MsgGomongoDeleteError, err)}
+		return DeleteResult{Attempts: op.Attempts, Err: err}
 	}
-	return DeleteResult{Err: nil, DelCount: delRes.DeletedCount}
+	return DeleteResult{Attempts: op.Attempts, Err: nil, DelCount: op.Deleted}
 }
 
 // DeleteManySync delete many documents from a collection. work in a sync way
 // for options and details mongo deleteon command see: [https://www.mongodb.com/docs/drivers/go/current/usage-examples/deleteOne/]
 func DeleteManySync(c *Client, collName string, filter interface{}, opts ...*options.DeleteOptions) DeleteResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return DeleteResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
+	c.guardScan(OpDeleteMany, collName, filter, func(c *Client) ExplainResult {
+		return explainDeleteMany(c, collName, filter, ExplainExecutionStats, opts...)
+	})
+	op := &Operation{Name: OpDeleteMany, Collection: collName, Filter: filter}
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Delete().SetComment(op.Comment))
+		}
 
-	ctx, cancel := c.ctx()
-	defer cancel()
-	delRes, err := coll.DeleteMany(ctx, filter, opts...)
+		delRes, err := coll.DeleteMany(ctx, filter, opts...)
+		if err != nil {
+			return NewError(MsgGomongoDeleteError, err)
+		}
+		op.Deleted = delRes.DeletedCount
+		return nil
+	})
 	if err != nil {
-		return DeleteResult{Err: NewError(MsgGomongoDeleteError, err)}
+		return DeleteResult{Attempts: op.Attempts, Err: err}
 	}
-	return DeleteResult{Err: nil, DelCount: delRes.DeletedCount}
+	return DeleteResult{Attempts: op.Attempts, Err: nil, DelCount: op.Deleted}
 }
 
 // CountDocuments  count the documents that return from the filter
 func CountDocumentsSync(c *Client, collName string, filter interface{}, opts ...*options.CountOptions) CountResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return CountResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
+	c.guardScan(OpCountDocuments, collName, filter, func(c *Client) ExplainResult {
+		return ExplainCountSync(c, collName, filter, ExplainExecutionStats, opts...)
+	})
+	op := &Operation{Name: OpCountDocuments, Collection: collName, Filter: filter, retryable: true}
+	var count int64
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Count().SetComment(op.Comment))
+		}
 
-	ctx, cancel := c.ctx()
-	defer cancel()
-	count, err := coll.CountDocuments(ctx, filter, opts...)
+		count, err = coll.CountDocuments(ctx, filter, opts...)
+		if err != nil {
+			return NewError(MsgGomongoCountError, err)
+		}
+		op.Returned = count
+		return nil
+	})
 	if err != nil {
-		return CountResult{Err: NewError(MsgGomongoConnectionError, err)}
+		return CountResult{Attempts: op.Attempts, Err: err}
 	}
-	return CountResult{Count: count}
+	return CountResult{Attempts: op.Attempts, Count: count}
 }
 
 // RunCommand run a command on the database
 func RunCommandSync(c *Client, cmd interface{}, opts ...*options.RunCmdOptions) CommandResult {
-	conn, err := c.GetMongoClient()
+	op := &Operation{Name: OpRunCommand, Filter: cmd}
+	var singleRes *mongo.SingleResult
+	err := c.run(op, func(ctx context.Context) error {
+		conn, err := c.GetMongoClient()
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		singleRes = conn.Database(c.database).RunCommand(ctx, cmd, opts...)
+		if singleRes.Err() != nil {
+			return NewError(MsgGomongoCommandError, singleRes.Err())
+		}
+		return nil
+	})
 	if err != nil {
-		return CommandResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-	ctx, cancel := c.ctx()
-	defer cancel()
-	singleRes := conn.Database(c.database).RunCommand(ctx, cmd, opts...)
-	if singleRes.Err() != nil {
-		return CommandResult{Err: NewError(MsgGomongoCommandError, singleRes.Err())}
+		return CommandResult{Err: err}
 	}
 	return CommandResult{DbRes: singleRes}
 }
 
 func CreateIndexSync(c *Client, collName string, indexDef interface{}, idxOpt *options.IndexOptions) IndexCreateResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return IndexCreateResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-	ctx, cancel := c.ctx()
-
-	defer cancel()
+	op := &Operation{Name: OpCreateIndex, Collection: collName, Filter: indexDef}
+	var name string
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
 
-	indexModel := mongo.IndexModel{
-		Keys:    indexDef,
-		Options: idxOpt,
-	}
+		indexModel := mongo.IndexModel{
+			Keys:    indexDef,
+			Options: idxOpt,
+		}
 
-	name, err := coll.Indexes().CreateOne(ctx, indexModel)
+		name, err = coll.CreateIndex(ctx, indexModel)
+		if err != nil {
+			return NewError(MsgGomongoIndexError, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return IndexCreateResult{Err: NewError(MsgGomongoIndexError, err)}
+		return IndexCreateResult{Err: err}
 	}
 	return IndexCreateResult{IndexName: name}
 }
 
 func DropIndexSync(c *Client, collName string, name string, opts ...*options.DropIndexesOptions) IndexDropResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return IndexDropResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-	ctx, cancel := c.ctx()
-	defer cancel()
-	raw, err := coll.Indexes().DropOne(ctx, name, opts...)
+	op := &Operation{Name: OpDropIndex, Collection: collName}
+	var raw bson.Raw
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		raw, err = coll.DropIndex(ctx, name, opts...)
+		if err != nil {
+			return NewError(MsgGomongoIndexError, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return IndexDropResult{Err: NewError(MsgGomongoIndexError, err)}
+		return IndexDropResult{Err: err}
 	}
 	return IndexDropResult{Doc: raw}
 }
 
 func DropAllIndexSync(c *Client, collName string, opts ...*options.DropIndexesOptions) IndexDropResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return IndexDropResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
-	ctx, cancel := c.ctx()
-	defer cancel()
-	raw, err := coll.Indexes().DropAll(ctx, opts...)
+	op := &Operation{Name: OpDropAllIndex, Collection: collName}
+	var raw bson.Raw
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		raw, err = coll.DropAllIndexes(ctx, opts...)
+		if err != nil {
+			return NewError(MsgGomongoIndexError, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return IndexDropResult{Err: NewError(MsgGomongoIndexError, err)}
+		return IndexDropResult{Err: err}
 	}
 	return IndexDropResult{Doc: raw}
 }
 
 func ListIndexSync(c *Client, collName string, opts ...*options.ListIndexesOptions) IndexListResult {
-	coll, err := c.coll(collName)
-	if err != nil {
-		return IndexListResult{Err: NewError(MsgGomongoConnectionError, err)}
-	}
+	op := &Operation{Name: OpListIndex, Collection: collName, retryable: true}
+	var result []interface{}
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
 
-	ctx, cancel := c.ctx()
-	defer cancel()
-	cursor, cur_err := coll.Indexes().List(ctx, opts...)
-	if cur_err != nil {
-		return IndexListResult{Err: NewError(MsgGomongoCursorError, cur_err)}
-	}
-	defer cursor.Close(context.TODO())
-	curs_ctx, curs_cancel := c.ctx()
-	defer curs_cancel()
+		cursor, cur_err := coll.ListIndexes(ctx, opts...)
+		if cur_err != nil {
+			return NewError(MsgGomongoCursorError, cur_err)
+		}
+		defer cursor.Close(context.TODO())
+		curs_ctx, curs_cancel := c.ctx()
+		defer curs_cancel()
 
-	var result []interface{}
-	all_err := cursor.All(curs_ctx, &result)
-	if all_err != nil {
-		return IndexListResult{Err: NewError(MsgGomongoFetchError, all_err)}
+		all_err := cursor.All(curs_ctx, &result)
+		if all_err != nil {
+			return NewError(MsgGomongoFetchError, all_err)
+		}
+		op.Returned = int64(len(result))
+		return nil
+	})
+	if err != nil {
+		return IndexListResult{Attempts: op.Attempts, Err: err}
 	}
-	return IndexListResult{Result: result}
+	return IndexListResult{Attempts: op.Attempts, Result: result}
 }
 
 /*
@@ -444,169 +748,118 @@ func ListIndexSync(c *Client, collName string, opts ...*options.ListIndexesOptio
 
 // InsertMany insert many document in async way
 func InsertMany[T any](c *Client, collName string, documents []T, opts ...*options.InsertManyOptions) chan WriteManyResult {
-	ret := make(chan WriteManyResult, 1)
-	go func() {
-		ret <- InsertManySync(c, collName, documents, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpInsertMany, func() WriteManyResult {
+		return InsertManySync(c, collName, documents, opts...)
+	})
 }
 
 func InsertOne(c *Client, collName string, document interface{}, opts ...*options.InsertOneOptions) chan WriteOneResult {
-	ret := make(chan WriteOneResult, 1)
-	go func() {
-		ret <- InsertOneSync(c, collName, document, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpInsertOne, func() WriteOneResult {
+		return InsertOneSync(c, collName, document, opts...)
+	})
 }
 
 // Update update a single document in an async way
 func UpdateOne(c *Client, collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) chan UpdateResult {
-	ret := make(chan UpdateResult, 1)
-	go func() {
-		ret <- UpdateOneSync(c, collName, filter, instruction, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpUpdateOne, func() UpdateResult {
+		return UpdateOneSync(c, collName, filter, instruction, opts...)
+	})
 }
 
 // Update update all documents matching the filter creteria in an async way
 func UpdateMany(c *Client, collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) chan UpdateResult {
-	ret := make(chan UpdateResult, 1)
-	go func() {
-		ret <- UpdateManySync(c, collName, filter, instruction, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpUpdateMany, func() UpdateResult {
+		return UpdateManySync(c, collName, filter, instruction, opts...)
+	})
 }
 
 func BulkWrite(c *Client, collName string, writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) chan BulkWriteResult {
-	ret := make(chan BulkWriteResult, 1)
-	go func() {
-		ret <- BulkWriteSync(c, collName, writeModels, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpBulkWrite, func() BulkWriteResult {
+		return BulkWriteSync(c, collName, writeModels, opts...)
+	})
 }
 
 // Update update a single document in an async way
 func ReplaceOne(c *Client, collName string, filter interface{}, document interface{}, opts ...*options.ReplaceOptions) chan UpdateResult {
-	ret := make(chan UpdateResult, 1)
-	go func() {
-		ret <- ReplaceOneSync(c, collName, filter, document, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpReplaceOne, func() UpdateResult {
+		return ReplaceOneSync(c, collName, filter, document, opts...)
+	})
 }
 
 // FindOne async function that search for a single document in a collection
 func FindOne[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOneOptions) chan ReadOneResult[T] {
-	ret := make(chan ReadOneResult[T], 1)
-	go func() {
-		ret <- FindOneSync[T](c, collName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpFindOne, func() ReadOneResult[T] {
+		return FindOneSync[T](c, collName, filter, opts...)
+	})
 }
 
 // Find query for documents in async way
 func Find[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOptions) chan ReadManyResult[T] {
-	ret := make(chan ReadManyResult[T], 1)
-	go func() {
-		ret <- FindSync[T](c, collName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpFind, func() ReadManyResult[T] {
+		return FindSync[T](c, collName, filter, opts...)
+	})
 }
 
 // Distinct query for documents in async way
 func Distinct[T any](c *Client, collName string, fieldName string, filter interface{}, opts ...*options.DistinctOptions) chan DistinctResult[T] {
-	ret := make(chan DistinctResult[T], 1)
-	go func() {
-		ret <- DistinctSync[T](c, collName, fieldName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpDistinct, func() DistinctResult[T] {
+		return DistinctSync[T](c, collName, fieldName, filter, opts...)
+	})
 }
 
 // Find query for documents in async way
-func FindStream[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOptions) chan ReadStreamResult[T] {
-	ret := make(chan ReadStreamResult[T], 1)
-	go func() {
-		ret <- FindStreamSync[T](c, collName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+func FindStream[T any](ctx context.Context, c *Client, collName string, filter interface{}, opts ...*options.FindOptions) chan ReadStreamResult[T] {
+	return async(c, OpFindStream, func() ReadStreamResult[T] {
+		return FindStreamSync[T](ctx, c, collName, filter, opts...)
+	})
 }
 
 // DeleteOne delete on document in a async way
 // for options and details mongo deleteon command see: [https://www.mongodb.com/docs/drivers/go/current/usage-examples/deleteOne/]
 func DeleteOne(c *Client, collName string, filter interface{}, opts ...*options.DeleteOptions) chan DeleteResult {
-	ret := make(chan DeleteResult, 1)
-	go func() {
-		ret <- DeleteOneSync(c, collName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpDeleteOne, func() DeleteResult {
+		return DeleteOneSync(c, collName, filter, opts...)
+	})
 }
 
 // DeleteMany delete many documents from a collection. work in async way. you need to check the result of the channel
 // for options and details mongo deleteon command see: [https://www.mongodb.com/docs/drivers/go/current/usage-examples/deleteOne/]
 func DeleteMany(c *Client, collName string, filter interface{}, opts ...*options.DeleteOptions) chan DeleteResult {
-	ret := make(chan DeleteResult, 1)
-	go func() {
-		ret <- DeleteManySync(c, collName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpDeleteMany, func() DeleteResult {
+		return DeleteManySync(c, collName, filter, opts...)
+	})
 }
 
 // CountDocuments async, count the documents that return from the filter
 func CountDocuments(c *Client, collName string, filter interface{}, opts ...*options.CountOptions) chan CountResult {
-	ret := make(chan CountResult, 1)
-	go func() {
-		ret <- CountDocumentsSync(c, collName, filter, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpCountDocuments, func() CountResult {
+		return CountDocumentsSync(c, collName, filter, opts...)
+	})
 }
 
 // RunCommand run database command async
 func RunCommand(c *Client, cmd interface{}, opts ...*options.RunCmdOptions) chan CommandResult {
-	ret := make(chan CommandResult, 1)
-	go func() {
-		ret <- RunCommandSync(c, cmd, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpRunCommand, func() CommandResult {
+		return RunCommandSync(c, cmd, opts...)
+	})
 }
 
 func CreateIndex(c *Client, collName string, indexDef interface{}, opt *options.IndexOptions) chan IndexCreateResult {
-	ret := make(chan IndexCreateResult, 1)
-	go func() {
-		ret <- CreateIndexSync(c, collName, indexDef, opt)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpCreateIndex, func() IndexCreateResult {
+		return CreateIndexSync(c, collName, indexDef, opt)
+	})
 }
 
 func DropIndex(c *Client, collName string, indexName string, opts ...*options.DropIndexesOptions) chan IndexDropResult {
-	ret := make(chan IndexDropResult, 1)
-	go func() {
-		ret <- DropIndexSync(c, collName, indexName, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpDropIndex, func() IndexDropResult {
+		return DropIndexSync(c, collName, indexName, opts...)
+	})
 }
 
 func DropAllIndex(c *Client, collName string, opts ...*options.DropIndexesOptions) chan IndexDropResult {
-	ret := make(chan IndexDropResult, 1)
-	go func() {
-		ret <- DropAllIndexSync(c, collName, opts...)
-		close(ret)
-	}()
-	return ret
+	return async(c, OpDropAllIndex, func() IndexDropResult {
+		return DropAllIndexSync(c, collName, opts...)
+	})
 }
 
 // NewClient return a pointer to gomongo.Client that is used to communicate the Mongodb
@@ -621,6 +874,7 @@ func NewClient(host string, database string, connTimeout ...time.Duration) *Clie
 		host:              host,
 		database:          database,
 		connectionTimeout: time.Duration(time.Second * 10),
+		conn:              &connection{},
 	}
 	if len(connTimeout) > 0 {
 		ret.connectionTimeout = connTimeout[0]
@@ -1,13 +1,17 @@
-package gomongo
+package gomongo_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/gomongotest"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Course struct {
@@ -23,12 +27,14 @@ type Restaurant struct {
 	Courses      []Course    `bson:"courses,omitempty"`
 }
 
-const HOST = "mongodb://localhost:27017"
-const DB_NAME = "test_client"
 const COLL_NAME_RESTAURANT = "restorant"
-const COLL_NAME_COURSE = "course"
 
-var client = NewClient(HOST, DB_NAME, time.Second*60)
+// client is the client of the running test, on its own database of the server of gomongotest
+var client *gomongo.Client
+
+func TestMain(m *testing.M) {
+	gomongotest.Main(m, gomongotest.Options{})
+}
 
 func testClient(t *testing.T) {
 	var _, err = client.GetMongoClient()
@@ -46,6 +52,7 @@ func testPing(t *testing.T) {
 }
 
 func TestGomongoConnection(t *testing.T) {
+	client = gomongotest.NewTestClient(t, 60*time.Second)
 
 	t.Run("connection", testClient)
 	t.Run("ping", testPing)
@@ -64,14 +71,14 @@ func testInsertMany(t *testing.T) {
 		Restaurant{Name: "restorant 3", Cuisine: "cuise 3"},
 	}
 
-	res := InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
+	res := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if res.Err != nil {
 		t.Errorf("%s", res.Err)
 	} else {
 		t.Logf("insert many result %s", res.DbRes)
 	}
 
-	idx_res := <-CreateIndex(client, COLL_NAME_RESTAURANT, bson.M{"name": 1}, nil)
+	idx_res := <-gomongo.CreateIndex(client, COLL_NAME_RESTAURANT, bson.M{"name": 1}, nil)
 	if idx_res.Err != nil {
 		t.Errorf("failed to create index on name field %s", idx_res.Err.Error())
 	} else {
@@ -83,7 +90,7 @@ func testInsertMany(t *testing.T) {
 func testInsertOne(t *testing.T) {
 	newRestaurant := Restaurant{Name: "restorant single", Cuisine: "cuise single"}
 
-	res := InsertOneSync(client, COLL_NAME_RESTAURANT, newRestaurant)
+	res := gomongo.InsertOneSync(client, COLL_NAME_RESTAURANT, newRestaurant)
 	if res.Err != nil {
 		t.Errorf("%s, %s", res.Err, errors.Unwrap(res.Err))
 	} else {
@@ -92,7 +99,7 @@ func testInsertOne(t *testing.T) {
 }
 
 func testFindOne(t *testing.T) {
-	res := FindOneSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"})
+	res := gomongo.FindOneSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"})
 	if res.Err != nil {
 		t.Errorf("failed to find document %s, %s", res.Err, errors.Unwrap(res.Err))
 	} else {
@@ -107,7 +114,7 @@ func testFindOne(t *testing.T) {
 }
 
 func testFindMany(t *testing.T) {
-	res := FindSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"})
+	res := gomongo.FindSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"})
 	if res.Err != nil {
 		t.Errorf("failed to find document %s, %s", res.Err, errors.Unwrap(res.Err))
 	} else {
@@ -120,7 +127,7 @@ func testFindMany(t *testing.T) {
 	}
 
 	t.Log("now checking async version")
-	chanRes := <-Find[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"})
+	chanRes := <-gomongo.Find[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"})
 	if chanRes.Err != nil {
 		t.Errorf("failed to find document %s, %s", chanRes.Err, errors.Unwrap(chanRes.Err))
 	} else {
@@ -147,12 +154,12 @@ func testFindStream(t *testing.T) {
 			Courses:      courses}
 	}
 
-	res := InsertManySync(client, COLL_NAME_RESTAURANT, data)
+	res := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, data)
 	if res.Err != nil {
 		t.Error("failed to insert documents", res.Err)
 	}
 
-	find := <-FindStream[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "stream name"})
+	find := <-gomongo.FindStream[Restaurant](context.Background(), client, COLL_NAME_RESTAURANT, bson.M{"name": "stream name"})
 
 	if find.Err != nil {
 		t.Error("failed to find via stream ", find.Err)
@@ -174,7 +181,7 @@ func testFindStream(t *testing.T) {
 }
 
 func testDistinct(t *testing.T) {
-	res := DistinctSync[string](client, COLL_NAME_RESTAURANT, "name", bson.M{})
+	res := gomongo.DistinctSync[string](client, COLL_NAME_RESTAURANT, "name", bson.M{})
 	if res.Err != nil {
 		t.Error(res.Err)
 	}
@@ -182,7 +189,7 @@ func testDistinct(t *testing.T) {
 }
 
 func testNotFindOne(t *testing.T) {
-	res := FindOneSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "not found restorant name"}, nil)
+	res := gomongo.FindOneSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "not found restorant name"}, nil)
 	if res.Err != nil {
 		t.Errorf("failed to find document %s, %s", res.Err, errors.Unwrap(res.Err))
 	} else {
@@ -200,12 +207,12 @@ func testDeleteOne(t *testing.T) {
 		Restaurant{Name: "restorant 1", Cuisine: "cuise 3"},
 	}
 
-	resInst := InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
+	resInst := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if resInst.Err != nil {
 		t.Errorf("%s, %s", resInst.Err, errors.Unwrap(resInst.Err))
 	}
 
-	res := DeleteOneSync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, nil)
+	res := gomongo.DeleteOneSync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, nil)
 
 	if res.Err != nil {
 		t.Errorf("failed to delete recipe %s", res.Err)
@@ -226,11 +233,11 @@ func testDeleteMany(t *testing.T) {
 		Restaurant{Name: "restorant 1", Cuisine: "cuise 3"},
 	}
 
-	resInst := InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
+	resInst := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if resInst.Err != nil {
 		t.Errorf("%s, %s", resInst.Err, errors.Unwrap(resInst.Err))
 	}
-	res := DeleteManySync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, nil)
+	res := gomongo.DeleteManySync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, nil)
 	if res.Err != nil {
 		t.Errorf("failed to delete recipe %s", res.Err)
 	}
@@ -249,22 +256,22 @@ func testUpdateDocument(t *testing.T) {
 		Restaurant{Name: "restorant 1", Cuisine: "cuise 2"},
 		Restaurant{Name: "restorant 1", Cuisine: "cuise 3"},
 	}
-	resInst := InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
+	resInst := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if resInst.Err != nil {
 		t.Errorf("%s", resInst.Err)
 	}
 	t.Log("updating restorate 1. All cuise will be called sagi 3")
-	resUpdate := UpdateManySync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, bson.M{"$set": bson.M{"cuisine": "sagi 3"}})
+	resUpdate := gomongo.UpdateManySync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, bson.M{"$set": bson.M{"cuisine": "sagi 3"}})
 	if resUpdate.Err != nil {
 		t.Errorf("failed at update many %s", resUpdate.Err)
 	}
 
-	resFind := FindSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"cuisine": "sagi 3"})
+	resFind := gomongo.FindSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"cuisine": "sagi 3"})
 	if len(resFind.Documents) < 1 {
 		t.Errorf("No document was updated at update check")
 	}
 
-	resFindNone := FindSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1", "cuisine": "cuise 1"})
+	resFindNone := gomongo.FindSync[Restaurant](client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1", "cuisine": "cuise 1"})
 	if len(resFindNone.Documents) != 0 {
 		t.Errorf("Some document where not updated")
 	}
@@ -277,7 +284,7 @@ func testBulkWrite(t *testing.T) {
 		Restaurant{Name: "restorant 2", Cuisine: "cuise 2"},
 		Restaurant{Name: "restorant 3", Cuisine: "cuise 3"},
 	}
-	resInst := InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
+	resInst := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if resInst.Err != nil {
 		t.Errorf("%s", resInst.Err)
 	}
@@ -293,7 +300,7 @@ func testBulkWrite(t *testing.T) {
 		update_models = append(update_models, uom)
 	}
 
-	res := BulkWriteSync(client, COLL_NAME_RESTAURANT, update_models)
+	res := gomongo.BulkWriteSync(client, COLL_NAME_RESTAURANT, update_models)
 	if res.Err != nil {
 		t.Error(res.Err)
 	}
@@ -304,6 +311,7 @@ func testBulkWrite(t *testing.T) {
 }
 
 func TestGomongoWrite(t *testing.T) {
+	client = gomongotest.NewTestClient(t, 60*time.Second)
 	t.Run("insert many", testInsertMany)
 
 	t.Run("insert one", testInsertOne)
@@ -314,6 +322,8 @@ func TestGomongoWrite(t *testing.T) {
 }
 
 func TestGomongoRead(t *testing.T) {
+	client = gomongotest.NewTestClient(t, 60*time.Second)
+	t.Run("insert many", testInsertMany)
 	t.Run("find one", testFindOne)
 
 	t.Run("do not find one", testNotFindOne)
@@ -326,6 +336,7 @@ func TestGomongoRead(t *testing.T) {
 }
 
 func TestGomongoDelete(t *testing.T) {
+	client = gomongotest.NewTestClient(t, 60*time.Second)
 	t.Run("delete one", testDeleteOne)
 
 	t.Run("delete many", testDeleteMany)
@@ -348,7 +359,7 @@ func bmInsertManySync(b *testing.B) {
 
 	b.Log("Sync operaions")
 	b.ResetTimer()
-	res := InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
+	res := gomongo.InsertManySync(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if res.Err != nil {
 		b.Errorf("%s", res.Err)
 	} else {
@@ -365,7 +376,7 @@ func bmInsertManyAsync(b *testing.B) {
 	}
 	b.Log("Async operaions")
 	b.ResetTimer()
-	resch := <-InsertMany(client, COLL_NAME_RESTAURANT, newRestaurants)
+	resch := <-gomongo.InsertMany(client, COLL_NAME_RESTAURANT, newRestaurants)
 	if resch.Err != nil {
 		b.Errorf("failed to insert many")
 	}
@@ -374,6 +385,44 @@ func bmInsertManyAsync(b *testing.B) {
 }
 
 func BenchmarkGomongoWrite(b *testing.B) {
+	client = gomongotest.NewTestClient(b, 60*time.Second)
 	b.Run("insert many sync", bmInsertManySync)
 	b.Run("insert many async", bmInsertManyAsync)
 }
+
+func testExplainFind(t *testing.T) {
+	res := gomongo.ExplainFindSync(client, COLL_NAME_RESTAURANT, bson.M{"name": "restorant 1"}, gomongo.ExplainExecutionStats, options.Find().SetLimit(5))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	t.Logf("find plan %v index %s", res.WinningPlan.Stages, res.IndexName)
+
+	countRes := gomongo.ExplainCountSync(client, COLL_NAME_RESTAURANT, bson.M{"cuisine": "cuise 1"}, gomongo.ExplainQueryPlanner)
+	if countRes.Err != nil {
+		t.Fatal(countRes.Err)
+	}
+	if !countRes.CollScan {
+		t.Errorf("count on cuisine should scan the collection")
+	}
+}
+
+func TestGomongoExplain(t *testing.T) {
+	client = gomongotest.NewTestClient(t, 60*time.Second)
+	t.Run("insert many", testInsertMany)
+	t.Run("find", testExplainFind)
+}
+
+func testScanGuardFind(t *testing.T) {
+	var violations []gomongo.ScanViolation
+	c := client.SetScanGuard(&gomongo.ScanGuard{OnViolation: func(v gomongo.ScanViolation) { violations = append(violations, v) }})
+	gomongo.FindSync[Restaurant](c, COLL_NAME_RESTAURANT, bson.M{"cuisine": "cuise 1"})
+	if len(violations) != 1 {
+		t.Errorf("find on cuisine should scan the collection")
+	}
+}
+
+func TestGomongoScanGuard(t *testing.T) {
+	client = gomongotest.NewTestClient(t, 60*time.Second)
+	t.Run("insert many", testInsertMany)
+	t.Run("find", testScanGuardFind)
+}
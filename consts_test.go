@@ -0,0 +1,6 @@
+package gomongo
+
+// The client of the unit tests is not connected: the tests that need a server are in client_test.go.
+const HOST = "mongodb://localhost:27017"
+const DB_NAME = "test_client"
+const COLL_NAME_RESTAURANT = "restorant"
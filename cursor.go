@@ -0,0 +1,116 @@
+package gomongo
+
+import (
+	"context"
+	"iter"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Cursor iterate the documents of a query, decoded as T. It must be closed.
+//
+//	res := gomongo.FindCursorSync[User](gmc, "users", filter)
+//	if res.Err != nil { ... }
+//	defer res.Cursor.Close(ctx)
+//	for res.Cursor.Next(ctx) {
+//		user, err := res.Cursor.Decode()
+//		...
+//	}
+//	if err := res.Cursor.Err(); err != nil { ... }
+type Cursor[T any] struct {
+	cur *mongo.Cursor
+}
+
+type CursorResult[T any] struct {
+	Cursor   *Cursor[T]
+	Attempts int
+	Err      error
+}
+
+// Next move to the next document. It returns false when there are no more documents, or on an error, which Err
+// returns.
+func (cr *Cursor[T]) Next(ctx context.Context) bool {
+	return cr.cur.Next(ctx)
+}
+
+// Decode return the current document
+func (cr *Cursor[T]) Decode() (T, error) {
+	var doc T
+	if err := cr.cur.Decode(&doc); err != nil {
+		return doc, NewError(MsgGomongoUnmarshalError, err)
+	}
+	return doc, nil
+}
+
+// Err return the error that stopped Next, if any
+func (cr *Cursor[T]) Err() error {
+	if err := cr.cur.Err(); err != nil {
+		return NewError(MsgGomongoFetchError, err)
+	}
+	return nil
+}
+
+// Close release the cursor on the server
+func (cr *Cursor[T]) Close(ctx context.Context) error {
+	return cr.cur.Close(ctx)
+}
+
+// openCursor run the find of a cursor as an operation named opName
+func openCursor[T any](c *Client, opName string, collName string, filter interface{}, opts []*options.FindOptions) CursorResult[T] {
+	op := &Operation{Name: opName, Collection: collName, Filter: filter, retryable: true}
+	var cursor *mongo.Cursor
+	err := c.run(op, func(ctx context.Context) error {
+		coll, err := c.coll(collName)
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		if op.Comment != "" && op.Attempts == 1 {
+			opts = append(opts[:len(opts):len(opts)], options.Find().SetComment(op.Comment))
+		}
+
+		cursor, err = coll.Find(ctx, filter, opts...)
+		if err != nil {
+			return NewError(MsgGomongoCursorError, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return CursorResult[T]{Attempts: op.Attempts, Err: err}
+	}
+	return CursorResult[T]{Cursor: &Cursor[T]{cur: cursor}, Attempts: op.Attempts}
+}
+
+// FindCursorSync query for documents and return a cursor over them
+func FindCursorSync[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOptions) CursorResult[T] {
+	return openCursor[T](c, OpFindCursor, collName, filter, opts)
+}
+
+// FindIter query for documents and return them as an iterator. An error is yielded once and ends the iteration.
+// The cursor is closed when the iteration ends, also when the range loop breaks.
+//
+//	for user, err := range gomongo.FindIter[User](ctx, gmc, "users", filter) {
+//		if err != nil { ... }
+//	}
+func FindIter[T any](ctx context.Context, c *Client, collName string, filter interface{}, opts ...*options.FindOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		res := openCursor[T](c.WithContext(ctx), OpFindCursor, collName, filter, opts)
+		if res.Err != nil {
+			yield(zero, res.Err)
+			return
+		}
+		cursor := res.Cursor
+		defer cursor.Close(context.WithoutCancel(ctx))
+
+		for cursor.Next(ctx) {
+			doc, err := cursor.Decode()
+			if !yield(doc, err) || err != nil {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
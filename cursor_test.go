@@ -0,0 +1,142 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type cursorDoc struct {
+	ID  int    `bson:"_id"`
+	Tag string `bson:"tag"`
+}
+
+func cursorClient(t *testing.T, n int) *gomongo.Client {
+	c := memdb.NewClient("test_cursor")
+	docs := make([]cursorDoc, n)
+	for i := range docs {
+		docs[i] = cursorDoc{ID: i, Tag: "t"}
+	}
+	if res := gomongo.InsertManySync(c, "docs", docs); res.Err != nil {
+		t.Fatalf("insert failed: %s", res.Err)
+	}
+	return c
+}
+
+func TestGomongoCursor(t *testing.T) {
+	c := cursorClient(t, 10)
+	ctx := context.Background()
+
+	res := gomongo.FindCursorSync[cursorDoc](c, "docs", bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if res.Err != nil {
+		t.Fatalf("find failed: %s", res.Err)
+	}
+	defer res.Cursor.Close(ctx)
+	count := 0
+	for res.Cursor.Next(ctx) {
+		doc, err := res.Cursor.Decode()
+		if err != nil || doc.ID != count {
+			t.Fatalf("unexpected document %d: %+v %v", count, doc, err)
+		}
+		count++
+	}
+	if res.Cursor.Err() != nil || count != 10 {
+		t.Fatalf("expected 10 documents, got %d %v", count, res.Cursor.Err())
+	}
+
+	var ids []int
+	for doc, err := range gomongo.FindIter[cursorDoc](ctx, c, "docs", bson.M{"_id": bson.M{"$gte": 5}}, options.Find().SetSort(bson.M{"_id": 1})) {
+		if err != nil {
+			t.Fatalf("iteration failed: %s", err)
+		}
+		ids = append(ids, doc.ID)
+		if len(ids) == 3 {
+			break
+		}
+	}
+	if len(ids) != 3 || ids[0] != 5 || ids[2] != 7 {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+
+	for _, err := range gomongo.FindIter[cursorDoc](ctx, c, "docs", bson.M{"$bad": 1}) {
+		if err == nil {
+			t.Fatalf("expected an error for an invalid filter")
+		}
+	}
+}
+
+func TestGomongoFindStreamCancel(t *testing.T) {
+	c := cursorClient(t, 500)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	res := gomongo.FindStreamSync[cursorDoc](ctx, c, "docs", bson.M{}, options.Find().SetBatchSize(150))
+	if res.Err != nil {
+		t.Fatalf("find failed: %s", res.Err)
+	}
+	if cap(res.DocumentStream) != 300 {
+		t.Fatalf("expected a buffer of twice the batch size, got %d", cap(res.DocumentStream))
+	}
+	if first := <-res.DocumentStream; first.Err != nil || !first.Found {
+		t.Fatalf("unexpected first document %+v", first)
+	}
+	cancel()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case doc, ok := <-res.DocumentStream:
+			if !ok {
+				return
+			}
+			if doc.Err != nil && !errors.Is(doc.Err, context.Canceled) {
+				t.Fatalf("unexpected error %v", doc.Err)
+			}
+		case <-timeout:
+			t.Fatalf("the stream was not closed after the context was cancelled")
+		}
+	}
+}
+
+// commentHook set the comment of every operation
+type commentHook struct{}
+
+func (commentHook) BeforeOperation(ctx context.Context, op *gomongo.Operation) context.Context {
+	op.Comment = "trace"
+	return ctx
+}
+
+func (commentHook) AfterOperation(ctx context.Context, op *gomongo.Operation) {}
+
+func TestGomongoCommentKeepsOpts(t *testing.T) {
+	c := cursorClient(t, 3).AddHook(commentHook{})
+	ctx := context.Background()
+
+	spare := options.Find().SetLimit(7)
+	findOpts := append(make([]*options.FindOptions, 0, 2), options.Find().SetSort(bson.M{"_id": 1}), spare)[:1]
+	if res := gomongo.FindSync[cursorDoc](c, "docs", bson.M{}, findOpts...); res.Err != nil || len(res.Documents) != 3 {
+		t.Fatalf("find failed: %v", res.Err)
+	}
+	cur := gomongo.FindCursorSync[cursorDoc](c, "docs", bson.M{}, findOpts...)
+	if cur.Err != nil {
+		t.Fatalf("find cursor failed: %s", cur.Err)
+	}
+	cur.Cursor.Close(ctx)
+	if findOpts[:2][1] != spare {
+		t.Fatalf("the comment was written to the spare capacity of the caller's options")
+	}
+
+	spareOne := options.FindOne().SetSkip(1)
+	findOneOpts := append(make([]*options.FindOneOptions, 0, 2), options.FindOne(), spareOne)[:1]
+	if res := gomongo.FindOneSync[cursorDoc](c, "docs", bson.M{"_id": 0}, findOneOpts...); res.Err != nil || !res.Found {
+		t.Fatalf("find one failed: %v", res.Err)
+	}
+	if findOneOpts[:2][1] != spareOne {
+		t.Fatalf("the comment was written to the spare capacity of the caller's options")
+	}
+}
@@ -1,20 +1,69 @@
 package gomongo
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 const MsgGomongoConnectionError = "failed to connect to database"
 const MsgGomongoCursorError = "failed to open cursor to query result"
 const MsgGomongoFailedFindError = "failed to query database"
 const MsgGomongoUnmarshalError = "failed to unmarshal document"
+const MsgGomongoInsertError = "failed to insert document"
 const MsgGomongoInsertManyError = "failed to insert many documents"
+const MsgGomongoUpdateError = "failed to update documents"
+const MsgGomongoReplaceError = "failed to replace document"
+const MsgGomongoBulkWriteError = "failed on bulk write"
+const MsgGomongoCountError = "failed to count documents"
 const MsgGomongoFetchError = "failed to fetch documents from database"
 const MsgGomongoDeleteError = "failed to delete documents"
 const MsgGomongoCommandError = "failed to run command"
 const MsgGomongoIndexError = "index command failed"
+const MsgGomongoExplainError = "failed to explain query"
+const MsgGomongoExportError = "failed to export documents"
+const MsgGomongoImportError = "failed to import documents"
+const MsgGomongoCircuitOpenError = "operation rejected"
+const MsgGomongoOverloadedError = "operation not started"
+const MsgGomongoChangeStreamError = "failed to watch collection changes"
+const MsgGomongoNotFoundError = "no document with key"
+const MsgGomongoPopulateError = "failed to populate references"
+
+// ErrNotSupported is returned for operations the backend of the client can not run
+var ErrNotSupported = errors.New("operation is not supported by the client backend")
+
+// Classes of errors. A GomongoError matches them with errors.Is according to the error of the driver it wraps:
+//
+//	if errors.Is(res.Err, gomongo.ErrDuplicateKey) { ... }
+var (
+	ErrNotFound      = errors.New("document not found")
+	ErrDuplicateKey  = errors.New("duplicate key")
+	ErrTimeout       = errors.New("operation timed out")
+	ErrNetwork       = errors.New("network error")
+	ErrValidation    = errors.New("document failed validation")
+	ErrWriteConflict = errors.New("write conflict")
+	ErrUnauthorized  = errors.New("unauthorized")
+)
 
+// server error codes used to classify errors
+const (
+	codeUnauthorized         = 13
+	codeAuthenticationFailed = 18
+	codeWriteConflict        = 112
+	codeDocumentValidation   = 121
+)
+
+// GomongoError is the error of the operations. Op and Collection are set when it is returned by an operation, Code
+// is the code of the first server error, or 0.
 type GomongoError struct {
-	Err      error
-	mongoErr error
+	Err        error
+	Op         string
+	Collection string
+	Code       int
+	mongoErr   error
 }
 
 func (ge *GomongoError) Error() string {
@@ -25,9 +74,192 @@ func (ge *GomongoError) Unwrap() error {
 	return ge.mongoErr
 }
 
+// Is tell if the error is of the class target, one of ErrNotFound, ErrDuplicateKey, ErrTimeout, ErrNetwork,
+// ErrValidation, ErrWriteConflict and ErrUnauthorized
+func (ge *GomongoError) Is(target error) bool {
+	err := ge.mongoErr
+	if err == nil {
+		return false
+	}
+	switch target {
+	case ErrNotFound:
+		return errors.Is(err, mongo.ErrNoDocuments)
+	case ErrDuplicateKey:
+		return mongo.IsDuplicateKeyError(err)
+	case ErrTimeout:
+		return mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded)
+	case ErrNetwork:
+		return mongo.IsNetworkError(err)
+	case ErrValidation:
+		return hasServerCode(err, codeDocumentValidation)
+	case ErrWriteConflict:
+		return hasServerCode(err, codeWriteConflict)
+	case ErrUnauthorized:
+		return hasServerCode(err, codeUnauthorized, codeAuthenticationFailed)
+	}
+	return false
+}
+
+// As set a **DuplicateKeyError target when the error is a duplicate key error
+func (ge *GomongoError) As(target any) bool {
+	dke, ok := target.(**DuplicateKeyError)
+	if !ok || ge.mongoErr == nil {
+		return false
+	}
+	if d := newDuplicateKeyError(ge.mongoErr); d != nil {
+		*dke = d
+		return true
+	}
+	return false
+}
+
+// setOp record the operation that returned the error, unless it is already known
+func (ge *GomongoError) setOp(op string, collName string) *GomongoError {
+	if ge.Op == "" {
+		ge.Op, ge.Collection = op, collName
+	}
+	return ge
+}
+
 func NewError(msg string, mongoerror error) *GomongoError {
+	code, _ := serverErrorCode(mongoerror)
 	return &GomongoError{
 		Err:      fmt.Errorf("%s %s", msg, mongoerror),
+		Code:     code,
 		mongoErr: mongoerror,
 	}
 }
+
+// DuplicateKeyError tell which unique index rejected a write, and the duplicated value. It is found with errors.As:
+//
+//	var dke *gomongo.DuplicateKeyError
+//	if errors.As(res.Err, &dke) {
+//		log.Printf("%v already exists", dke.KeyValue)
+//	}
+type DuplicateKeyError struct {
+	// KeyPattern is the key of the index, as {email: 1}
+	KeyPattern bson.D
+	// KeyValue is the value that already exists, as {email: "a@b.c"}
+	KeyValue bson.D
+	Message  string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return e.Message
+}
+
+// Is match ErrDuplicateKey
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// newDuplicateKeyError return the first duplicate key error of err, or nil
+func newDuplicateKeyError(err error) *DuplicateKeyError {
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	var raws []bson.Raw
+	var msgs []string
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		raws, msgs = append(raws, cmdErr.Raw), append(msgs, cmdErr.Message)
+	}
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, we := range writeErr.WriteErrors {
+			raws, msgs = append(raws, we.Raw), append(msgs, we.Message)
+		}
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			raws, msgs = append(raws, we.Raw), append(msgs, we.Message)
+		}
+	}
+
+	ret := &DuplicateKeyError{Message: err.Error()}
+	for i, raw := range raws {
+		var doc struct {
+			Code       int    `bson:"code"`
+			KeyPattern bson.D `bson:"keyPattern"`
+			KeyValue   bson.D `bson:"keyValue"`
+		}
+		if len(raw) == 0 || bson.Unmarshal(raw, &doc) != nil || doc.KeyPattern == nil {
+			continue
+		}
+		ret.KeyPattern, ret.KeyValue, ret.Message = doc.KeyPattern, doc.KeyValue, msgs[i]
+		break
+	}
+	return ret
+}
+
+// retryableCodes are the server codes of transient errors, such as a primary step down or a write conflict
+var retryableCodes = map[int]bool{
+	6: true, 7: true, 89: true, 91: true, codeWriteConflict: true, 189: true, 262: true, 9001: true,
+	10107: true, 11600: true, 11602: true, 13435: true, 13436: true,
+}
+
+// IsRetryable tell if running again the operation that failed with err may succeed: network errors, timeouts, errors
+// labeled RetryableWriteError or TransientTransactionError, and server errors such as a primary step down
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var le mongo.LabeledError
+	if errors.As(err, &le) && (le.HasErrorLabel("RetryableWriteError") || le.HasErrorLabel("TransientTransactionError")) {
+		return true
+	}
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		for code := range retryableCodes {
+			if se.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasServerCode tell if err hold a server error with one of codes
+func hasServerCode(err error, codes ...int) bool {
+	var se mongo.ServerError
+	if !errors.As(err, &se) {
+		return false
+	}
+	for _, code := range codes {
+		if se.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverErrorCode return the code of the first server error found in err
+func serverErrorCode(err error) (int, bool) {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return int(cmdErr.Code), true
+	}
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		if len(writeErr.WriteErrors) > 0 {
+			return writeErr.WriteErrors[0].Code, true
+		}
+		if writeErr.WriteConcernError != nil {
+			return writeErr.WriteConcernError.Code, true
+		}
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		if len(bulkErr.WriteErrors) > 0 {
+			return bulkErr.WriteErrors[0].Code, true
+		}
+		if bulkErr.WriteConcernError != nil {
+			return bulkErr.WriteConcernError.Code, true
+		}
+	}
+	return 0, false
+}
@@ -1,8 +1,13 @@
 package gomongo
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func withWrapTest(t *testing.T) {
@@ -25,8 +30,76 @@ func noWrapTest(t *testing.T) {
 	}
 }
 
+func classifyTest(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected error
+	}{
+		{mongo.ErrNoDocuments, ErrNotFound},
+		{mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000 duplicate key"}}}, ErrDuplicateKey},
+		{fmt.Errorf("find: %w", context.DeadlineExceeded), ErrTimeout},
+		{mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}}, ErrNetwork},
+		{mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}}}, ErrValidation},
+		{mongo.CommandError{Code: 112, Name: "WriteConflict"}, ErrWriteConflict},
+		{mongo.CommandError{Code: 13, Name: "Unauthorized"}, ErrUnauthorized},
+	}
+	classes := []error{ErrNotFound, ErrDuplicateKey, ErrTimeout, ErrNetwork, ErrValidation, ErrWriteConflict, ErrUnauthorized}
+	for _, c := range cases {
+		err := error(NewError("op failed", c.err))
+		for _, class := range classes {
+			if errors.Is(err, class) != (class == c.expected) {
+				t.Errorf("%v: errors.Is(%v) should be %t", c.err, class, class == c.expected)
+			}
+		}
+	}
+	if errors.Is(NewError("op failed", nil), ErrNotFound) {
+		t.Errorf("an error without cause should not be classified")
+	}
+}
+
+func duplicateKeyTest(t *testing.T) {
+	raw, _ := bson.Marshal(bson.D{
+		{Key: "code", Value: 11000},
+		{Key: "keyPattern", Value: bson.D{{Key: "email", Value: 1}}},
+		{Key: "keyValue", Value: bson.D{{Key: "email", Value: "a@b.c"}}},
+	})
+	err := error(NewError(MsgGomongoInsertError, mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000", Raw: raw}}}))
+
+	var dke *DuplicateKeyError
+	if !errors.As(err, &dke) {
+		t.Fatalf("expected a duplicate key error in %v", err)
+	}
+	if len(dke.KeyPattern) != 1 || dke.KeyPattern[0].Key != "email" || dke.KeyValue[0].Value != "a@b.c" {
+		t.Errorf("unexpected key pattern %v and value %v", dke.KeyPattern, dke.KeyValue)
+	}
+	if !errors.Is(dke, ErrDuplicateKey) {
+		t.Errorf("duplicate key error should match ErrDuplicateKey")
+	}
+	if ge := NewError(MsgGomongoInsertError, mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}); ge.Code != 11000 {
+		t.Errorf("expected code 11000, got %d", ge.Code)
+	}
+	if errors.As(NewError("op failed", errors.New("plain")), &dke) {
+		t.Errorf("plain error should not be a duplicate key error")
+	}
+}
+
+func opTest(t *testing.T) {
+	c := NewClient("localhost", "test_errors")
+	op := &Operation{Name: OpInsertOne, Collection: "users"}
+	err := c.run(op, func(ctx context.Context) error {
+		return NewError(MsgGomongoInsertError, errors.New("failed"))
+	})
+	var ge *GomongoError
+	if !errors.As(err, &ge) || ge.Op != OpInsertOne || ge.Collection != "users" {
+		t.Errorf("expected the operation in the error, got %+v", ge)
+	}
+}
+
 func TestGomongoError(t *testing.T) {
 	t.Run("wrap", withWrapTest)
 	t.Run("no-wrap", noWrapTest)
+	t.Run("classify", classifyTest)
+	t.Run("duplicate-key", duplicateKeyTest)
+	t.Run("op", opTest)
 
 }
@@ -0,0 +1,347 @@
+package gomongo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExplainVerbosity is the mode of the explain command
+type ExplainVerbosity string
+
+const (
+	ExplainQueryPlanner      ExplainVerbosity = "queryPlanner"
+	ExplainExecutionStats    ExplainVerbosity = "executionStats"
+	ExplainAllPlansExecution ExplainVerbosity = "allPlansExecution"
+)
+
+// ExplainPlan is a query plan chosen or considered by the server
+type ExplainPlan struct {
+	// Stages lists the plan stages from the root, such as FETCH, IXSCAN or COLLSCAN
+	Stages []string
+	// Indexes are the names of the indexes the plan scans
+	Indexes []string
+}
+
+// CollScan tells if the plan scans the whole collection
+func (p ExplainPlan) CollScan() bool {
+	for _, s := range p.Stages {
+		if s == "COLLSCAN" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExplainResult summarizes the output of the explain command. Execution statistics are only
+// set when the verbosity is ExplainExecutionStats or ExplainAllPlansExecution.
+type ExplainResult struct {
+	WinningPlan   ExplainPlan
+	RejectedPlans []ExplainPlan
+	// IndexName is the first index used by the winning plan, empty when none is used
+	IndexName     string
+	CollScan      bool
+	DocsExamined  int64
+	KeysExamined  int64
+	Returned      int64
+	ExecutionTime time.Duration
+	Raw           bson.Raw
+	Err           error
+}
+
+// ExplainFindSync explain the plan of FindSync with the same filter and options
+func ExplainFindSync(c *Client, collName string, filter interface{}, verbosity ExplainVerbosity, opts ...*options.FindOptions) ExplainResult {
+	cmd := bson.D{{Key: "find", Value: collName}, {Key: "filter", Value: emptyIfNil(filter)}}
+	fields := map[string]interface{}{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		setIf(fields, "sort", opt.Sort)
+		setIf(fields, "projection", opt.Projection)
+		setIf(fields, "hint", opt.Hint)
+		setIf(fields, "min", opt.Min)
+		setIf(fields, "max", opt.Max)
+		setIf(fields, "let", opt.Let)
+		if opt.Limit != nil {
+			fields["limit"] = *opt.Limit
+		}
+		if opt.Skip != nil {
+			fields["skip"] = *opt.Skip
+		}
+		if opt.AllowDiskUse != nil {
+			fields["allowDiskUse"] = *opt.AllowDiskUse
+		}
+		if opt.Collation != nil {
+			fields["collation"] = collationDoc(opt.Collation)
+		}
+		if opt.MaxTime != nil {
+			fields["maxTimeMS"] = opt.MaxTime.Milliseconds()
+		}
+	}
+	return explain(c, collName, filter, appendFields(cmd, fields), verbosity)
+}
+
+// ExplainAggregateSync explain the plan of an aggregation pipeline
+func ExplainAggregateSync(c *Client, collName string, pipeline interface{}, verbosity ExplainVerbosity, opts ...*options.AggregateOptions) ExplainResult {
+	if pipeline == nil {
+		pipeline = bson.A{}
+	}
+	cmd := bson.D{{Key: "aggregate", Value: collName}, {Key: "pipeline", Value: pipeline}, {Key: "cursor", Value: bson.D{}}}
+	fields := map[string]interface{}{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		setIf(fields, "hint", opt.Hint)
+		setIf(fields, "let", opt.Let)
+		if opt.AllowDiskUse != nil {
+			fields["allowDiskUse"] = *opt.AllowDiskUse
+		}
+		if opt.Collation != nil {
+			fields["collation"] = collationDoc(opt.Collation)
+		}
+		if opt.MaxTime != nil {
+			fields["maxTimeMS"] = opt.MaxTime.Milliseconds()
+		}
+	}
+	return explain(c, collName, pipeline, appendFields(cmd, fields), verbosity)
+}
+
+// ExplainCountSync explain the plan of CountDocumentsSync with the same filter and options
+func ExplainCountSync(c *Client, collName string, filter interface{}, verbosity ExplainVerbosity, opts ...*options.CountOptions) ExplainResult {
+	cmd := bson.D{{Key: "count", Value: collName}, {Key: "query", Value: emptyIfNil(filter)}}
+	fields := map[string]interface{}{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		setIf(fields, "hint", opt.Hint)
+		if opt.Limit != nil {
+			fields["limit"] = *opt.Limit
+		}
+		if opt.Skip != nil {
+			fields["skip"] = *opt.Skip
+		}
+		if opt.Collation != nil {
+			fields["collation"] = collationDoc(opt.Collation)
+		}
+		if opt.MaxTime != nil {
+			fields["maxTimeMS"] = opt.MaxTime.Milliseconds()
+		}
+	}
+	return explain(c, collName, filter, appendFields(cmd, fields), verbosity)
+}
+
+// ExplainUpdateOneSync explain the plan of UpdateOneSync with the same filter, instruction and options.
+// The update is not applied, even with ExplainExecutionStats.
+func ExplainUpdateOneSync(c *Client, collName string, filter interface{}, instruction interface{}, verbosity ExplainVerbosity, opts ...*options.UpdateOptions) ExplainResult {
+	return explainUpdate(c, collName, filter, instruction, false, verbosity, opts...)
+}
+
+// ExplainUpdateManySync explain the plan of UpdateManySync with the same filter, instruction and options.
+// The update is not applied, even with ExplainExecutionStats.
+func ExplainUpdateManySync(c *Client, collName string, filter interface{}, instruction interface{}, verbosity ExplainVerbosity, opts ...*options.UpdateOptions) ExplainResult {
+	return explainUpdate(c, collName, filter, instruction, true, verbosity, opts...)
+}
+
+// explainUpdate explain an update of a single document, or of every matching document when multi is set
+func explainUpdate(c *Client, collName string, filter interface{}, instruction interface{}, multi bool, verbosity ExplainVerbosity, opts ...*options.UpdateOptions) ExplainResult {
+	update := bson.D{{Key: "q", Value: emptyIfNil(filter)}, {Key: "u", Value: instruction}, {Key: "multi", Value: multi}}
+	fields := map[string]interface{}{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		setIf(fields, "hint", opt.Hint)
+		if opt.Upsert != nil {
+			fields["upsert"] = *opt.Upsert
+		}
+		if opt.ArrayFilters != nil {
+			fields["arrayFilters"] = opt.ArrayFilters.Filters
+		}
+		if opt.Collation != nil {
+			fields["collation"] = collationDoc(opt.Collation)
+		}
+	}
+	update = appendFields(update, fields)
+	cmd := bson.D{{Key: "update", Value: collName}, {Key: "updates", Value: bson.A{update}}}
+	return explain(c, collName, filter, cmd, verbosity)
+}
+
+func explain(c *Client, collName string, filter interface{}, cmd bson.D, verbosity ExplainVerbosity) ExplainResult {
+	if verbosity == "" {
+		verbosity = ExplainQueryPlanner
+	}
+	op := &Operation{Name: OpExplain, Collection: collName, Filter: filter}
+	var raw bson.Raw
+	err := c.run(op, func(ctx context.Context) error {
+		conn, err := c.GetMongoClient()
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		explainCmd := bson.D{{Key: "explain", Value: cmd}, {Key: "verbosity", Value: string(verbosity)}}
+		if op.Comment != "" {
+			explainCmd = append(explainCmd, bson.E{Key: "comment", Value: op.Comment})
+		}
+		raw, err = conn.Database(c.database).RunCommand(ctx, explainCmd).Raw()
+		if err != nil {
+			return NewError(MsgGomongoExplainError, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ExplainResult{Err: err}
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return ExplainResult{Raw: raw, Err: NewError(MsgGomongoUnmarshalError, err)}
+	}
+	return parseExplain(doc, raw)
+}
+
+func parseExplain(doc bson.M, raw bson.Raw) ExplainResult {
+	ret := ExplainResult{Raw: raw}
+
+	planner, stats := asMap(doc["queryPlanner"]), asMap(doc["executionStats"])
+	if planner == nil {
+		// aggregations that are not fully pushed down report the query plan in their $cursor stage
+		for _, s := range asArray(doc["stages"]) {
+			if cursor := asMap(asMap(s)["$cursor"]); cursor != nil {
+				planner, stats = asMap(cursor["queryPlanner"]), asMap(cursor["executionStats"])
+				break
+			}
+		}
+	}
+
+	if planner != nil {
+		ret.WinningPlan = walkPlan(asMap(planner["winningPlan"]))
+		for _, p := range asArray(planner["rejectedPlans"]) {
+			ret.RejectedPlans = append(ret.RejectedPlans, walkPlan(asMap(p)))
+		}
+	}
+	if len(ret.WinningPlan.Indexes) > 0 {
+		ret.IndexName = ret.WinningPlan.Indexes[0]
+	}
+	ret.CollScan = ret.WinningPlan.CollScan()
+
+	if stats != nil {
+		ret.Returned = asInt64(stats["nReturned"])
+		ret.DocsExamined = asInt64(stats["totalDocsExamined"])
+		ret.KeysExamined = asInt64(stats["totalKeysExamined"])
+		ret.ExecutionTime = time.Duration(asInt64(stats["executionTimeMillis"])) * time.Millisecond
+	}
+	return ret
+}
+
+// walkPlan collect the stages and indexes of a plan tree
+func walkPlan(node bson.M) ExplainPlan {
+	var plan ExplainPlan
+	var walk func(n bson.M)
+	walk = func(n bson.M) {
+		if n == nil {
+			return
+		}
+		// slot based execution wraps the classic plan in queryPlan
+		if qp := asMap(n["queryPlan"]); qp != nil {
+			walk(qp)
+			return
+		}
+		if stage, ok := n["stage"].(string); ok {
+			plan.Stages = append(plan.Stages, stage)
+		}
+		if idx, ok := n["indexName"].(string); ok {
+			plan.Indexes = append(plan.Indexes, idx)
+		}
+		walk(asMap(n["inputStage"]))
+		for _, in := range asArray(n["inputStages"]) {
+			walk(asMap(in))
+		}
+		for _, shard := range asArray(n["shards"]) {
+			walk(asMap(asMap(shard)["winningPlan"]))
+		}
+	}
+	walk(node)
+	return plan
+}
+
+func asMap(v interface{}) bson.M {
+	m, _ := v.(bson.M)
+	return m
+}
+
+func asArray(v interface{}) bson.A {
+	a, _ := v.(bson.A)
+	return a
+}
+
+func asInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+func emptyIfNil(filter interface{}) interface{} {
+	if filter == nil {
+		return bson.D{}
+	}
+	return filter
+}
+
+func setIf(fields map[string]interface{}, key string, value interface{}) {
+	if value != nil {
+		fields[key] = value
+	}
+}
+
+// appendFields append fields to doc sorted by name
+func appendFields(doc bson.D, fields map[string]interface{}) bson.D {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc = append(doc, bson.E{Key: k, Value: fields[k]})
+	}
+	return doc
+}
+
+func collationDoc(co *options.Collation) bson.D {
+	doc := bson.D{{Key: "locale", Value: co.Locale}}
+	if co.CaseLevel {
+		doc = append(doc, bson.E{Key: "caseLevel", Value: true})
+	}
+	if co.CaseFirst != "" {
+		doc = append(doc, bson.E{Key: "caseFirst", Value: co.CaseFirst})
+	}
+	if co.Strength != 0 {
+		doc = append(doc, bson.E{Key: "strength", Value: co.Strength})
+	}
+	if co.NumericOrdering {
+		doc = append(doc, bson.E{Key: "numericOrdering", Value: true})
+	}
+	if co.Alternate != "" {
+		doc = append(doc, bson.E{Key: "alternate", Value: co.Alternate})
+	}
+	if co.MaxVariable != "" {
+		doc = append(doc, bson.E{Key: "maxVariable", Value: co.MaxVariable})
+	}
+	if co.Normalization {
+		doc = append(doc, bson.E{Key: "normalization", Value: true})
+	}
+	if co.Backwards {
+		doc = append(doc, bson.E{Key: "backwards", Value: true})
+	}
+	return doc
+}
@@ -0,0 +1,81 @@
+package gomongo
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func explainDoc(t *testing.T, doc bson.D) (bson.M, bson.Raw) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m, raw
+}
+
+func testParseExplainIndex(t *testing.T) {
+	doc, raw := explainDoc(t, bson.D{
+		{Key: "queryPlanner", Value: bson.D{
+			{Key: "winningPlan", Value: bson.D{
+				{Key: "stage", Value: "FETCH"},
+				{Key: "inputStage", Value: bson.D{{Key: "stage", Value: "IXSCAN"}, {Key: "indexName", Value: "name_1"}}},
+			}},
+			{Key: "rejectedPlans", Value: bson.A{
+				bson.D{{Key: "stage", Value: "COLLSCAN"}},
+			}},
+		}},
+		{Key: "executionStats", Value: bson.D{
+			{Key: "nReturned", Value: int32(2)},
+			{Key: "executionTimeMillis", Value: int32(7)},
+			{Key: "totalKeysExamined", Value: int32(2)},
+			{Key: "totalDocsExamined", Value: int64(2)},
+		}},
+	})
+	res := parseExplain(doc, raw)
+	if res.CollScan || res.IndexName != "name_1" {
+		t.Errorf("unexpected plan %+v", res.WinningPlan)
+	}
+	if len(res.WinningPlan.Stages) != 2 || res.WinningPlan.Stages[1] != "IXSCAN" {
+		t.Errorf("unexpected stages %v", res.WinningPlan.Stages)
+	}
+	if len(res.RejectedPlans) != 1 || !res.RejectedPlans[0].CollScan() {
+		t.Errorf("unexpected rejected plans %+v", res.RejectedPlans)
+	}
+	if res.Returned != 2 || res.DocsExamined != 2 || res.KeysExamined != 2 || res.ExecutionTime != 7*time.Millisecond {
+		t.Errorf("unexpected execution stats %+v", res)
+	}
+	if len(res.Raw) == 0 {
+		t.Errorf("raw document missing")
+	}
+}
+
+func testParseExplainAggregate(t *testing.T) {
+	doc, raw := explainDoc(t, bson.D{
+		{Key: "stages", Value: bson.A{
+			bson.D{{Key: "$cursor", Value: bson.D{
+				{Key: "queryPlanner", Value: bson.D{
+					{Key: "winningPlan", Value: bson.D{
+						{Key: "queryPlan", Value: bson.D{{Key: "stage", Value: "COLLSCAN"}}},
+					}},
+				}},
+				{Key: "executionStats", Value: bson.D{{Key: "totalDocsExamined", Value: int32(1000)}}},
+			}}},
+			bson.D{{Key: "$group", Value: bson.D{}}},
+		}},
+	})
+	res := parseExplain(doc, raw)
+	if !res.CollScan || res.IndexName != "" || res.DocsExamined != 1000 {
+		t.Errorf("unexpected aggregate explain %+v", res)
+	}
+}
+
+func TestGomongoExplainParse(t *testing.T) {
+	t.Run("index", testParseExplainIndex)
+	t.Run("aggregate", testParseExplainAggregate)
+}
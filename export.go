@@ -0,0 +1,232 @@
+package gomongo
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportFormat is the output format of ExportSync
+type ExportFormat string
+
+const (
+	// ExportJSONL write one Extended JSON document per line
+	ExportJSONL ExportFormat = "jsonl"
+	// ExportJSONArray write a JSON array of Extended JSON documents
+	ExportJSONArray ExportFormat = "json"
+	// ExportCSV write the ExportOptions.Fields of each document as a CSV row
+	ExportCSV ExportFormat = "csv"
+	// ExportBSON write the documents as concatenated BSON, like the .bson files of mongodump
+	ExportBSON ExportFormat = "bson"
+)
+
+// DefaultExportProgressEvery is how many documents are exported between calls to ExportOptions.Progress
+const DefaultExportProgressEvery = 1000
+
+// ExportDateLayout is the layout of dates in CSV exports
+const ExportDateLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// ErrExportNoFields is returned when a CSV export has no field list
+var ErrExportNoFields = errors.New("csv export needs a list of fields")
+
+// ExportOptions of ExportSync
+type ExportOptions struct {
+	// Canonical write canonical Extended JSON instead of relaxed
+	Canonical bool
+	// Fields are the columns of a CSV export. Nested fields use dotted paths, such as address.city or grades.0.score.
+	Fields []string
+	// NoHeader omit the header line of a CSV export
+	NoHeader bool
+	// Find are the options of the query, such as a sort or a projection
+	Find *options.FindOptions
+	// Progress is called with the number of documents exported, every ProgressEvery documents and at the end
+	Progress      func(exported int64)
+	ProgressEvery int64
+}
+
+type ExportResult struct {
+	Count int64
+	Err   error
+}
+
+// ExportSync write the documents of collName matching filter to w, one at a time, so memory use does not grow
+// with the size of the collection.
+//
+// Values of a CSV export are written as text: dates in ExportDateLayout, ObjectIDs in hex, and embedded documents
+// and arrays as relaxed Extended JSON. Missing fields and nulls are empty.
+func ExportSync(c *Client, collName string, filter interface{}, w io.Writer, format ExportFormat, opts ...ExportOptions) ExportResult {
+	var opt ExportOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.ProgressEvery <= 0 {
+		opt.ProgressEvery = DefaultExportProgressEvery
+	}
+
+	enc, err := newExportEncoder(w, format, opt)
+	if err != nil {
+		return ExportResult{Err: NewError(MsgGomongoExportError, err)}
+	}
+
+	var findOpts []*options.FindOptions
+	if opt.Find != nil {
+		findOpts = append(findOpts, opt.Find)
+	}
+	var count int64
+	if err := enc.begin(); err != nil {
+		return ExportResult{Err: NewError(MsgGomongoExportError, err)}
+	}
+	// breaking out of the loop closes the cursor. On an error the documents counted so far are flushed to w.
+	for doc, err := range FindIter[bson.Raw](c.parentCtx(), c, collName, filter, findOpts...) {
+		if err != nil {
+			enc.flush()
+			return ExportResult{Count: count, Err: err}
+		}
+		if err := enc.encode(doc); err != nil {
+			enc.flush()
+			return ExportResult{Count: count, Err: NewError(MsgGomongoExportError, err)}
+		}
+		count++
+		if opt.Progress != nil && count%opt.ProgressEvery == 0 {
+			opt.Progress(count)
+		}
+	}
+	if err := enc.end(); err != nil {
+		return ExportResult{Count: count, Err: NewError(MsgGomongoExportError, err)}
+	}
+	if opt.Progress != nil && count%opt.ProgressEvery != 0 {
+		opt.Progress(count)
+	}
+	return ExportResult{Count: count}
+}
+
+// exportEncoder write documents in one format
+type exportEncoder struct {
+	w      *bufio.Writer
+	csv    *csv.Writer
+	format ExportFormat
+	opt    ExportOptions
+	count  int64
+}
+
+func newExportEncoder(w io.Writer, format ExportFormat, opt ExportOptions) (*exportEncoder, error) {
+	enc := &exportEncoder{w: bufio.NewWriter(w), format: format, opt: opt}
+	switch format {
+	case ExportJSONL, ExportJSONArray, ExportBSON:
+	case ExportCSV:
+		if len(opt.Fields) == 0 {
+			return nil, ErrExportNoFields
+		}
+		enc.csv = csv.NewWriter(enc.w)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	return enc, nil
+}
+
+func (enc *exportEncoder) begin() error {
+	switch enc.format {
+	case ExportJSONArray:
+		_, err := enc.w.WriteString("[")
+		return err
+	case ExportCSV:
+		if !enc.opt.NoHeader {
+			return enc.csv.Write(enc.opt.Fields)
+		}
+	}
+	return nil
+}
+
+func (enc *exportEncoder) encode(doc bson.Raw) error {
+	switch enc.format {
+	case ExportBSON:
+		_, err := enc.w.Write(doc)
+		return err
+	case ExportCSV:
+		row := make([]string, len(enc.opt.Fields))
+		for i, field := range enc.opt.Fields {
+			v, err := doc.LookupErr(strings.Split(field, ".")...)
+			if err != nil {
+				continue
+			}
+			row[i] = csvValue(v)
+		}
+		return enc.csv.Write(row)
+	}
+
+	out, err := bson.MarshalExtJSON(doc, enc.opt.Canonical, false)
+	if err != nil {
+		return err
+	}
+	if enc.format == ExportJSONArray && enc.count > 0 {
+		if _, err := enc.w.WriteString(","); err != nil {
+			return err
+		}
+	}
+	if _, err := enc.w.Write(out); err != nil {
+		return err
+	}
+	enc.count++
+	if enc.format == ExportJSONL {
+		return enc.w.WriteByte('\n')
+	}
+	return nil
+}
+
+func (enc *exportEncoder) end() error {
+	if enc.format == ExportJSONArray {
+		if _, err := enc.w.WriteString("]\n"); err != nil {
+			return err
+		}
+	}
+	return enc.flush()
+}
+
+// flush write the buffered output to w
+func (enc *exportEncoder) flush() error {
+	if enc.csv != nil {
+		enc.csv.Flush()
+		if err := enc.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return enc.w.Flush()
+}
+
+// csvValue return the text of a value in a CSV export
+func csvValue(v bson.RawValue) string {
+	switch v.Type {
+	case bsontype.String:
+		return v.StringValue()
+	case bsontype.Int32:
+		return strconv.FormatInt(int64(v.Int32()), 10)
+	case bsontype.Int64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case bsontype.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	case bsontype.Boolean:
+		return strconv.FormatBool(v.Boolean())
+	case bsontype.DateTime:
+		return time.UnixMilli(v.DateTime()).UTC().Format(ExportDateLayout)
+	case bsontype.ObjectID:
+		return v.ObjectID().Hex()
+	case bsontype.Decimal128:
+		return v.Decimal128().String()
+	case bsontype.Null, bsontype.Undefined:
+		return ""
+	}
+	out, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return v.String()
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(out), `{"v":`), "}")
+}
@@ -0,0 +1,141 @@
+package gomongo_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func exportClient(t *testing.T) *gomongo.Client {
+	c := memdb.NewClient("test_export")
+	opened := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	docs := []bson.D{
+		{{Key: "_id", Value: 1}, {Key: "name", Value: "Café, \"Le\""}, {Key: "opened", Value: opened}, {Key: "address", Value: bson.D{{Key: "city", Value: "paris"}}}, {Key: "tags", Value: bson.A{"a", "b"}}},
+		{{Key: "_id", Value: 2}, {Key: "name", Value: "Pizza"}, {Key: "score", Value: 4.5}},
+	}
+	if res := gomongo.InsertManySync(c, "restaurants", docs); res.Err != nil {
+		t.Fatalf("failed to insert: %s", res.Err)
+	}
+	return c
+}
+
+func TestGomongoExport(t *testing.T) {
+	c := exportClient(t)
+	sorted := gomongo.ExportOptions{Find: options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})}
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+		res := gomongo.ExportSync(c, "restaurants", bson.M{}, &buf, gomongo.ExportJSONL, sorted)
+		if res.Err != nil || res.Count != 2 {
+			t.Fatalf("export failed: %d %v", res.Count, res.Err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[0], `"opened":{"$date":"2023-11-14T22:13:20Z"}`) {
+			t.Fatalf("unexpected relaxed output %q", buf.String())
+		}
+	})
+
+	t.Run("canonical array", func(t *testing.T) {
+		var buf bytes.Buffer
+		opt := sorted
+		opt.Canonical = true
+		res := gomongo.ExportSync(c, "restaurants", bson.M{}, &buf, gomongo.ExportJSONArray, opt)
+		if res.Err != nil {
+			t.Fatalf("export failed: %s", res.Err)
+		}
+		var out struct {
+			Docs []bson.M `bson:"docs"`
+		}
+		if err := bson.UnmarshalExtJSON([]byte(`{"docs":`+buf.String()+`}`), true, &out); err != nil || len(out.Docs) != 2 {
+			t.Fatalf("invalid array output %q: %v", buf.String(), err)
+		}
+		if !strings.Contains(buf.String(), `{"$numberInt":"1"}`) {
+			t.Fatalf("expected canonical numbers in %q", buf.String())
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		opt := sorted
+		opt.Fields = []string{"_id", "name", "address.city", "tags", "tags.1", "opened", "score"}
+		res := gomongo.ExportSync(c, "restaurants", bson.M{}, &buf, gomongo.ExportCSV, opt)
+		if res.Err != nil {
+			t.Fatalf("export failed: %s", res.Err)
+		}
+		expected := "_id,name,address.city,tags,tags.1,opened,score\n" +
+			`1,"Café, ""Le""",paris,"[""a"",""b""]",b,2023-11-14T22:13:20.000Z,` + "\n" +
+			"2,Pizza,,,,,4.5\n"
+		if buf.String() != expected {
+			t.Fatalf("unexpected csv\n%s\nexpected\n%s", buf.String(), expected)
+		}
+
+		opt.Fields = nil
+		if res := gomongo.ExportSync(c, "restaurants", bson.M{}, &buf, gomongo.ExportCSV, opt); res.Err == nil {
+			t.Fatalf("expected an error without fields")
+		}
+	})
+
+	t.Run("bson", func(t *testing.T) {
+		var buf bytes.Buffer
+		var progress []int64
+		opt := sorted
+		opt.ProgressEvery = 1
+		opt.Progress = func(n int64) { progress = append(progress, n) }
+		res := gomongo.ExportSync(c, "restaurants", bson.M{"_id": 2}, &buf, gomongo.ExportBSON, opt)
+		if res.Err != nil || res.Count != 1 || len(progress) != 1 || progress[0] != 1 {
+			t.Fatalf("export failed: %d %v %v", res.Count, progress, res.Err)
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(buf.Bytes(), &doc); err != nil || doc["name"] != "Pizza" {
+			t.Fatalf("invalid bson output: %v %v", doc, err)
+		}
+	})
+}
+
+// docsBackend serve finds with a cursor over docs, which are not validated
+type docsBackend struct {
+	docs []interface{}
+}
+
+func (b docsBackend) Collection(database string, name string) (gomongo.Collection, error) {
+	return docsCollection{docs: b.docs}, nil
+}
+
+type docsCollection struct {
+	gomongo.Collection
+	docs []interface{}
+}
+
+func (c docsCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return mongo.NewCursorFromDocuments(c.docs, nil, nil)
+}
+
+func TestGomongoExportFailure(t *testing.T) {
+	// the embedded document of the last one has an element of an unknown type
+	bad := bson.RawValue{Type: bsontype.EmbeddedDocument, Value: []byte{8, 0, 0, 0, 0x99, 'a', 0, 0}}
+	docs := []interface{}{
+		bson.D{{Key: "_id", Value: 1}},
+		bson.D{{Key: "_id", Value: 2}},
+		bson.D{{Key: "_id", Value: 3}},
+		bson.D{{Key: "_id", Value: 4}, {Key: "bad", Value: bad}},
+	}
+	c := gomongo.NewClientWithBackend("test_export", docsBackend{docs: docs})
+
+	var buf bytes.Buffer
+	res := gomongo.ExportSync(c, "restaurants", bson.M{}, &buf, gomongo.ExportJSONL)
+	if res.Err == nil || res.Count != 3 {
+		t.Fatalf("expected the error of the fourth document, got %d %v", res.Count, res.Err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Fatalf("expected the 3 counted documents to be written, got %q", buf.String())
+	}
+}
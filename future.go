@@ -0,0 +1,193 @@
+package gomongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Future is the result of an operation running in the background. Unlike the channels of the async functions, it
+// can be awaited with a deadline, read any number of times, and combined with All, Any and Then.
+//
+//	f := gomongo.FindOneFuture[User](gmc, "users", filter)
+//	res, err := f.Await(ctx)
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+}
+
+// NewFuture run fn in a goroutine and return the Future of its result
+func NewFuture[T any](fn func() T) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	go func() {
+		f.value = fn()
+		close(f.done)
+	}()
+	return f
+}
+
+// Await wait for the result, or return the error of ctx when it is done first. The operation keeps running.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Done is closed once the result is ready
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// TryGet return the result and true when it is ready, without waiting
+func (f *Future[T]) TryGet() (T, bool) {
+	select {
+	case <-f.done:
+		return f.value, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
+
+// Get wait for the result
+func (f *Future[T]) Get() T {
+	<-f.done
+	return f.value
+}
+
+// All return a Future of the results of futures, in the same order
+func All[T any](futures ...*Future[T]) *Future[[]T] {
+	return NewFuture(func() []T {
+		ret := make([]T, len(futures))
+		for i, f := range futures {
+			ret[i] = f.Get()
+		}
+		return ret
+	})
+}
+
+// Any return a Future of the first of futures to complete, and its index. It never completes when futures is empty.
+func Any[T any](futures ...*Future[T]) *Future[AnyResult[T]] {
+	first := make(chan AnyResult[T], len(futures))
+	for i, f := range futures {
+		go func() {
+			first <- AnyResult[T]{Index: i, Value: f.Get()}
+		}()
+	}
+	return NewFuture(func() AnyResult[T] {
+		return <-first
+	})
+}
+
+// AnyResult is the first Future to complete of Any
+type AnyResult[T any] struct {
+	Index int
+	Value T
+}
+
+// Then return a Future of fn applied to the result of f, to chain a dependent operation:
+//
+//	user := gomongo.FindOneFuture[User](gmc, "users", bson.M{"email": email})
+//	orders := gomongo.Then(user, func(u gomongo.ReadOneResult[User]) gomongo.ReadManyResult[Order] {
+//		return gomongo.FindSync[Order](gmc, "orders", bson.M{"user_id": u.Document.ID})
+//	})
+func Then[T any, U any](f *Future[T], fn func(T) U) *Future[U] {
+	return NewFuture(func() U {
+		return fn(f.Get())
+	})
+}
+
+// InsertManyFuture is InsertMany returning a Future
+func InsertManyFuture[T any](c *Client, collName string, documents []T, opts ...*options.InsertManyOptions) *Future[WriteManyResult] {
+	return future(c, OpInsertMany, func() WriteManyResult { return InsertManySync(c, collName, documents, opts...) })
+}
+
+// InsertOneFuture is InsertOne returning a Future
+func InsertOneFuture(c *Client, collName string, document interface{}, opts ...*options.InsertOneOptions) *Future[WriteOneResult] {
+	return future(c, OpInsertOne, func() WriteOneResult { return InsertOneSync(c, collName, document, opts...) })
+}
+
+// UpdateOneFuture is UpdateOne returning a Future
+func UpdateOneFuture(c *Client, collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) *Future[UpdateResult] {
+	return future(c, OpUpdateOne, func() UpdateResult { return UpdateOneSync(c, collName, filter, instruction, opts...) })
+}
+
+// UpdateManyFuture is UpdateMany returning a Future
+func UpdateManyFuture(c *Client, collName string, filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) *Future[UpdateResult] {
+	return future(c, OpUpdateMany, func() UpdateResult { return UpdateManySync(c, collName, filter, instruction, opts...) })
+}
+
+// BulkWriteFuture is BulkWrite returning a Future
+func BulkWriteFuture(c *Client, collName string, writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) *Future[BulkWriteResult] {
+	return future(c, OpBulkWrite, func() BulkWriteResult { return BulkWriteSync(c, collName, writeModels, opts...) })
+}
+
+// ReplaceOneFuture is ReplaceOne returning a Future
+func ReplaceOneFuture(c *Client, collName string, filter interface{}, document interface{}, opts ...*options.ReplaceOptions) *Future[UpdateResult] {
+	return future(c, OpReplaceOne, func() UpdateResult { return ReplaceOneSync(c, collName, filter, document, opts...) })
+}
+
+// FindOneFuture is FindOne returning a Future
+func FindOneFuture[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOneOptions) *Future[ReadOneResult[T]] {
+	return future(c, OpFindOne, func() ReadOneResult[T] { return FindOneSync[T](c, collName, filter, opts...) })
+}
+
+// FindFuture is Find returning a Future
+func FindFuture[T any](c *Client, collName string, filter interface{}, opts ...*options.FindOptions) *Future[ReadManyResult[T]] {
+	return future(c, OpFind, func() ReadManyResult[T] { return FindSync[T](c, collName, filter, opts...) })
+}
+
+// DistinctFuture is Distinct returning a Future
+func DistinctFuture[T any](c *Client, collName string, fieldName string, filter interface{}, opts ...*options.DistinctOptions) *Future[DistinctResult[T]] {
+	return future(c, OpDistinct, func() DistinctResult[T] { return DistinctSync[T](c, collName, fieldName, filter, opts...) })
+}
+
+// FindStreamFuture is FindStream returning a Future
+func FindStreamFuture[T any](ctx context.Context, c *Client, collName string, filter interface{}, opts ...*options.FindOptions) *Future[ReadStreamResult[T]] {
+	return future(c, OpFindStream, func() ReadStreamResult[T] { return FindStreamSync[T](ctx, c, collName, filter, opts...) })
+}
+
+// DeleteOneFuture is DeleteOne returning a Future
+func DeleteOneFuture(c *Client, collName string, filter interface{}, opts ...*options.DeleteOptions) *Future[DeleteResult] {
+	return future(c, OpDeleteOne, func() DeleteResult { return DeleteOneSync(c, collName, filter, opts...) })
+}
+
+// DeleteManyFuture is DeleteMany returning a Future
+func DeleteManyFuture(c *Client, collName string, filter interface{}, opts ...*options.DeleteOptions) *Future[DeleteResult] {
+	return future(c, OpDeleteMany, func() DeleteResult { return DeleteManySync(c, collName, filter, opts...) })
+}
+
+// CountDocumentsFuture is CountDocuments returning a Future
+func CountDocumentsFuture(c *Client, collName string, filter interface{}, opts ...*options.CountOptions) *Future[CountResult] {
+	return future(c, OpCountDocuments, func() CountResult { return CountDocumentsSync(c, collName, filter, opts...) })
+}
+
+// RunCommandFuture is RunCommand returning a Future
+func RunCommandFuture(c *Client, cmd interface{}, opts ...*options.RunCmdOptions) *Future[CommandResult] {
+	return future(c, OpRunCommand, func() CommandResult { return RunCommandSync(c, cmd, opts...) })
+}
+
+// CreateIndexFuture is CreateIndex returning a Future
+func CreateIndexFuture(c *Client, collName string, indexDef interface{}, opt *options.IndexOptions) *Future[IndexCreateResult] {
+	return future(c, OpCreateIndex, func() IndexCreateResult { return CreateIndexSync(c, collName, indexDef, opt) })
+}
+
+// DropIndexFuture is DropIndex returning a Future
+func DropIndexFuture(c *Client, collName string, indexName string, opts ...*options.DropIndexesOptions) *Future[IndexDropResult] {
+	return future(c, OpDropIndex, func() IndexDropResult { return DropIndexSync(c, collName, indexName, opts...) })
+}
+
+// DropAllIndexFuture is DropAllIndex returning a Future
+func DropAllIndexFuture(c *Client, collName string, opts ...*options.DropIndexesOptions) *Future[IndexDropResult] {
+	return future(c, OpDropAllIndex, func() IndexDropResult { return DropAllIndexSync(c, collName, opts...) })
+}
+
+// ListIndexFuture run ListIndexSync in the background
+func ListIndexFuture(c *Client, collName string, opts ...*options.ListIndexesOptions) *Future[IndexListResult] {
+	return future(c, OpListIndex, func() IndexListResult { return ListIndexSync(c, collName, opts...) })
+}
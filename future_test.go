@@ -0,0 +1,61 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGomongoFuture(t *testing.T) {
+	c := cursorClient(t, 10)
+	ctx := context.Background()
+
+	count := gomongo.CountDocumentsFuture(c, "docs", bson.M{})
+	res, err := count.Await(ctx)
+	if err != nil || res.Err != nil || res.Count != 10 {
+		t.Fatalf("unexpected count %+v %v", res, err)
+	}
+	if again, ok := count.TryGet(); !ok || again.Count != 10 {
+		t.Fatalf("a future should be read more than once")
+	}
+
+	one := gomongo.FindOneFuture[cursorDoc](c, "docs", bson.M{"_id": 3})
+	doubled := gomongo.Then(one, func(r gomongo.ReadOneResult[cursorDoc]) gomongo.CountResult {
+		return gomongo.CountDocumentsSync(c, "docs", bson.M{"_id": bson.M{"$lt": r.Document.ID * 2}})
+	})
+	if r, _ := doubled.Await(ctx); r.Count != 6 {
+		t.Fatalf("expected the chained count to be 6, got %+v", r)
+	}
+
+	all, _ := gomongo.All(
+		gomongo.InsertOneFuture(c, "docs", bson.M{"_id": 100}),
+		gomongo.InsertOneFuture(c, "docs", bson.M{"_id": 1}),
+	).Await(ctx)
+	if len(all) != 2 || all[0].Err != nil || !errors.Is(all[1].Err, gomongo.ErrDuplicateKey) {
+		t.Fatalf("unexpected results %+v", all)
+	}
+
+	slow := gomongo.NewFuture(func() int { time.Sleep(time.Second); return 1 })
+	fast := gomongo.NewFuture(func() int { return 2 })
+	if first, _ := gomongo.Any(slow, fast).Await(ctx); first.Index != 1 || first.Value != 2 {
+		t.Fatalf("expected the fast future first, got %+v", first)
+	}
+
+	if _, ok := slow.TryGet(); ok {
+		t.Fatalf("the slow future should not be done")
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := slow.Await(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	select {
+	case <-slow.Done():
+		t.Fatalf("the slow future should not be done")
+	default:
+	}
+}
@@ -1,8 +1,11 @@
 module github.com/sagiforbes/gomongo
 
-go 1.22.2
+go 1.23
 
-require go.mongodb.org/mongo-driver v1.17.0
+require (
+	go.mongodb.org/mongo-driver v1.17.0
+	golang.org/x/sync v0.8.0
+)
 
 require (
 	github.com/golang/snappy v0.0.4 // indirect
@@ -13,6 +16,5 @@ require (
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
-	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
 )
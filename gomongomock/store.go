@@ -0,0 +1,521 @@
+// Package gomongomock has a mock of gomongo.Store for testing services built on the Store, Reader, Writer
+// and Indexer interfaces. Set the Func field of a method to program its response, and read the calls it
+// received with the Calls method of the same name:
+//
+//	store := &gomongomock.Store[User]{
+//		FindOneFunc: func(filter interface{}, opts ...*options.FindOneOptions) gomongo.ReadOneResult[User] {
+//			return gomongo.ReadOneResult[User]{Found: true, Document: User{Name: "name 1"}}
+//		},
+//	}
+//	svc := NewUserService(store)
+//	...
+//	if len(store.FindOneCalls()) != 1 { ... }
+//
+// Calling a method whose Func is nil panics, so tests fail on calls they did not expect.
+package gomongomock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ gomongo.Store[any] = &Store[any]{}
+
+// Store is a mock of gomongo.Store. It is safe for concurrent use.
+type Store[T any] struct {
+	// FindOneFunc mocks the FindOne method.
+	FindOneFunc func(filter interface{}, opts ...*options.FindOneOptions) gomongo.ReadOneResult[T]
+
+	// FindFunc mocks the Find method.
+	FindFunc func(filter interface{}, opts ...*options.FindOptions) gomongo.ReadManyResult[T]
+
+	// FindStreamFunc mocks the FindStream method.
+	FindStreamFunc func(ctx context.Context, filter interface{}, opts ...*options.FindOptions) gomongo.ReadStreamResult[T]
+
+	// DistinctFunc mocks the Distinct method.
+	DistinctFunc func(fieldName string, filter interface{}, opts ...*options.DistinctOptions) gomongo.DistinctResult[interface{}]
+
+	// CountDocumentsFunc mocks the CountDocuments method.
+	CountDocumentsFunc func(filter interface{}, opts ...*options.CountOptions) gomongo.CountResult
+
+	// InsertOneFunc mocks the InsertOne method.
+	InsertOneFunc func(document T, opts ...*options.InsertOneOptions) gomongo.WriteOneResult
+
+	// InsertManyFunc mocks the InsertMany method.
+	InsertManyFunc func(documents []T, opts ...*options.InsertManyOptions) gomongo.WriteManyResult
+
+	// UpdateOneFunc mocks the UpdateOne method.
+	UpdateOneFunc func(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) gomongo.UpdateResult
+
+	// UpdateManyFunc mocks the UpdateMany method.
+	UpdateManyFunc func(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) gomongo.UpdateResult
+
+	// ReplaceOneFunc mocks the ReplaceOne method.
+	ReplaceOneFunc func(filter interface{}, document T, opts ...*options.ReplaceOptions) gomongo.UpdateResult
+
+	// BulkWriteFunc mocks the BulkWrite method.
+	BulkWriteFunc func(writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) gomongo.BulkWriteResult
+
+	// DeleteOneFunc mocks the DeleteOne method.
+	DeleteOneFunc func(filter interface{}, opts ...*options.DeleteOptions) gomongo.DeleteResult
+
+	// DeleteManyFunc mocks the DeleteMany method.
+	DeleteManyFunc func(filter interface{}, opts ...*options.DeleteOptions) gomongo.DeleteResult
+
+	// CreateIndexFunc mocks the CreateIndex method.
+	CreateIndexFunc func(indexDef interface{}, idxOpt *options.IndexOptions) gomongo.IndexCreateResult
+
+	// DropIndexFunc mocks the DropIndex method.
+	DropIndexFunc func(name string, opts ...*options.DropIndexesOptions) gomongo.IndexDropResult
+
+	// DropAllIndexFunc mocks the DropAllIndex method.
+	DropAllIndexFunc func(opts ...*options.DropIndexesOptions) gomongo.IndexDropResult
+
+	// ListIndexFunc mocks the ListIndex method.
+	ListIndexFunc func(opts ...*options.ListIndexesOptions) gomongo.IndexListResult
+
+	mu    sync.Mutex
+	calls calls[T]
+}
+
+type calls[T any] struct {
+	FindOne        []FindOneCall[T]
+	Find           []FindCall[T]
+	FindStream     []FindStreamCall[T]
+	Distinct       []DistinctCall[T]
+	CountDocuments []CountDocumentsCall[T]
+	InsertOne      []InsertOneCall[T]
+	InsertMany     []InsertManyCall[T]
+	UpdateOne      []UpdateOneCall[T]
+	UpdateMany     []UpdateManyCall[T]
+	ReplaceOne     []ReplaceOneCall[T]
+	BulkWrite      []BulkWriteCall[T]
+	DeleteOne      []DeleteOneCall[T]
+	DeleteMany     []DeleteManyCall[T]
+	CreateIndex    []CreateIndexCall[T]
+	DropIndex      []DropIndexCall[T]
+	DropAllIndex   []DropAllIndexCall[T]
+	ListIndex      []ListIndexCall[T]
+}
+
+// FindOneCall holds the arguments of a call to FindOne
+type FindOneCall[T any] struct {
+	Filter interface{}
+	Opts   []*options.FindOneOptions
+}
+
+// FindCall holds the arguments of a call to Find
+type FindCall[T any] struct {
+	Filter interface{}
+	Opts   []*options.FindOptions
+}
+
+// FindStreamCall holds the arguments of a call to FindStream
+type FindStreamCall[T any] struct {
+	Ctx    context.Context
+	Filter interface{}
+	Opts   []*options.FindOptions
+}
+
+// DistinctCall holds the arguments of a call to Distinct
+type DistinctCall[T any] struct {
+	FieldName string
+	Filter    interface{}
+	Opts      []*options.DistinctOptions
+}
+
+// CountDocumentsCall holds the arguments of a call to CountDocuments
+type CountDocumentsCall[T any] struct {
+	Filter interface{}
+	Opts   []*options.CountOptions
+}
+
+// InsertOneCall holds the arguments of a call to InsertOne
+type InsertOneCall[T any] struct {
+	Document T
+	Opts     []*options.InsertOneOptions
+}
+
+// InsertManyCall holds the arguments of a call to InsertMany
+type InsertManyCall[T any] struct {
+	Documents []T
+	Opts      []*options.InsertManyOptions
+}
+
+// UpdateOneCall holds the arguments of a call to UpdateOne
+type UpdateOneCall[T any] struct {
+	Filter      interface{}
+	Instruction interface{}
+	Opts        []*options.UpdateOptions
+}
+
+// UpdateManyCall holds the arguments of a call to UpdateMany
+type UpdateManyCall[T any] struct {
+	Filter      interface{}
+	Instruction interface{}
+	Opts        []*options.UpdateOptions
+}
+
+// ReplaceOneCall holds the arguments of a call to ReplaceOne
+type ReplaceOneCall[T any] struct {
+	Filter   interface{}
+	Document T
+	Opts     []*options.ReplaceOptions
+}
+
+// BulkWriteCall holds the arguments of a call to BulkWrite
+type BulkWriteCall[T any] struct {
+	WriteModels []mongo.WriteModel
+	Opts        []*options.BulkWriteOptions
+}
+
+// DeleteOneCall holds the arguments of a call to DeleteOne
+type DeleteOneCall[T any] struct {
+	Filter interface{}
+	Opts   []*options.DeleteOptions
+}
+
+// DeleteManyCall holds the arguments of a call to DeleteMany
+type DeleteManyCall[T any] struct {
+	Filter interface{}
+	Opts   []*options.DeleteOptions
+}
+
+// CreateIndexCall holds the arguments of a call to CreateIndex
+type CreateIndexCall[T any] struct {
+	IndexDef interface{}
+	IdxOpt   *options.IndexOptions
+}
+
+// DropIndexCall holds the arguments of a call to DropIndex
+type DropIndexCall[T any] struct {
+	Name string
+	Opts []*options.DropIndexesOptions
+}
+
+// DropAllIndexCall holds the arguments of a call to DropAllIndex
+type DropAllIndexCall[T any] struct {
+	Opts []*options.DropIndexesOptions
+}
+
+// ListIndexCall holds the arguments of a call to ListIndex
+type ListIndexCall[T any] struct {
+	Opts []*options.ListIndexesOptions
+}
+
+// FindOne record the call and return the response of FindOneFunc
+func (m *Store[T]) FindOne(filter interface{}, opts ...*options.FindOneOptions) gomongo.ReadOneResult[T] {
+	if m.FindOneFunc == nil {
+		panic("gomongomock: Store.FindOneFunc is nil but Store.FindOne was called")
+	}
+	m.mu.Lock()
+	m.calls.FindOne = append(m.calls.FindOne, FindOneCall[T]{Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.FindOneFunc(filter, opts...)
+}
+
+// FindOneCalls return the calls made to FindOne
+func (m *Store[T]) FindOneCalls() []FindOneCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]FindOneCall[T](nil), m.calls.FindOne...)
+}
+
+// Find record the call and return the response of FindFunc
+func (m *Store[T]) Find(filter interface{}, opts ...*options.FindOptions) gomongo.ReadManyResult[T] {
+	if m.FindFunc == nil {
+		panic("gomongomock: Store.FindFunc is nil but Store.Find was called")
+	}
+	m.mu.Lock()
+	m.calls.Find = append(m.calls.Find, FindCall[T]{Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.FindFunc(filter, opts...)
+}
+
+// FindCalls return the calls made to Find
+func (m *Store[T]) FindCalls() []FindCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]FindCall[T](nil), m.calls.Find...)
+}
+
+// FindStream record the call and return the response of FindStreamFunc
+func (m *Store[T]) FindStream(ctx context.Context, filter interface{}, opts ...*options.FindOptions) gomongo.ReadStreamResult[T] {
+	if m.FindStreamFunc == nil {
+		panic("gomongomock: Store.FindStreamFunc is nil but Store.FindStream was called")
+	}
+	m.mu.Lock()
+	m.calls.FindStream = append(m.calls.FindStream, FindStreamCall[T]{Ctx: ctx, Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.FindStreamFunc(ctx, filter, opts...)
+}
+
+// FindStreamCalls return the calls made to FindStream
+func (m *Store[T]) FindStreamCalls() []FindStreamCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]FindStreamCall[T](nil), m.calls.FindStream...)
+}
+
+// Distinct record the call and return the response of DistinctFunc
+func (m *Store[T]) Distinct(fieldName string, filter interface{}, opts ...*options.DistinctOptions) gomongo.DistinctResult[interface{}] {
+	if m.DistinctFunc == nil {
+		panic("gomongomock: Store.DistinctFunc is nil but Store.Distinct was called")
+	}
+	m.mu.Lock()
+	m.calls.Distinct = append(m.calls.Distinct, DistinctCall[T]{FieldName: fieldName, Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.DistinctFunc(fieldName, filter, opts...)
+}
+
+// DistinctCalls return the calls made to Distinct
+func (m *Store[T]) DistinctCalls() []DistinctCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DistinctCall[T](nil), m.calls.Distinct...)
+}
+
+// CountDocuments record the call and return the response of CountDocumentsFunc
+func (m *Store[T]) CountDocuments(filter interface{}, opts ...*options.CountOptions) gomongo.CountResult {
+	if m.CountDocumentsFunc == nil {
+		panic("gomongomock: Store.CountDocumentsFunc is nil but Store.CountDocuments was called")
+	}
+	m.mu.Lock()
+	m.calls.CountDocuments = append(m.calls.CountDocuments, CountDocumentsCall[T]{Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.CountDocumentsFunc(filter, opts...)
+}
+
+// CountDocumentsCalls return the calls made to CountDocuments
+func (m *Store[T]) CountDocumentsCalls() []CountDocumentsCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]CountDocumentsCall[T](nil), m.calls.CountDocuments...)
+}
+
+// InsertOne record the call and return the response of InsertOneFunc
+func (m *Store[T]) InsertOne(document T, opts ...*options.InsertOneOptions) gomongo.WriteOneResult {
+	if m.InsertOneFunc == nil {
+		panic("gomongomock: Store.InsertOneFunc is nil but Store.InsertOne was called")
+	}
+	m.mu.Lock()
+	m.calls.InsertOne = append(m.calls.InsertOne, InsertOneCall[T]{Document: document, Opts: opts})
+	m.mu.Unlock()
+	return m.InsertOneFunc(document, opts...)
+}
+
+// InsertOneCalls return the calls made to InsertOne
+func (m *Store[T]) InsertOneCalls() []InsertOneCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]InsertOneCall[T](nil), m.calls.InsertOne...)
+}
+
+// InsertMany record the call and return the response of InsertManyFunc
+func (m *Store[T]) InsertMany(documents []T, opts ...*options.InsertManyOptions) gomongo.WriteManyResult {
+	if m.InsertManyFunc == nil {
+		panic("gomongomock: Store.InsertManyFunc is nil but Store.InsertMany was called")
+	}
+	m.mu.Lock()
+	m.calls.InsertMany = append(m.calls.InsertMany, InsertManyCall[T]{Documents: documents, Opts: opts})
+	m.mu.Unlock()
+	return m.InsertManyFunc(documents, opts...)
+}
+
+// InsertManyCalls return the calls made to InsertMany
+func (m *Store[T]) InsertManyCalls() []InsertManyCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]InsertManyCall[T](nil), m.calls.InsertMany...)
+}
+
+// UpdateOne record the call and return the response of UpdateOneFunc
+func (m *Store[T]) UpdateOne(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) gomongo.UpdateResult {
+	if m.UpdateOneFunc == nil {
+		panic("gomongomock: Store.UpdateOneFunc is nil but Store.UpdateOne was called")
+	}
+	m.mu.Lock()
+	m.calls.UpdateOne = append(m.calls.UpdateOne, UpdateOneCall[T]{Filter: filter, Instruction: instruction, Opts: opts})
+	m.mu.Unlock()
+	return m.UpdateOneFunc(filter, instruction, opts...)
+}
+
+// UpdateOneCalls return the calls made to UpdateOne
+func (m *Store[T]) UpdateOneCalls() []UpdateOneCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]UpdateOneCall[T](nil), m.calls.UpdateOne...)
+}
+
+// UpdateMany record the call and return the response of UpdateManyFunc
+func (m *Store[T]) UpdateMany(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) gomongo.UpdateResult {
+	if m.UpdateManyFunc == nil {
+		panic("gomongomock: Store.UpdateManyFunc is nil but Store.UpdateMany was called")
+	}
+	m.mu.Lock()
+	m.calls.UpdateMany = append(m.calls.UpdateMany, UpdateManyCall[T]{Filter: filter, Instruction: instruction, Opts: opts})
+	m.mu.Unlock()
+	return m.UpdateManyFunc(filter, instruction, opts...)
+}
+
+// UpdateManyCalls return the calls made to UpdateMany
+func (m *Store[T]) UpdateManyCalls() []UpdateManyCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]UpdateManyCall[T](nil), m.calls.UpdateMany...)
+}
+
+// ReplaceOne record the call and return the response of ReplaceOneFunc
+func (m *Store[T]) ReplaceOne(filter interface{}, document T, opts ...*options.ReplaceOptions) gomongo.UpdateResult {
+	if m.ReplaceOneFunc == nil {
+		panic("gomongomock: Store.ReplaceOneFunc is nil but Store.ReplaceOne was called")
+	}
+	m.mu.Lock()
+	m.calls.ReplaceOne = append(m.calls.ReplaceOne, ReplaceOneCall[T]{Filter: filter, Document: document, Opts: opts})
+	m.mu.Unlock()
+	return m.ReplaceOneFunc(filter, document, opts...)
+}
+
+// ReplaceOneCalls return the calls made to ReplaceOne
+func (m *Store[T]) ReplaceOneCalls() []ReplaceOneCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ReplaceOneCall[T](nil), m.calls.ReplaceOne...)
+}
+
+// BulkWrite record the call and return the response of BulkWriteFunc
+func (m *Store[T]) BulkWrite(writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) gomongo.BulkWriteResult {
+	if m.BulkWriteFunc == nil {
+		panic("gomongomock: Store.BulkWriteFunc is nil but Store.BulkWrite was called")
+	}
+	m.mu.Lock()
+	m.calls.BulkWrite = append(m.calls.BulkWrite, BulkWriteCall[T]{WriteModels: writeModels, Opts: opts})
+	m.mu.Unlock()
+	return m.BulkWriteFunc(writeModels, opts...)
+}
+
+// BulkWriteCalls return the calls made to BulkWrite
+func (m *Store[T]) BulkWriteCalls() []BulkWriteCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]BulkWriteCall[T](nil), m.calls.BulkWrite...)
+}
+
+// DeleteOne record the call and return the response of DeleteOneFunc
+func (m *Store[T]) DeleteOne(filter interface{}, opts ...*options.DeleteOptions) gomongo.DeleteResult {
+	if m.DeleteOneFunc == nil {
+		panic("gomongomock: Store.DeleteOneFunc is nil but Store.DeleteOne was called")
+	}
+	m.mu.Lock()
+	m.calls.DeleteOne = append(m.calls.DeleteOne, DeleteOneCall[T]{Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.DeleteOneFunc(filter, opts...)
+}
+
+// DeleteOneCalls return the calls made to DeleteOne
+func (m *Store[T]) DeleteOneCalls() []DeleteOneCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeleteOneCall[T](nil), m.calls.DeleteOne...)
+}
+
+// DeleteMany record the call and return the response of DeleteManyFunc
+func (m *Store[T]) DeleteMany(filter interface{}, opts ...*options.DeleteOptions) gomongo.DeleteResult {
+	if m.DeleteManyFunc == nil {
+		panic("gomongomock: Store.DeleteManyFunc is nil but Store.DeleteMany was called")
+	}
+	m.mu.Lock()
+	m.calls.DeleteMany = append(m.calls.DeleteMany, DeleteManyCall[T]{Filter: filter, Opts: opts})
+	m.mu.Unlock()
+	return m.DeleteManyFunc(filter, opts...)
+}
+
+// DeleteManyCalls return the calls made to DeleteMany
+func (m *Store[T]) DeleteManyCalls() []DeleteManyCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeleteManyCall[T](nil), m.calls.DeleteMany...)
+}
+
+// CreateIndex record the call and return the response of CreateIndexFunc
+func (m *Store[T]) CreateIndex(indexDef interface{}, idxOpt *options.IndexOptions) gomongo.IndexCreateResult {
+	if m.CreateIndexFunc == nil {
+		panic("gomongomock: Store.CreateIndexFunc is nil but Store.CreateIndex was called")
+	}
+	m.mu.Lock()
+	m.calls.CreateIndex = append(m.calls.CreateIndex, CreateIndexCall[T]{IndexDef: indexDef, IdxOpt: idxOpt})
+	m.mu.Unlock()
+	return m.CreateIndexFunc(indexDef, idxOpt)
+}
+
+// CreateIndexCalls return the calls made to CreateIndex
+func (m *Store[T]) CreateIndexCalls() []CreateIndexCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]CreateIndexCall[T](nil), m.calls.CreateIndex...)
+}
+
+// DropIndex record the call and return the response of DropIndexFunc
+func (m *Store[T]) DropIndex(name string, opts ...*options.DropIndexesOptions) gomongo.IndexDropResult {
+	if m.DropIndexFunc == nil {
+		panic("gomongomock: Store.DropIndexFunc is nil but Store.DropIndex was called")
+	}
+	m.mu.Lock()
+	m.calls.DropIndex = append(m.calls.DropIndex, DropIndexCall[T]{Name: name, Opts: opts})
+	m.mu.Unlock()
+	return m.DropIndexFunc(name, opts...)
+}
+
+// DropIndexCalls return the calls made to DropIndex
+func (m *Store[T]) DropIndexCalls() []DropIndexCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DropIndexCall[T](nil), m.calls.DropIndex...)
+}
+
+// DropAllIndex record the call and return the response of DropAllIndexFunc
+func (m *Store[T]) DropAllIndex(opts ...*options.DropIndexesOptions) gomongo.IndexDropResult {
+	if m.DropAllIndexFunc == nil {
+		panic("gomongomock: Store.DropAllIndexFunc is nil but Store.DropAllIndex was called")
+	}
+	m.mu.Lock()
+	m.calls.DropAllIndex = append(m.calls.DropAllIndex, DropAllIndexCall[T]{Opts: opts})
+	m.mu.Unlock()
+	return m.DropAllIndexFunc(opts...)
+}
+
+// DropAllIndexCalls return the calls made to DropAllIndex
+func (m *Store[T]) DropAllIndexCalls() []DropAllIndexCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DropAllIndexCall[T](nil), m.calls.DropAllIndex...)
+}
+
+// ListIndex record the call and return the response of ListIndexFunc
+func (m *Store[T]) ListIndex(opts ...*options.ListIndexesOptions) gomongo.IndexListResult {
+	if m.ListIndexFunc == nil {
+		panic("gomongomock: Store.ListIndexFunc is nil but Store.ListIndex was called")
+	}
+	m.mu.Lock()
+	m.calls.ListIndex = append(m.calls.ListIndex, ListIndexCall[T]{Opts: opts})
+	m.mu.Unlock()
+	return m.ListIndexFunc(opts...)
+}
+
+// ListIndexCalls return the calls made to ListIndex
+func (m *Store[T]) ListIndexCalls() []ListIndexCall[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ListIndexCall[T](nil), m.calls.ListIndex...)
+}
+
+// Reset clear the recorded calls
+func (m *Store[T]) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = calls[T]{}
+}
@@ -0,0 +1,88 @@
+package gomongomock
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type User struct {
+	Id   string `bson:"id"`
+	Name string `bson:"name"`
+}
+
+// rename is a service written against the Store interfaces
+func rename(store gomongo.Store[User], id string, name string) error {
+	found := store.FindOne(bson.M{"id": id})
+	if found.Err != nil {
+		return found.Err
+	}
+	if !found.Found {
+		return store.InsertOne(User{Id: id, Name: name}).Err
+	}
+	return store.UpdateOne(bson.M{"id": id}, bson.M{"$set": bson.M{"name": name}}).Err
+}
+
+func TestGomongoMockStore(t *testing.T) {
+	store := &Store[User]{
+		FindOneFunc: func(filter interface{}, opts ...*options.FindOneOptions) gomongo.ReadOneResult[User] {
+			return gomongo.ReadOneResult[User]{Found: true, Document: User{Id: "1", Name: "old"}}
+		},
+		UpdateOneFunc: func(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) gomongo.UpdateResult {
+			return gomongo.UpdateResult{}
+		},
+	}
+
+	if err := rename(store, "1", "new"); err != nil {
+		t.Fatalf("rename failed: %s", err)
+	}
+	if calls := store.FindOneCalls(); len(calls) != 1 || calls[0].Filter.(bson.M)["id"] != "1" {
+		t.Fatalf("unexpected FindOne calls %v", calls)
+	}
+	calls := store.UpdateOneCalls()
+	if len(calls) != 1 || calls[0].Instruction.(bson.M)["$set"].(bson.M)["name"] != "new" {
+		t.Fatalf("unexpected UpdateOne calls %v", calls)
+	}
+
+	store.Reset()
+	if len(store.FindOneCalls()) != 0 {
+		t.Fatalf("expected reset to clear the calls")
+	}
+
+	failure := errors.New("boom")
+	store.FindOneFunc = func(filter interface{}, opts ...*options.FindOneOptions) gomongo.ReadOneResult[User] {
+		return gomongo.ReadOneResult[User]{Err: failure}
+	}
+	if err := rename(store, "1", "new"); !errors.Is(err, failure) {
+		t.Fatalf("expected the programmed error, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic on a call without Func")
+		}
+	}()
+	store.InsertOne(User{})
+}
+
+func TestGomongoClientStore(t *testing.T) {
+	store := gomongo.NewStore[User](memdb.NewClient("test_db"), "users")
+
+	if err := rename(store, "1", "first"); err != nil {
+		t.Fatalf("rename failed: %s", err)
+	}
+	if err := rename(store, "1", "second"); err != nil {
+		t.Fatalf("rename failed: %s", err)
+	}
+	found := store.FindOne(bson.M{"id": "1"})
+	if !found.Found || found.Document.Name != "second" {
+		t.Fatalf("unexpected document %v (%v)", found.Document, found.Err)
+	}
+	if count := store.CountDocuments(bson.M{}); count.Count != 1 {
+		t.Fatalf("expected 1 document, got %d", count.Count)
+	}
+}
@@ -0,0 +1,158 @@
+package gomongotest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultFixtureBatchSize is the number of documents inserted at once when FixtureOptions.BatchSize is not set
+const DefaultFixtureBatchSize = 1000
+
+// FixtureOptions of LoadFixtures
+type FixtureOptions struct {
+	// Clean delete the documents of a collection before loading its files
+	Clean bool
+	// BatchSize is the number of documents given to each InsertManySync
+	BatchSize int
+}
+
+// FixtureResult hold the number of documents inserted in each collection
+type FixtureResult struct {
+	Inserted map[string]int64
+	Err      error
+}
+
+// LoadFixtures insert the documents of the files of fsys matching pattern. Each file is loaded into the collection
+// named after the file, without its extension, so fixtures/users.json fills the users collection. A file holds a
+// JSON array of documents, or one document per line (JSON Lines), in canonical or relaxed Extended JSON.
+//
+// It works on any client, so it can also seed a development database:
+//
+//	gomongotest.LoadFixtures(gmc, os.DirFS("."), "fixtures/*.json", gomongotest.FixtureOptions{Clean: true})
+func LoadFixtures(c *gomongo.Client, fsys fs.FS, pattern string, opts ...FixtureOptions) FixtureResult {
+	var opt FixtureOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = DefaultFixtureBatchSize
+	}
+
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return FixtureResult{Err: err}
+	}
+	ret := FixtureResult{Inserted: map[string]int64{}}
+	cleaned := map[string]bool{}
+	for _, file := range files {
+		collName := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		if opt.Clean && !cleaned[collName] {
+			if res := gomongo.DeleteManySync(c, collName, bson.D{}); res.Err != nil {
+				ret.Err = fmt.Errorf("gomongotest: failed to clean %s: %w", collName, res.Err)
+				return ret
+			}
+			cleaned[collName] = true
+		}
+		n, err := loadFixtureFile(c, fsys, file, collName, opt.BatchSize)
+		ret.Inserted[collName] += n
+		if err != nil {
+			ret.Err = fmt.Errorf("gomongotest: failed to load %s: %w", file, err)
+			return ret
+		}
+	}
+	return ret
+}
+
+func loadFixtureFile(c *gomongo.Client, fsys fs.FS, file string, collName string, batchSize int) (int64, error) {
+	f, err := fsys.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var inserted int64
+	batch := make([]bson.D, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		res := gomongo.InsertManySync(c, collName, batch)
+		if res.DbRes != nil {
+			inserted += int64(len(res.DbRes.InsertedIDs))
+		}
+		batch = batch[:0]
+		return res.Err
+	}
+	add := func(doc bson.D) error {
+		batch = append(batch, doc)
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	r := bufio.NewReader(f)
+	if isJSONArray(r) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return inserted, err
+		}
+		var wrapper struct {
+			Docs []bson.D `bson:"docs"`
+		}
+		doc := append(append([]byte(`{"docs":`), data...), '}')
+		if err := bson.UnmarshalExtJSON(doc, false, &wrapper); err != nil {
+			return inserted, err
+		}
+		for _, d := range wrapper.Docs {
+			if err := add(d); err != nil {
+				return inserted, err
+			}
+		}
+		return inserted, flush()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var d bson.D
+		if err := bson.UnmarshalExtJSON(text, false, &d); err != nil {
+			return inserted, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := add(d); err != nil {
+			return inserted, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return inserted, err
+	}
+	return inserted, flush()
+}
+
+// isJSONArray tell if the first character of r, after white space, opens an array
+func isJSONArray(r *bufio.Reader) bool {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		r.UnreadByte()
+		return b == '['
+	}
+}
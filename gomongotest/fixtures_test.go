@@ -0,0 +1,75 @@
+package gomongotest
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Restaurant struct {
+	Name    string    `bson:"name"`
+	Score   int32     `bson:"score"`
+	Opened  time.Time `bson:"opened"`
+	Cuisine string    `bson:"cuisine,omitempty"`
+}
+
+var fixtures = fstest.MapFS{
+	"fixtures/restaurants.json": {Data: []byte(`[
+		{"name": "canonical", "score": {"$numberInt": "7"}, "opened": {"$date": {"$numberLong": "1700000000000"}}},
+		{"name": "relaxed", "score": 8, "opened": {"$date": "2023-11-14T22:13:20Z"}}
+	]`)},
+	"fixtures/courses.jsonl": {Data: []byte(`{"name": "soup"}
+
+{"name": "salad", "price": {"$numberDecimal": "9.50"}}
+{"name": "cake"}
+`)},
+	"other/ignored.json": {Data: []byte(`[{"name": "ignored"}]`)},
+}
+
+func TestGomongotestLoadFixtures(t *testing.T) {
+	c := memdb.NewClient("test_db")
+
+	res := LoadFixtures(c, fixtures, "fixtures/*", FixtureOptions{BatchSize: 2})
+	if res.Err != nil {
+		t.Fatalf("failed to load fixtures: %s", res.Err)
+	}
+	if res.Inserted["restaurants"] != 2 || res.Inserted["courses"] != 3 || len(res.Inserted) != 2 {
+		t.Fatalf("unexpected inserted counts %v", res.Inserted)
+	}
+
+	found := gomongo.FindSync[Restaurant](c, "restaurants", bson.M{})
+	if found.Err != nil || len(found.Documents) != 2 {
+		t.Fatalf("expected 2 restaurants, got %v (%v)", found.Documents, found.Err)
+	}
+	for _, r := range found.Documents {
+		if r.Opened.Unix() != 1700000000 || r.Score == 0 {
+			t.Fatalf("extended JSON not decoded: %+v", r)
+		}
+	}
+
+	res = LoadFixtures(c, fixtures, "fixtures/courses.jsonl")
+	if count := gomongo.CountDocumentsSync(c, "courses", bson.M{}); res.Err != nil || count.Count != 6 {
+		t.Fatalf("expected fixtures to be added, got %d (%v)", count.Count, res.Err)
+	}
+	res = LoadFixtures(c, fixtures, "fixtures/courses.jsonl", FixtureOptions{Clean: true})
+	if count := gomongo.CountDocumentsSync(c, "courses", bson.M{}); res.Err != nil || count.Count != 3 {
+		t.Fatalf("expected clean to remove previous documents, got %d (%v)", count.Count, res.Err)
+	}
+}
+
+func TestGomongotestLoadFixturesError(t *testing.T) {
+	c := memdb.NewClient("test_db")
+	bad := fstest.MapFS{"bad.jsonl": {Data: []byte("{\"name\": \"ok\"}\n{\"name\": \n")}}
+
+	res := LoadFixtures(c, bad, "*.jsonl")
+	if res.Err == nil {
+		t.Fatalf("expected an error for invalid JSON")
+	}
+	if res.Inserted["bad"] != 0 {
+		t.Fatalf("expected nothing inserted before the batch is full, got %d", res.Inserted["bad"])
+	}
+}
@@ -0,0 +1,122 @@
+// Package gomongotest runs tests against a real MongoDB. Each test gets a client on its own database, so tests
+// can run in parallel, and the database is dropped when the test ends:
+//
+//	func TestMain(m *testing.M) {
+//		gomongotest.Main(m, gomongotest.Options{ReplicaSet: true})
+//	}
+//
+//	func TestUsers(t *testing.T) {
+//		t.Parallel()
+//		c := gomongotest.NewTestClient(t)
+//		gomongo.InsertOneSync(c, "users", User{Id: "1000"})
+//	}
+//
+// The tests use the MongoDB of MONGODB_URI when it is set. Otherwise the first NewTestClient starts a mongod
+// found on PATH, on a free port and with a temporary dbpath, which Main stops after the tests. Tests are skipped
+// when there is neither.
+package gomongotest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+)
+
+var shared struct {
+	mu     sync.Mutex
+	opts   Options
+	server *Server
+	err    error
+}
+
+// Main run the tests with the shared server started with opts, stop the server and exit.
+// Call it from TestMain.
+func Main(m *testing.M, opts Options) {
+	shared.mu.Lock()
+	shared.opts = opts
+	shared.mu.Unlock()
+
+	code := m.Run()
+
+	shared.mu.Lock()
+	if shared.server != nil {
+		shared.server.Stop()
+	}
+	shared.mu.Unlock()
+	os.Exit(code)
+}
+
+// SharedServer return the server used by NewTestClient, starting it on first use
+func SharedServer() (*Server, error) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.server == nil && shared.err == nil {
+		shared.server, shared.err = Start(shared.opts)
+	}
+	return shared.server, shared.err
+}
+
+// NewTestClient return a client on a new database of the shared server. The database is dropped and the client
+// disconnected when the test ends. The test is skipped when MONGODB_URI is not set and mongod is not found.
+func NewTestClient(t testing.TB, connTimeout ...time.Duration) *gomongo.Client {
+	t.Helper()
+	s, err := SharedServer()
+	if err == ErrNoMongod {
+		t.Skip(err.Error())
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.NewTestClient(t, connTimeout...)
+}
+
+// NewTestClient return a client on a new database of s. The database is dropped and the client
+// disconnected when the test ends.
+func (s *Server) NewTestClient(t testing.TB, connTimeout ...time.Duration) *gomongo.Client {
+	t.Helper()
+	database := DatabaseName(t.Name())
+	c := gomongo.NewClient(s.URI, database, connTimeout...)
+	t.Cleanup(func() {
+		conn, err := c.GetMongoClient()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := conn.Database(database).Drop(ctx); err != nil {
+				t.Errorf("gomongotest: failed to drop database %s: %s", database, err)
+			}
+		}
+		c.Disconnect()
+	})
+	return c
+}
+
+// maxDatabaseName is the longest database name accepted by MongoDB
+const maxDatabaseName = 63
+
+// DatabaseName return a unique database name made from the name of a test
+func DatabaseName(testName string) string {
+	var b strings.Builder
+	for _, r := range testName {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	tail := "_" + hex.EncodeToString(suffix)
+
+	name := b.String()
+	if len(name) > maxDatabaseName-len(tail) {
+		name = name[:maxDatabaseName-len(tail)]
+	}
+	return name + tail
+}
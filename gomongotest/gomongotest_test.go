@@ -0,0 +1,54 @@
+package gomongotest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMain(m *testing.M) {
+	Main(m, Options{})
+}
+
+func TestGomongotestDatabaseName(t *testing.T) {
+	a := DatabaseName("TestUsers/find by id")
+	b := DatabaseName("TestUsers/find by id")
+	if a == b {
+		t.Fatalf("expected unique names, got %s twice", a)
+	}
+	if !strings.HasPrefix(a, "TestUsers_find_by_id_") {
+		t.Fatalf("unexpected name %s", a)
+	}
+	if long := DatabaseName(strings.Repeat("x", 100)); len(long) != maxDatabaseName {
+		t.Fatalf("expected a name of %d characters, got %d", maxDatabaseName, len(long))
+	}
+}
+
+func TestGomongotestStart(t *testing.T) {
+	t.Setenv(URIEnv, "mongodb://example:27017")
+	s, err := Start(Options{})
+	if err != nil || s.URI != "mongodb://example:27017" {
+		t.Fatalf("expected the server of %s, got %v (%v)", URIEnv, s, err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("stop of an attached server failed: %s", err)
+	}
+
+	t.Setenv(URIEnv, "")
+	if _, err := Start(Options{Binary: "no-such-mongod"}); err != ErrNoMongod {
+		t.Fatalf("expected ErrNoMongod, got %v", err)
+	}
+}
+
+func TestGomongotestClient(t *testing.T) {
+	t.Parallel()
+	c := NewTestClient(t)
+	if res := gomongo.InsertOneSync(c, "items", bson.M{"name": "item 1"}); res.Err != nil {
+		t.Fatalf("insert failed: %s", res.Err)
+	}
+	if count := gomongo.CountDocumentsSync(c, "items", bson.M{}); count.Count != 1 {
+		t.Fatalf("expected 1 document in a new database, got %d (%v)", count.Count, count.Err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build linux
+
+package gomongotest
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcAttr kill mongod when the test binary dies without stopping it
+func setProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
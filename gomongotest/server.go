@@ -0,0 +1,207 @@
+package gomongotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// URIEnv is the environment variable holding the URI of a running MongoDB to use instead of starting mongod
+const URIEnv = "MONGODB_URI"
+
+// ReplicaSetName is the name of the replica set started when Options.ReplicaSet is set
+const ReplicaSetName = "rs0"
+
+// ErrNoMongod is returned by Start when MONGODB_URI is not set and mongod is not found on PATH
+var ErrNoMongod = errors.New("gomongotest: " + URIEnv + " is not set and mongod is not found on PATH")
+
+// Options of the server started by Start
+type Options struct {
+	// ReplicaSet start mongod as a single-node replica set, so transactions and change streams work
+	ReplicaSet bool
+	// Binary is the mongod executable. Default is mongod, found on PATH.
+	Binary string
+	// StartTimeout is how long to wait for mongod to accept connections. Default is 30 seconds.
+	StartTimeout time.Duration
+	// Args are added to the mongod command line
+	Args []string
+}
+
+// Server is a MongoDB used by tests, either a mongod started by Start or the one of MONGODB_URI
+type Server struct {
+	// URI to connect to the server
+	URI string
+
+	cmd      *exec.Cmd
+	dbPath   string
+	output   *syncBuffer
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// Start return the server of MONGODB_URI when it is set. Otherwise it starts mongod on a free port of the local host,
+// with its data in a new temporary directory. Stop the server when you are done with it.
+func Start(opts Options) (*Server, error) {
+	if uri := os.Getenv(URIEnv); uri != "" {
+		return &Server{URI: uri}, nil
+	}
+
+	binary := opts.Binary
+	if binary == "" {
+		binary = "mongod"
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, ErrNoMongod
+	}
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	dbPath, err := os.MkdirTemp("", "gomongotest-")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--dbpath", dbPath, "--port", strconv.Itoa(port), "--bind_ip", "127.0.0.1"}
+	if opts.ReplicaSet {
+		args = append(args, "--replSet", ReplicaSetName)
+	}
+	args = append(args, opts.Args...)
+
+	s := &Server{dbPath: dbPath, output: &syncBuffer{}}
+	s.cmd = exec.Command(path, args...)
+	s.cmd.Stdout = s.output
+	s.cmd.Stderr = s.output
+	setProcAttr(s.cmd)
+	if err := s.cmd.Start(); err != nil {
+		os.RemoveAll(dbPath)
+		return nil, fmt.Errorf("gomongotest: failed to start mongod: %w", err)
+	}
+
+	host := "127.0.0.1:" + strconv.Itoa(port)
+	s.URI = "mongodb://" + host + "/?directConnection=true"
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.waitReady(ctx, host, opts.ReplicaSet); err != nil {
+		s.Stop()
+		return nil, fmt.Errorf("gomongotest: mongod did not start: %w\n%s", err, s.output.String())
+	}
+	if opts.ReplicaSet {
+		s.URI = "mongodb://" + host + "/?replicaSet=" + ReplicaSetName
+	}
+	return s, nil
+}
+
+// waitReady wait until mongod accepts connections and, for a replica set, is the writable primary
+func (s *Server) waitReady(ctx context.Context, host string, replicaSet bool) error {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(s.URI))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	initiated := false
+	for {
+		err = s.ready(ctx, client, host, replicaSet, &initiated)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (s *Server) ready(ctx context.Context, client *mongo.Client, host string, replicaSet bool, initiated *bool) error {
+	pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := client.Ping(pingCtx, nil); err != nil {
+		return err
+	}
+	if !replicaSet {
+		return nil
+	}
+	admin := client.Database("admin")
+	if !*initiated {
+		cfg := bson.D{
+			{Key: "_id", Value: ReplicaSetName},
+			{Key: "members", Value: bson.A{bson.D{{Key: "_id", Value: 0}, {Key: "host", Value: host}}}},
+		}
+		if err := admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: cfg}}).Err(); err != nil {
+			return err
+		}
+		*initiated = true
+	}
+	var hello struct {
+		IsWritablePrimary bool `bson:"isWritablePrimary"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return err
+	}
+	if !hello.IsWritablePrimary {
+		return errors.New("replica set has no primary yet")
+	}
+	return nil
+}
+
+// Stop kill the mongod started by Start and remove its data. It does nothing for the server of MONGODB_URI.
+func (s *Server) Stop() error {
+	s.stopOnce.Do(func() {
+		if s.cmd == nil {
+			return
+		}
+		if s.cmd.Process != nil {
+			s.cmd.Process.Kill()
+			s.cmd.Wait()
+		}
+		s.stopErr = os.RemoveAll(s.dbPath)
+	})
+	return s.stopErr
+}
+
+// syncBuffer collect the output of mongod, written by the goroutines of exec and read by Start
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// freePort return a TCP port of the local host that nobody listens on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,119 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Operation names reported in Operation.Name
+const (
+	OpInsertMany     = "insertMany"
+	OpInsertOne      = "insertOne"
+	OpUpdateOne      = "updateOne"
+	OpUpdateMany     = "updateMany"
+	OpBulkWrite      = "bulkWrite"
+	OpReplaceOne     = "replaceOne"
+	OpFindOne        = "findOne"
+	OpFind           = "find"
+	OpDistinct       = "distinct"
+	OpFindStream     = "findStream"
+	OpFindCursor     = "findCursor"
+	OpDeleteOne      = "deleteOne"
+	OpDeleteMany     = "deleteMany"
+	OpCountDocuments = "countDocuments"
+	OpRunCommand     = "runCommand"
+	OpCreateIndex    = "createIndex"
+	OpDropIndex      = "dropIndex"
+	OpDropAllIndex   = "dropAllIndex"
+	OpListIndex      = "listIndex"
+	OpExplain        = "explain"
+	OpAggregate      = "aggregate"
+)
+
+// Operation describes a single gomongo call. The same value is passed to every Hook before and after
+// the call reaches the database, so hooks can keep per call state on the context they return.
+type Operation struct {
+	Name       string
+	Database   string
+	Collection string
+	// Filter is the query, pipeline or command sent to the database. It is nil for inserts.
+	Filter interface{}
+	// Comment is attached to the driver call as $comment when it is not empty. Hooks set it in BeforeOperation.
+	Comment string
+
+	Started  time.Time
+	Duration time.Duration
+
+	Matched  int64
+	Modified int64
+	Upserted int64
+	Deleted  int64
+	Inserted int64
+	Returned int64
+
+	// Attempts is the number of times the operation ran, more than 1 when it was retried by the RetryPolicy
+	Attempts int
+	Err      error
+
+	// retryable is set for reads. idempotent is set for writes that can run again without writing twice, they are
+	// retried when RetryPolicy.Writes is set.
+	retryable  bool
+	idempotent bool
+}
+
+// Hook observes every operation a Client executes.
+//
+// BeforeOperation is called before the database is contacted and the returned context is used for the call.
+// AfterOperation is called with that context once the call is done, and Operation holds the result counts and error.
+type Hook interface {
+	BeforeOperation(ctx context.Context, op *Operation) context.Context
+	AfterOperation(ctx context.Context, op *Operation)
+}
+
+// run executes fn as op, surrounded by the client hooks.
+func (c *Client) run(op *Operation, fn func(ctx context.Context) error) error {
+	ctx, cancel := c.ctx()
+	defer cancel()
+
+	op.Database = c.database
+	for _, h := range c.hooks {
+		ctx = h.BeforeOperation(ctx, op)
+	}
+
+	op.Started = time.Now()
+	if c.breaker != nil && !c.breaker.allow(op.Collection) {
+		op.Err = NewError(MsgGomongoCircuitOpenError, ErrCircuitOpen)
+		op.Duration = time.Since(op.Started)
+	} else {
+		op.Err = c.attempt(ctx, op, fn)
+		op.Duration = time.Since(op.Started)
+		if c.breaker != nil {
+			c.breaker.record(op.Collection, op.Duration, op.Err)
+		}
+	}
+	if c.cache != nil {
+		c.cache.afterWrite(op)
+	}
+	var ge *GomongoError
+	if errors.As(op.Err, &ge) {
+		ge.setOp(op.Name, op.Collection)
+	}
+
+	for i := len(c.hooks) - 1; i >= 0; i-- {
+		c.hooks[i].AfterOperation(ctx, op)
+	}
+	if c.metrics != nil {
+		c.metrics.RecordOperation(op)
+	}
+	c.logOperation(ctx, op)
+	return op.Err
+}
+
+func (op *Operation) setUpdateCounts(res *mongo.UpdateResult) {
+	op.Matched = res.MatchedCount
+	op.Modified = res.ModifiedCount
+	op.Upserted = res.UpsertedCount
+}
@@ -0,0 +1,86 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type ctxKey string
+
+type recordHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordHook) BeforeOperation(ctx context.Context, op *Operation) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	op.Comment = h.name
+	return context.WithValue(ctx, ctxKey(h.name), op.Name)
+}
+
+func (h recordHook) AfterOperation(ctx context.Context, op *Operation) {
+	if ctx.Value(ctxKey(h.name)) != op.Name {
+		*h.calls = append(*h.calls, "missing context "+h.name)
+	}
+	*h.calls = append(*h.calls, "after "+h.name)
+}
+
+func testHookOrder(t *testing.T) {
+	var calls []string
+	c := NewClient(HOST, DB_NAME).AddHook(recordHook{"a", &calls}, recordHook{"b", &calls})
+	op := &Operation{Name: OpFind, Collection: COLL_NAME_RESTAURANT}
+	err := c.run(op, func(ctx context.Context) error {
+		if ctx.Value(ctxKey("a")) == nil || ctx.Value(ctxKey("b")) == nil {
+			t.Errorf("hook context did not reach the operation")
+		}
+		op.Returned = 5
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"before a", "before b", "after b", "after a"}
+	if len(calls) != len(expected) {
+		t.Fatalf("hook calls %v", calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("hook calls %v expected %v", calls, expected)
+			break
+		}
+	}
+	if op.Database != DB_NAME || op.Comment != "b" || op.Returned != 5 {
+		t.Errorf("unexpected operation %+v", op)
+	}
+}
+
+func testHookError(t *testing.T) {
+	var calls []string
+	c := NewClient(HOST, DB_NAME).AddHook(recordHook{"a", &calls})
+	op := &Operation{Name: OpInsertOne}
+	err := c.run(op, func(ctx context.Context) error {
+		return NewError(MsgGomongoInsertManyError, errors.New("boom"))
+	})
+	if err == nil || op.Err != err {
+		t.Errorf("operation error not recorded")
+	}
+}
+
+func testWithContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := NewClient(HOST, DB_NAME).WithContext(parent)
+	err := c.run(&Operation{Name: OpFind}, func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("parent context was not used")
+	}
+}
+
+func TestGomongoHooks(t *testing.T) {
+	t.Run("order", testHookOrder)
+	t.Run("error", testHookError)
+	t.Run("with context", testWithContext)
+}
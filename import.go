@@ -0,0 +1,423 @@
+package gomongo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ImportFormat is the input format of ImportSync
+type ImportFormat string
+
+const (
+	// ImportJSONL read one canonical or relaxed Extended JSON document per line
+	ImportJSONL ImportFormat = "jsonl"
+	// ImportCSV read one document per CSV row, with the fields and types of ImportOptions.Columns or of the header line
+	ImportCSV ImportFormat = "csv"
+	// ImportBSON read concatenated BSON documents, like the .bson files of mongodump
+	ImportBSON ImportFormat = "bson"
+)
+
+// DefaultImportBatchSize is the number of documents written at once when ImportOptions.BatchSize is not set
+const DefaultImportBatchSize = 1000
+
+// ImportOptions of ImportSync
+type ImportOptions struct {
+	// Columns are the CSV columns, in the form field.type(), such as name.string(), address.zip.int32() or
+	// opened.date(2006-01-02). The types are auto(), string(), int32(), int64(), double(), boolean(), decimal(),
+	// objectId() and date(layout), where layout is a Go time layout and defaults to RFC 3339. A column without
+	// a type is auto(), which reads numbers as int32, int64 or double and anything else as a string.
+	// When Columns is empty the first line of the file holds them.
+	Columns []string
+	// IgnoreBlanks omit the fields of empty CSV cells. Otherwise empty cells are empty strings in string and auto
+	// columns and null in the others.
+	IgnoreBlanks bool
+	// UpsertFields replace the document that has the same values of these fields, or insert it when there is none.
+	// Without UpsertFields documents are inserted.
+	UpsertFields []string
+	// Ordered stop at the first failure. Otherwise failed documents are reported and the import goes on.
+	Ordered bool
+	// BatchSize is the number of documents written at once
+	BatchSize int
+}
+
+// ImportFailure is a document that could not be parsed or written. Line is the line of the document in JSONL and
+// CSV files, and its position, starting at 1, in BSON files.
+type ImportFailure struct {
+	Line int64
+	Err  error
+}
+
+type ImportResult struct {
+	Inserted int64
+	Upserted int64
+	Replaced int64
+	Failed   int64
+	Failures []ImportFailure
+	Err      error
+}
+
+// errImportStop stop an ordered import after a failure
+var errImportStop = errors.New("import stopped")
+
+// ImportSync read the documents of r and write them to collName in batches. Documents that can not be parsed or
+// written are reported in Failures; Err is set when the import could not go on.
+func ImportSync(c *Client, collName string, r io.Reader, format ImportFormat, opts ...ImportOptions) ImportResult {
+	var opt ImportOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = DefaultImportBatchSize
+	}
+
+	imp := &importer{c: c, collName: collName, opt: opt}
+	var err error
+	switch format {
+	case ImportJSONL:
+		err = imp.readJSONL(r)
+	case ImportCSV:
+		err = imp.readCSV(r)
+	case ImportBSON:
+		err = imp.readBSON(r)
+	default:
+		err = fmt.Errorf("unknown import format %q", format)
+	}
+	if err == nil {
+		err = imp.flush()
+	}
+	if err != nil && err != errImportStop {
+		imp.res.Err = err
+		var ge *GomongoError
+		if !errors.As(err, &ge) {
+			imp.res.Err = NewError(MsgGomongoImportError, err)
+		}
+	}
+	imp.res.Failed = int64(len(imp.res.Failures))
+	return imp.res
+}
+
+type importer struct {
+	c        *Client
+	collName string
+	opt      ImportOptions
+	docs     []bson.D
+	lines    []int64
+	res      ImportResult
+}
+
+// add queue a parsed document, or the failure to parse it, and write the batch when it is full
+func (imp *importer) add(line int64, doc bson.D, parseErr error) error {
+	if parseErr == nil && len(imp.opt.UpsertFields) > 0 {
+		for _, field := range imp.opt.UpsertFields {
+			if _, ok := lookupField(doc, field); !ok {
+				parseErr = fmt.Errorf("upsert field %s is missing", field)
+				break
+			}
+		}
+	}
+	if parseErr != nil {
+		imp.res.Failures = append(imp.res.Failures, ImportFailure{Line: line, Err: parseErr})
+		if imp.opt.Ordered {
+			if err := imp.flush(); err != nil {
+				return err
+			}
+			return errImportStop
+		}
+		return nil
+	}
+	imp.docs = append(imp.docs, doc)
+	imp.lines = append(imp.lines, line)
+	if len(imp.docs) >= imp.opt.BatchSize {
+		return imp.flush()
+	}
+	return nil
+}
+
+// flush write the queued documents
+func (imp *importer) flush() error {
+	if len(imp.docs) == 0 {
+		return nil
+	}
+	docs, lines := imp.docs, imp.lines
+	imp.docs, imp.lines = nil, nil
+
+	var err error
+	if len(imp.opt.UpsertFields) > 0 {
+		models := make([]mongo.WriteModel, len(docs))
+		for i, doc := range docs {
+			filter := bson.D{}
+			for _, field := range imp.opt.UpsertFields {
+				v, _ := lookupField(doc, field)
+				filter = append(filter, bson.E{Key: field, Value: v})
+			}
+			models[i] = mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(true)
+		}
+		res := BulkWriteSync(imp.c, imp.collName, models, options.BulkWrite().SetOrdered(imp.opt.Ordered))
+		if res.DbRes != nil {
+			imp.res.Upserted += res.DbRes.UpsertedCount
+			imp.res.Replaced += res.DbRes.MatchedCount
+		}
+		err = res.Err
+	} else {
+		res := InsertManySync(imp.c, imp.collName, docs, options.InsertMany().SetOrdered(imp.opt.Ordered))
+		if res.DbRes != nil {
+			imp.res.Inserted += int64(len(res.DbRes.InsertedIDs))
+		}
+		err = res.Err
+	}
+	if err == nil {
+		return nil
+	}
+
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0 {
+		return err
+	}
+	for _, we := range bwe.WriteErrors {
+		imp.res.Failures = append(imp.res.Failures, ImportFailure{Line: lines[we.Index], Err: we.WriteError})
+	}
+	if imp.opt.Ordered {
+		return errImportStop
+	}
+	return nil
+}
+
+func (imp *importer) readJSONL(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := int64(1); scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var doc bson.D
+		err := bson.UnmarshalExtJSON([]byte(text), false, &doc)
+		if err := imp.add(line, doc, err); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (imp *importer) readBSON(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for n := int64(1); ; n++ {
+		var size [4]byte
+		if _, err := io.ReadFull(br, size[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		length := binary.LittleEndian.Uint32(size[:])
+		if length < 5 || length > 48*1024*1024 {
+			return fmt.Errorf("document %d has an invalid length %d", n, length)
+		}
+		raw := make([]byte, length)
+		copy(raw, size[:])
+		if _, err := io.ReadFull(br, raw[4:]); err != nil {
+			return err
+		}
+		var doc bson.D
+		err := bson.Unmarshal(raw, &doc)
+		if err := imp.add(n, doc, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (imp *importer) readCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	spec := imp.opt.Columns
+	if len(spec) == 0 {
+		header, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		spec = append([]string{}, header...)
+	}
+	columns := make([]csvColumn, len(spec))
+	for i, s := range spec {
+		col, err := parseCSVColumn(s)
+		if err != nil {
+			return err
+		}
+		columns[i] = col
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var line int64
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			line = int64(parseErr.StartLine)
+		} else if err != nil {
+			return err
+		} else {
+			l, _ := cr.FieldPos(0)
+			line = int64(l)
+		}
+
+		var doc bson.D
+		if err == nil {
+			doc, err = csvDocument(columns, record, imp.opt.IgnoreBlanks)
+		}
+		if err := imp.add(line, doc, err); err != nil {
+			return err
+		}
+	}
+}
+
+// csvColumn is a parsed column spec of a CSV import
+type csvColumn struct {
+	path []string
+	kind string
+	arg  string
+}
+
+var csvColumnTypes = map[string]bool{
+	"auto": true, "string": true, "int32": true, "int64": true, "double": true,
+	"boolean": true, "decimal": true, "objectId": true, "date": true,
+}
+
+func parseCSVColumn(spec string) (csvColumn, error) {
+	field, kind, arg := spec, "auto", ""
+	if strings.HasSuffix(spec, ")") {
+		paren := strings.Index(spec, "(")
+		dot := strings.LastIndex(spec[:max(paren, 0)], ".")
+		if paren < 0 || dot < 0 {
+			return csvColumn{}, fmt.Errorf("invalid column %q", spec)
+		}
+		field, kind, arg = spec[:dot], spec[dot+1:paren], spec[paren+1:len(spec)-1]
+		if !csvColumnTypes[kind] {
+			return csvColumn{}, fmt.Errorf("unknown type %s of column %q", kind, spec)
+		}
+	}
+	if field == "" {
+		return csvColumn{}, fmt.Errorf("invalid column %q", spec)
+	}
+	if kind == "date" && arg == "" {
+		arg = time.RFC3339
+	}
+	return csvColumn{path: strings.Split(field, "."), kind: kind, arg: arg}, nil
+}
+
+func csvDocument(columns []csvColumn, record []string, ignoreBlanks bool) (bson.D, error) {
+	if len(record) > len(columns) {
+		return nil, fmt.Errorf("row has %d fields, expected %d", len(record), len(columns))
+	}
+	doc := bson.D{}
+	for i, cell := range record {
+		col := columns[i]
+		if cell == "" && ignoreBlanks {
+			continue
+		}
+		v, err := col.value(cell)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", strings.Join(col.path, "."), err)
+		}
+		doc = setField(doc, col.path, v)
+	}
+	return doc, nil
+}
+
+func (col csvColumn) value(cell string) (interface{}, error) {
+	if cell == "" && col.kind != "string" && col.kind != "auto" {
+		return nil, nil
+	}
+	switch col.kind {
+	case "string":
+		return cell, nil
+	case "int32":
+		n, err := strconv.ParseInt(cell, 10, 32)
+		return int32(n), err
+	case "int64":
+		return strconv.ParseInt(cell, 10, 64)
+	case "double":
+		return strconv.ParseFloat(cell, 64)
+	case "boolean":
+		return strconv.ParseBool(cell)
+	case "decimal":
+		return primitive.ParseDecimal128(cell)
+	case "objectId":
+		return primitive.ObjectIDFromHex(cell)
+	case "date":
+		t, err := time.Parse(col.arg, cell)
+		if err != nil {
+			return nil, err
+		}
+		return primitive.NewDateTimeFromTime(t), nil
+	}
+	if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		if int64(int32(n)) == n {
+			return int32(n), nil
+		}
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil {
+		return f, nil
+	}
+	return cell, nil
+}
+
+// setField set the value at path in doc, creating the embedded documents on the way
+func setField(doc bson.D, path []string, v interface{}) bson.D {
+	for i, e := range doc {
+		if e.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			doc[i].Value = v
+			return doc
+		}
+		sub, _ := e.Value.(bson.D)
+		doc[i].Value = setField(sub, path[1:], v)
+		return doc
+	}
+	if len(path) == 1 {
+		return append(doc, bson.E{Key: path[0], Value: v})
+	}
+	return append(doc, bson.E{Key: path[0], Value: setField(bson.D{}, path[1:], v)})
+}
+
+// lookupField return the value at the dotted path field of doc
+func lookupField(doc bson.D, field string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		d, ok := cur.(bson.D)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for _, e := range d {
+			if e.Key == key {
+				cur, found = e.Value, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,97 @@
+package gomongo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestGomongoImportJSONL(t *testing.T) {
+	c := memdb.NewClient("test_import")
+	gomongo.CreateIndexSync(c, "partners", bson.D{{Key: "code", Value: 1}}, options.Index().SetUnique(true))
+
+	data := `{"code": "a", "since": {"$date": "2020-01-01T00:00:00Z"}}
+{"code": "b"}
+not json
+{"code": "a"}
+
+{"code": "c", "n": {"$numberLong": "5"}}
+`
+	res := gomongo.ImportSync(c, "partners", strings.NewReader(data), gomongo.ImportJSONL, gomongo.ImportOptions{BatchSize: 2})
+	if res.Err != nil {
+		t.Fatalf("import failed: %s", res.Err)
+	}
+	if res.Inserted != 3 || res.Failed != 2 || res.Failures[0].Line != 3 || res.Failures[1].Line != 4 {
+		t.Fatalf("unexpected report %+v", res)
+	}
+
+	ordered := gomongo.ImportSync(c, "partners", strings.NewReader(`{"code": "d"}
+{"code": "a"}
+{"code": "e"}
+`), gomongo.ImportJSONL, gomongo.ImportOptions{Ordered: true})
+	if ordered.Err != nil || ordered.Inserted != 1 || ordered.Failed != 1 || ordered.Failures[0].Line != 2 {
+		t.Fatalf("unexpected ordered report %+v", ordered)
+	}
+	if count := gomongo.CountDocumentsSync(c, "partners", bson.M{}); count.Count != 4 {
+		t.Fatalf("expected 4 partners, got %d", count.Count)
+	}
+}
+
+func TestGomongoImportCSV(t *testing.T) {
+	c := memdb.NewClient("test_import")
+
+	data := "code,name.string(),address.zip.int32(),since.date(2006-01-02),score,vip.boolean()\n" +
+		"a,Alpha,75001,2020-01-31,4.5,true\n" +
+		"b,\"Beta, Inc\",bad,2021-02-01,3,false\n" +
+		"c,Gamma,,2022-03-01,7,\n"
+	res := gomongo.ImportSync(c, "partners", strings.NewReader(data), gomongo.ImportCSV, gomongo.ImportOptions{IgnoreBlanks: true})
+	if res.Err != nil || res.Inserted != 2 || res.Failed != 1 || res.Failures[0].Line != 3 {
+		t.Fatalf("unexpected report %+v", res)
+	}
+
+	found := gomongo.FindOneSync[bson.M](c, "partners", bson.M{"code": "a"})
+	doc := found.Document
+	since := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	if doc["name"] != "Alpha" || doc["address"].(bson.M)["zip"] != int32(75001) || doc["score"] != 4.5 ||
+		doc["vip"] != true || doc["since"] != primitive.NewDateTimeFromTime(since) {
+		t.Fatalf("unexpected document %v", doc)
+	}
+	if doc := gomongo.FindOneSync[bson.M](c, "partners", bson.M{"code": "c"}).Document; doc["address"] != nil || doc["vip"] != nil {
+		t.Fatalf("expected blank cells to be omitted, got %v", doc)
+	}
+
+	columns := gomongo.ImportOptions{Columns: []string{"code.string()", "score.unknown()"}}
+	if res := gomongo.ImportSync(c, "partners", strings.NewReader("a,1\n"), gomongo.ImportCSV, columns); res.Err == nil {
+		t.Fatalf("expected an error for an unknown column type")
+	}
+}
+
+func TestGomongoImportUpsert(t *testing.T) {
+	c := memdb.NewClient("test_import")
+	gomongo.InsertOneSync(c, "partners", bson.D{{Key: "code", Value: "a"}, {Key: "name", Value: "old"}})
+
+	var dump bytes.Buffer
+	for _, doc := range []bson.D{
+		{{Key: "code", Value: "a"}, {Key: "name", Value: "new"}},
+		{{Key: "name", Value: "no code"}},
+		{{Key: "code", Value: "b"}, {Key: "name", Value: "beta"}},
+	} {
+		raw, _ := bson.Marshal(doc)
+		dump.Write(raw)
+	}
+
+	res := gomongo.ImportSync(c, "partners", &dump, gomongo.ImportBSON, gomongo.ImportOptions{UpsertFields: []string{"code"}})
+	if res.Err != nil || res.Upserted != 1 || res.Replaced != 1 || res.Failed != 1 || res.Failures[0].Line != 2 {
+		t.Fatalf("unexpected report %+v", res)
+	}
+	if doc := gomongo.FindOneSync[bson.M](c, "partners", bson.M{"code": "a"}).Document; doc["name"] != "new" {
+		t.Fatalf("expected the document to be replaced, got %v", doc)
+	}
+}
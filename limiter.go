@@ -0,0 +1,132 @@
+package gomongo
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ErrOverloaded is the error of async operations that could not start within the limits set by SetMaxInFlight
+var ErrOverloaded = errors.New("too many operations in flight")
+
+// OverloadMode tell what an async call does when its budget is used up
+type OverloadMode int
+
+const (
+	// OverloadBlock wait for a running operation to end, until the context of the client is done
+	OverloadBlock OverloadMode = iota
+	// OverloadReject fail the call with ErrOverloaded at once
+	OverloadReject
+)
+
+// InFlightLimits of SetMaxInFlight
+type InFlightLimits struct {
+	// Reads is the budget of the finds, distincts, counts and index listings running at once. 0 is unlimited.
+	Reads int64
+	// Writes is the budget of the other operations. 0 is unlimited.
+	Writes int64
+	Mode   OverloadMode
+	// Weights is how much of the budget an operation takes, by Operation name. Default is 1.
+	Weights map[string]int64
+}
+
+type inFlightLimiter struct {
+	limits InFlightLimits
+	reads  *semaphore.Weighted
+	writes *semaphore.Weighted
+}
+
+// readOps are the operations limited by InFlightLimits.Reads
+var readOps = map[string]bool{
+	OpFindOne:        true,
+	OpFind:           true,
+	OpDistinct:       true,
+	OpFindStream:     true,
+	OpCountDocuments: true,
+	OpListIndex:      true,
+}
+
+// SetMaxInFlight limit the async operations (the channel and Future functions) running at once, with separate
+// budgets for reads and writes. When a budget is used up the call waits or fails with ErrOverloaded, as limits.Mode
+// says, before a goroutine is started.
+func (c *Client) SetMaxInFlight(limits InFlightLimits) *Client {
+	l := &inFlightLimiter{limits: limits}
+	if limits.Reads > 0 {
+		l.reads = semaphore.NewWeighted(limits.Reads)
+	}
+	if limits.Writes > 0 {
+		l.writes = semaphore.NewWeighted(limits.Writes)
+	}
+	c.limiter = l
+	return c
+}
+
+// acquire take the budget of an opName operation. release gives it back once the operation ends.
+func (c *Client) acquire(opName string) (release func(), err error) {
+	if c.limiter == nil {
+		return func() {}, nil
+	}
+	sem, size := c.limiter.writes, c.limiter.limits.Writes
+	if readOps[opName] {
+		sem, size = c.limiter.reads, c.limiter.limits.Reads
+	}
+	if sem == nil {
+		return func() {}, nil
+	}
+	weight := int64(1)
+	if w, ok := c.limiter.limits.Weights[opName]; ok && w > 0 {
+		weight = min(w, size)
+	}
+
+	if c.limiter.limits.Mode == OverloadReject {
+		if !sem.TryAcquire(weight) {
+			return nil, NewError(MsgGomongoOverloadedError, ErrOverloaded).setOp(opName, "")
+		}
+	} else {
+		ctx, cancel := c.ctx()
+		defer cancel()
+		if err := sem.Acquire(ctx, weight); err != nil {
+			return nil, NewError(MsgGomongoOverloadedError, fmt.Errorf("%w: %w", ErrOverloaded, err)).setOp(opName, "")
+		}
+	}
+	return func() { sem.Release(weight) }, nil
+}
+
+// async run fn in a goroutine within the budget of opName, and send its result on the returned channel
+func async[R any, P result[R]](c *Client, opName string, fn func() R) chan R {
+	ret := make(chan R, 1)
+	release, err := c.acquire(opName)
+	if err != nil {
+		ret <- withErr[R, P](err)
+		close(ret)
+		return ret
+	}
+	go func() {
+		defer release()
+		ret <- fn()
+		close(ret)
+	}()
+	return ret
+}
+
+// future run fn in a goroutine within the budget of opName
+func future[R any, P result[R]](c *Client, opName string, fn func() R) *Future[R] {
+	release, err := c.acquire(opName)
+	if err != nil {
+		f := &Future[R]{done: make(chan struct{}), value: withErr[R, P](err)}
+		close(f.done)
+		return f
+	}
+	return NewFuture(func() R {
+		defer release()
+		return fn()
+	})
+}
+
+// withErr return a result holding err
+func withErr[R any, P result[R]](err error) R {
+	var ret R
+	P(&ret).setErr(err)
+	return ret
+}
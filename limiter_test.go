@@ -0,0 +1,85 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// gateHook hold every operation until the gate is closed
+type gateHook struct {
+	started chan string
+	gate    chan struct{}
+}
+
+func (h gateHook) BeforeOperation(ctx context.Context, op *gomongo.Operation) context.Context {
+	h.started <- op.Name
+	<-h.gate
+	return ctx
+}
+
+func (h gateHook) AfterOperation(ctx context.Context, op *gomongo.Operation) {}
+
+func TestGomongoMaxInFlight(t *testing.T) {
+	ctx := context.Background()
+	hook := gateHook{started: make(chan string, 10), gate: make(chan struct{})}
+	c := memdb.NewClient("db", 50*time.Millisecond).AddHook(hook).SetMaxInFlight(gomongo.InFlightLimits{
+		Reads:   2,
+		Writes:  1,
+		Mode:    gomongo.OverloadReject,
+		Weights: map[string]int64{gomongo.OpFind: 2},
+	})
+
+	count := gomongo.CountDocumentsFuture(c, "docs", bson.M{})
+	<-hook.started
+	if res := <-gomongo.Find[bson.M](c, "docs", bson.M{}); !errors.Is(res.Err, gomongo.ErrOverloaded) {
+		t.Fatalf("expected the find to be rejected, got %v", res.Err)
+	}
+	// writes have their own budget
+	insert := gomongo.InsertOneFuture(c, "docs", bson.M{"_id": 1})
+	<-hook.started
+	if res := gomongo.InsertOneFuture(c, "docs", bson.M{"_id": 2}).Get(); !errors.Is(res.Err, gomongo.ErrOverloaded) {
+		t.Fatalf("expected the second insert to be rejected, got %v", res.Err)
+	}
+
+	close(hook.gate)
+	if res, _ := insert.Await(ctx); res.Err != nil {
+		t.Fatalf("unexpected insert error %v", res.Err)
+	}
+	if res, _ := count.Await(ctx); res.Err != nil {
+		t.Fatalf("unexpected count error %v", res.Err)
+	}
+	if res := <-gomongo.Find[bson.M](c, "docs", bson.M{}); res.Err != nil || len(res.Documents) != 1 {
+		t.Fatalf("expected the find to run once the budget is free, got %+v", res)
+	}
+}
+
+func TestGomongoMaxInFlightBlock(t *testing.T) {
+	hook := gateHook{started: make(chan string, 10), gate: make(chan struct{})}
+	c := memdb.NewClient("db", 50*time.Millisecond).AddHook(hook).SetMaxInFlight(gomongo.InFlightLimits{Writes: 1})
+
+	first := gomongo.DeleteManyFuture(c, "docs", bson.M{})
+	<-hook.started
+	start := time.Now()
+	res := <-gomongo.DeleteMany(c, "docs", bson.M{})
+	if !errors.Is(res.Err, gomongo.ErrOverloaded) || !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Fatalf("expected an overloaded deadline error, got %v", res.Err)
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Fatalf("expected the call to wait for the timeout of the client")
+	}
+
+	second := make(chan gomongo.DeleteResult, 1)
+	go func() { second <- <-gomongo.DeleteMany(c, "docs", bson.M{}) }()
+	time.Sleep(10 * time.Millisecond)
+	close(hook.gate)
+	first.Get()
+	if r := <-second; r.Err != nil {
+		t.Fatalf("expected the waiting call to run, got %v", r.Err)
+	}
+}
@@ -0,0 +1,260 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultLoaderWait is how long a Loader collects keys before it queries them
+	DefaultLoaderWait = time.Millisecond
+	// DefaultLoaderMaxBatch is the most keys a Loader queries at once
+	DefaultLoaderMaxBatch = 100
+)
+
+// LoaderOptions of NewLoader
+type LoaderOptions struct {
+	// Wait is how long keys are collected after the first Load of a batch. Default is DefaultLoaderWait.
+	Wait time.Duration
+	// MaxBatch sends the batch as soon as it has this many keys. Default is DefaultLoaderMaxBatch.
+	MaxBatch int
+	// Find are the options of the queries, as a projection
+	Find *options.FindOptions
+}
+
+// Loader batch the lookups of documents by a key field: the keys of the Load calls made within a short window are
+// queried with a single $in query. Results are cached for the lifetime of the Loader, so create one per request:
+//
+//	users := gomongo.NewLoader[primitive.ObjectID, User](gmc.WithContext(ctx), "users", "_id")
+//	user, err := users.Load(ctx, order.UserID)
+//	if errors.Is(err, gomongo.ErrNotFound) { ... }
+type Loader[K comparable, T any] struct {
+	c        *Client
+	collName string
+	keyField string
+	opt      LoaderOptions
+
+	mu    sync.Mutex
+	cache map[K]*loaderCall[T]
+	batch *loaderBatch[K, T]
+}
+
+type loaderCall[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+type loaderBatch[K comparable, T any] struct {
+	keys  []K
+	calls []*loaderCall[T]
+	timer *time.Timer
+}
+
+// NewLoader return a loader of the documents of collName by keyField. keyField should have a unique index, when
+// more than one document has a key, Load returns one of them. The queries run under the context of c, bind it to
+// the request with WithContext.
+func NewLoader[K comparable, T any](c *Client, collName string, keyField string, opts ...LoaderOptions) *Loader[K, T] {
+	var opt LoaderOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Wait <= 0 {
+		opt.Wait = DefaultLoaderWait
+	}
+	if opt.MaxBatch <= 0 {
+		opt.MaxBatch = DefaultLoaderMaxBatch
+	}
+	return &Loader[K, T]{c: c, collName: collName, keyField: keyField, opt: opt, cache: map[K]*loaderCall[T]{}}
+}
+
+// Load return the document with key. The error matches ErrNotFound when there is none. ctx bounds the wait for the
+// result, the query is not canceled by it.
+func (l *Loader[K, T]) Load(ctx context.Context, key K) (T, error) {
+	call := l.enqueue(key)
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany return the documents of keys, in the same order, and the error of each
+func (l *Loader[K, T]) LoadMany(ctx context.Context, keys []K) ([]T, []error) {
+	calls := make([]*loaderCall[T], len(keys))
+	for i, key := range keys {
+		calls[i] = l.enqueue(key)
+	}
+	values := make([]T, len(keys))
+	errs := make([]error, len(keys))
+	for i, call := range calls {
+		select {
+		case <-call.done:
+			values[i], errs[i] = call.value, call.err
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	return values, errs
+}
+
+// Prime set the cached document of key, unless it is already loaded
+func (l *Loader[K, T]) Prime(key K, doc T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	call := &loaderCall[T]{done: make(chan struct{}), value: doc}
+	close(call.done)
+	l.cache[key] = call
+}
+
+// Clear drop key from the cache, so the next Load queries it again
+func (l *Loader[K, T]) Clear(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cache, key)
+}
+
+// enqueue return the cached call of key, or add key to the pending batch
+func (l *Loader[K, T]) enqueue(key K) *loaderCall[T] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if call, ok := l.cache[key]; ok {
+		return call
+	}
+	call := &loaderCall[T]{done: make(chan struct{})}
+	l.cache[key] = call
+
+	b := l.batch
+	if b == nil {
+		b = &loaderBatch[K, T]{}
+		b.timer = time.AfterFunc(l.opt.Wait, func() { l.dispatch(b) })
+		l.batch = b
+	}
+	b.keys = append(b.keys, key)
+	b.calls = append(b.calls, call)
+	if len(b.keys) >= l.opt.MaxBatch {
+		// the next keys go to a new batch, whether b is sent here or by its timer
+		l.batch = nil
+		if b.timer.Stop() {
+			go l.dispatch(b)
+		}
+	}
+	return call
+}
+
+// dispatch query the keys of b and complete its calls
+func (l *Loader[K, T]) dispatch(b *loaderBatch[K, T]) {
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	complete := func(call *loaderCall[T], value T, err error) {
+		select {
+		case <-call.done:
+		default:
+			call.value, call.err = value, err
+			close(call.done)
+		}
+	}
+	var zero T
+
+	byKey := make(map[string][]*loaderCall[T], len(b.keys))
+	keys := make(bson.A, 0, len(b.keys))
+	for i, key := range b.keys {
+		k, err := loaderKey(key)
+		if err != nil {
+			// a key that can not be marshaled would fail the query of the others
+			complete(b.calls[i], zero, NewError(MsgGomongoFailedFindError, err).setOp(OpFind, l.collName))
+			continue
+		}
+		keys = append(keys, key)
+		byKey[k] = append(byKey[k], b.calls[i])
+	}
+	if len(keys) == 0 {
+		l.forget(b)
+		return
+	}
+	filter := bson.D{{Key: l.keyField, Value: bson.D{{Key: "$in", Value: keys}}}}
+	var findOpts []*options.FindOptions
+	if l.opt.Find != nil {
+		findOpts = append(findOpts, l.opt.Find)
+	}
+	res := FindSync[bson.Raw](l.c, l.collName, filter, findOpts...)
+
+	if res.Err != nil {
+		for _, call := range b.calls {
+			complete(call, zero, res.Err)
+		}
+		l.forget(b)
+		return
+	}
+	for _, raw := range res.Documents {
+		v, err := raw.LookupErr(strings.Split(l.keyField, ".")...)
+		if err != nil {
+			continue
+		}
+		var doc T
+		var decodeErr error
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			decodeErr = NewError(MsgGomongoUnmarshalError, err).setOp(OpFind, l.collName)
+		}
+		values := []bson.RawValue{v}
+		if arr, ok := v.ArrayOK(); ok {
+			values, _ = arr.Values()
+		}
+		for _, v := range values {
+			for _, call := range byKey[rawKey(v)] {
+				complete(call, doc, decodeErr)
+			}
+		}
+	}
+	// the calls left have no document
+	for i, call := range b.calls {
+		complete(call, zero, NewError(fmt.Sprintf("%s %v", MsgGomongoNotFoundError, b.keys[i]), mongo.ErrNoDocuments).setOp(OpFind, l.collName))
+	}
+	l.forget(b)
+}
+
+// forget drop from the cache the calls of b that failed for another reason than a missing document, so the next
+// Load queries them again
+func (l *Loader[K, T]) forget(b *loaderBatch[K, T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, call := range b.calls {
+		if call.err != nil && !errors.Is(call.err, ErrNotFound) && l.cache[b.keys[i]] == call {
+			delete(l.cache, b.keys[i])
+		}
+	}
+}
+
+// loaderKey return the key of a Loader key, comparable with the rawKey of the documents
+func loaderKey(key interface{}) (string, error) {
+	t, data, err := bson.MarshalValue(key)
+	if err != nil {
+		return "", err
+	}
+	return rawKey(bson.RawValue{Type: t, Value: data}), nil
+}
+
+// rawKey return the valueKey of v, or its type and bytes for documents and arrays
+func rawKey(v bson.RawValue) string {
+	if k, ok := valueKey(v); ok {
+		return k
+	}
+	return string(rune(v.Type)) + string(v.Value)
+}
@@ -0,0 +1,69 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+)
+
+func TestGomongoLoader(t *testing.T) {
+	ops := 0
+	c := memdb.NewClient("db").AddHook(countHook{&ops})
+	gomongo.InsertManySync(c, "users", []cachedUser{{1, "a@x", "a"}, {2, "b@x", "b"}, {3, "c@x", "c"}})
+	ctx := context.Background()
+
+	ops = 0
+	users := gomongo.NewLoader[string, cachedUser](c, "users", "email", gomongo.LoaderOptions{Wait: 20 * time.Millisecond})
+	var wg sync.WaitGroup
+	found := make([]cachedUser, 4)
+	errs := make([]error, 4)
+	for i, email := range []string{"a@x", "c@x", "a@x", "z@x"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found[i], errs[i] = users.Load(ctx, email)
+		}()
+	}
+	wg.Wait()
+	if ops != 1 {
+		t.Fatalf("expected a single query, got %d", ops)
+	}
+	if found[0].ID != 1 || found[1].ID != 3 || found[2].ID != 1 || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected documents %+v %v", found, errs)
+	}
+	if !errors.Is(errs[3], gomongo.ErrNotFound) {
+		t.Fatalf("expected a not found error, got %v", errs[3])
+	}
+
+	// loaded keys are cached
+	if u, err := users.Load(ctx, "c@x"); err != nil || u.ID != 3 || ops != 1 {
+		t.Fatalf("expected the cached document, got %+v %v after %d queries", u, err, ops)
+	}
+
+	ops = 0
+	ids := gomongo.NewLoader[int, cachedUser](c, "users", "_id", gomongo.LoaderOptions{Wait: time.Second, MaxBatch: 2})
+	docs, errs := ids.LoadMany(ctx, []int{3, 2})
+	if ops != 1 || docs[0].ID != 3 || docs[1].ID != 2 || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("expected the full batch to be sent at once, got %+v %v after %d queries", docs, errs, ops)
+	}
+
+	// the keys after a full batch go to the next one
+	ops = 0
+	ids = gomongo.NewLoader[int, cachedUser](c, "users", "_id", gomongo.LoaderOptions{Wait: 20 * time.Millisecond, MaxBatch: 2})
+	docs, errs = ids.LoadMany(ctx, []int{3, 2, 1})
+	if ops != 2 || docs[2].ID != 1 || errs[2] != nil {
+		t.Fatalf("expected two queries, got %+v %v after %d queries", docs, errs, ops)
+	}
+
+	// a key that can not be marshaled fails alone
+	anyKeys := gomongo.NewLoader[any, cachedUser](c, "users", "_id")
+	docs, errs = anyKeys.LoadMany(ctx, []any{1, make(chan int)})
+	if docs[0].ID != 1 || errs[0] != nil || errs[1] == nil || errors.Is(errs[1], gomongo.ErrNotFound) {
+		t.Fatalf("expected the marshal error of the second key, got %+v %v", docs, errs)
+	}
+}
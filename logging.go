@@ -0,0 +1,148 @@
+package gomongo
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RedactedValue replaces the value of redacted fields in logged filters
+const RedactedValue = "[redacted]"
+
+// RedactAll can be passed to SetLogRedaction to redact the value of every field, as is done by default
+const RedactAll = "*"
+
+// redactAllFields is the redaction of a client on which SetLogRedaction was not called
+var redactAllFields = map[string]bool{RedactAll: true}
+
+// SetLogger set the logger of the client operations. Each operation is logged at debug level with its collection,
+// duration and result size. Operations slower than the threshold set by SetSlowQueryThreshold are logged at warn
+// level along with their filter, and failed operations at error level with the server error code.
+func (c *Client) SetLogger(logger *slog.Logger) *Client {
+	c.logger = logger
+	return c
+}
+
+// SetSlowQueryThreshold set the duration above which an operation is logged as slow. Zero, the default, turn the slow log off.
+func (c *Client) SetSlowQueryThreshold(threshold time.Duration) *Client {
+	c.slowThreshold = threshold
+	return c
+}
+
+// SetLogRedaction set the fields whose values are replaced by RedactedValue when a filter is logged, instead of
+// every value as is done by default. Fields are matched by name at any depth of the filter, and field names are
+// always logged. Call it with no field to log filters as they are.
+func (c *Client) SetLogRedaction(fields ...string) *Client {
+	c.redactFields = make(map[string]bool, len(fields))
+	for _, f := range fields {
+		c.redactFields[f] = true
+	}
+	return c
+}
+
+func (c *Client) logOperation(ctx context.Context, op *Operation) {
+	if c.logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", op.Name),
+		slog.String("database", op.Database),
+		slog.String("collection", op.Collection),
+		slog.Duration("duration", op.Duration),
+	}
+	if op.Attempts > 1 {
+		attrs = append(attrs, slog.Int("attempts", op.Attempts))
+	}
+
+	switch {
+	case op.Err != nil:
+		attrs = append(attrs, slog.String("error", op.Err.Error()))
+		if code, ok := serverErrorCode(op.Err); ok {
+			attrs = append(attrs, slog.Int("code", code))
+		}
+		c.logger.LogAttrs(ctx, slog.LevelError, "gomongo operation failed", attrs...)
+	case c.slowThreshold > 0 && op.Duration >= c.slowThreshold:
+		attrs = append(attrs, resultAttr(op))
+		if op.Filter != nil {
+			fields := c.redactFields
+			if fields == nil {
+				fields = redactAllFields
+			}
+			attrs = append(attrs, slog.String("filter", redactFilter(op.Filter, fields)))
+		}
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "gomongo slow operation", attrs...)
+	default:
+		attrs = append(attrs, resultAttr(op))
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "gomongo operation", attrs...)
+	}
+}
+
+// resultAttr group the non zero result counts of op
+func resultAttr(op *Operation) slog.Attr {
+	counts := []struct {
+		name  string
+		value int64
+	}{
+		{"returned", op.Returned},
+		{"inserted", op.Inserted},
+		{"matched", op.Matched},
+		{"modified", op.Modified},
+		{"upserted", op.Upserted},
+		{"deleted", op.Deleted},
+	}
+	var attrs []any
+	for _, cnt := range counts {
+		if cnt.value != 0 {
+			attrs = append(attrs, slog.Int64(cnt.name, cnt.value))
+		}
+	}
+	return slog.Group("result", attrs...)
+}
+
+// redactFilter render filter as relaxed extended JSON with the value of the given fields redacted
+func redactFilter(filter interface{}, fields map[string]bool) string {
+	raw, err := bson.Marshal(bson.D{{Key: "v", Value: filter}})
+	if err != nil {
+		return RedactedValue
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil || len(doc) != 1 {
+		return RedactedValue
+	}
+	doc[0].Value = redactValue(doc[0].Value, fields)
+
+	out, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return RedactedValue
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(out), `{"v":`), "}")
+}
+
+func redactValue(v interface{}, fields map[string]bool) interface{} {
+	switch val := v.(type) {
+	case primitive.D:
+		for i, e := range val {
+			if fields[e.Key] {
+				val[i].Value = RedactedValue
+				continue
+			}
+			val[i].Value = redactValue(e.Value, fields)
+		}
+		return val
+	case primitive.A:
+		for i, e := range val {
+			val[i] = redactValue(e, fields)
+		}
+		return val
+	default:
+		if fields[RedactAll] {
+			return RedactedValue
+		}
+		return v
+	}
+}
@@ -0,0 +1,103 @@
+package gomongo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var ret []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("bad log line %s", line)
+		}
+		ret = append(ret, entry)
+	}
+	return ret
+}
+
+func testLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := NewClient(HOST, DB_NAME).SetLogger(logger).SetSlowQueryThreshold(10 * time.Millisecond).SetLogRedaction("name")
+
+	filter := bson.M{"name": "secret", "cuisine": "open"}
+	c.run(&Operation{Name: OpFind, Collection: COLL_NAME_RESTAURANT, Filter: filter}, func(ctx context.Context) error {
+		return nil
+	})
+	c.run(&Operation{Name: OpFind, Collection: COLL_NAME_RESTAURANT, Filter: filter}, func(ctx context.Context) error {
+		time.Sleep(15 * time.Millisecond)
+		return nil
+	})
+	c.run(&Operation{Name: OpInsertOne, Collection: COLL_NAME_RESTAURANT}, func(ctx context.Context) error {
+		return NewError(MsgGomongoInsertManyError, mongo.CommandError{Code: 11000, Message: "duplicate"})
+	})
+
+	entries := logLines(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 log lines got %d", len(entries))
+	}
+	if entries[0]["level"] != "DEBUG" || entries[0]["collection"] != COLL_NAME_RESTAURANT {
+		t.Errorf("unexpected debug entry %v", entries[0])
+	}
+	if entries[1]["level"] != "WARN" {
+		t.Errorf("slow operation was not logged at warn %v", entries[1])
+	}
+	loggedFilter, _ := entries[1]["filter"].(string)
+	if strings.Contains(loggedFilter, "secret") || !strings.Contains(loggedFilter, RedactedValue) || !strings.Contains(loggedFilter, "open") {
+		t.Errorf("filter was not redacted %s", loggedFilter)
+	}
+	if entries[2]["level"] != "ERROR" || entries[2]["code"] != float64(11000) {
+		t.Errorf("unexpected error entry %v", entries[2])
+	}
+}
+
+func testRedactFilter(t *testing.T) {
+	filter := bson.D{{Key: "email", Value: "a@b.c"}, {Key: "$or", Value: bson.A{bson.M{"age": 3}, bson.M{"email": bson.M{"$in": bson.A{"x"}}}}}}
+	got := redactFilter(filter, map[string]bool{"email": true})
+	expected := `{"email":"[redacted]","$or":[{"age":3},{"email":"[redacted]"}]}`
+	if got != expected {
+		t.Errorf("got %s expected %s", got, expected)
+	}
+
+	got = redactFilter(bson.M{"age": bson.M{"$gt": 3}}, map[string]bool{RedactAll: true})
+	if got != `{"age":{"$gt":"[redacted]"}}` {
+		t.Errorf("redact all returned %s", got)
+	}
+}
+
+func testDefaultRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewClient(HOST, DB_NAME).SetLogger(slog.New(slog.NewJSONHandler(&buf, nil))).SetSlowQueryThreshold(time.Millisecond)
+	op := &Operation{Name: OpFind, Collection: "users", Filter: bson.M{"email": "a@b.c"}, Duration: time.Second}
+	c.logOperation(context.Background(), op)
+	lines := logLines(t, &buf)
+	if len(lines) != 1 || lines[0]["filter"] != `{"email":"[redacted]"}` {
+		t.Errorf("expected every value to be redacted by default, got %v", lines)
+	}
+
+	buf.Reset()
+	c.SetLogRedaction()
+	c.logOperation(context.Background(), op)
+	if lines := logLines(t, &buf); len(lines) != 1 || lines[0]["filter"] != `{"email":"a@b.c"}` {
+		t.Errorf("expected the filter as is, got %v", lines)
+	}
+}
+
+func TestGomongoLogging(t *testing.T) {
+	t.Run("levels", testLogLevels)
+	t.Run("default redaction", testDefaultRedaction)
+	t.Run("redact", testRedactFilter)
+}
@@ -0,0 +1,679 @@
+package memdb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collection implements gomongo.Collection on a Server
+type collection struct {
+	srv      *Server
+	database string
+	name     string
+}
+
+func (c *collection) ns() string {
+	return c.database + "." + c.name
+}
+
+// lock the server and return the collection data. The data is nil when the collection does not exist and create is false.
+func (c *collection) lock(ctx context.Context, create bool) (*collData, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	c.srv.mu.Lock()
+	return c.srv.data(c.database, c.name, create), c.srv.mu.Unlock, nil
+}
+
+func (cd *collData) matching(filter primitive.D) ([]int, error) {
+	if cd == nil {
+		return nil, nil
+	}
+	var ret []int
+	for i, d := range cd.docs {
+		ok, err := match(d, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ret = append(ret, i)
+		}
+	}
+	return ret, nil
+}
+
+func (cd *collData) checkUnique(ns string, doc primitive.D, skip int) error {
+	for _, idx := range cd.indexes {
+		if key, dup := idx.conflict(cd.docs, doc, skip); dup {
+			return duplicateKeyError(ns, idx, key)
+		}
+	}
+	return nil
+}
+
+// insert add doc to the collection, with a new ObjectID when it has no _id, and return the _id
+func (cd *collData) insert(ns string, doc primitive.D) (interface{}, error) {
+	id, ok := lookupKey(doc, "_id")
+	if !ok {
+		id = primitive.NewObjectID()
+		doc = append(primitive.D{{Key: "_id", Value: id}}, doc...)
+	}
+	if arr, ok := id.(primitive.A); ok {
+		return nil, writeError(codeBadValue, "The '_id' value cannot be of type array: "+formatValue(arr))
+	}
+	if err := cd.checkUnique(ns, doc, -1); err != nil {
+		return nil, err
+	}
+	cd.docs = append(cd.docs, doc)
+	return id, nil
+}
+
+// withID make sure doc has an _id, so the id can be reported even when the insert fails
+func withID(doc primitive.D) primitive.D {
+	if _, ok := lookupKey(doc, "_id"); ok {
+		return doc
+	}
+	return append(primitive.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...)
+}
+
+// updateSpec is either an update made of operators or a replacement document
+type updateSpec struct {
+	update      primitive.D
+	replacement primitive.D
+}
+
+func (cd *collData) update(ns string, filter primitive.D, spec updateSpec, multi bool, upsert bool) (*mongo.UpdateResult, error) {
+	matches, err := cd.matching(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		if !upsert {
+			return &mongo.UpdateResult{}, nil
+		}
+		seed, err := upsertSeed(filter)
+		if err != nil {
+			return nil, err
+		}
+		var doc primitive.D
+		if spec.replacement != nil {
+			doc = copyDoc(spec.replacement)
+			if id, ok := lookupKey(seed, "_id"); ok {
+				if _, has := lookupKey(doc, "_id"); !has {
+					doc = append(primitive.D{{Key: "_id", Value: id}}, doc...)
+				}
+			}
+		} else {
+			doc, err = applyUpdate(seed, spec.update, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		id, err := cd.insert(ns, withID(doc))
+		if err != nil {
+			return nil, err
+		}
+		return &mongo.UpdateResult{UpsertedCount: 1, UpsertedID: id}, nil
+	}
+
+	if !multi {
+		matches = matches[:1]
+	}
+	res := &mongo.UpdateResult{MatchedCount: int64(len(matches))}
+	for _, i := range matches {
+		old := cd.docs[i]
+		var updated primitive.D
+		if spec.replacement != nil {
+			updated, err = replaceDoc(old, spec.replacement)
+		} else {
+			updated, err = applyUpdate(old, spec.update, false)
+		}
+		if err != nil {
+			return res, err
+		}
+		if equal(old, updated) {
+			continue
+		}
+		if err := cd.checkUnique(ns, updated, i); err != nil {
+			return res, err
+		}
+		cd.docs[i] = updated
+		res.ModifiedCount++
+	}
+	return res, nil
+}
+
+// replaceDoc return the replacement, keeping the _id of old
+func replaceDoc(old primitive.D, replacement primitive.D) (primitive.D, error) {
+	oldID, _ := lookupKey(old, "_id")
+	ret := primitive.D{{Key: "_id", Value: oldID}}
+	for _, e := range replacement {
+		if e.Key == "_id" {
+			if !equal(e.Value, oldID) {
+				return nil, writeError(codeImmutableField, "After applying the update, the (immutable) field '_id' was found to have been altered to _id: "+formatValue(e.Value))
+			}
+			continue
+		}
+		ret = append(ret, primitive.E{Key: e.Key, Value: copyValue(e.Value)})
+	}
+	return ret, nil
+}
+
+func (cd *collData) delete(filter primitive.D, multi bool) (int64, error) {
+	matches, err := cd.matching(filter)
+	if err != nil || len(matches) == 0 {
+		return 0, err
+	}
+	if !multi {
+		matches = matches[:1]
+	}
+	removed := make(map[int]bool, len(matches))
+	for _, i := range matches {
+		removed[i] = true
+	}
+	kept := cd.docs[:0:0]
+	for i, d := range cd.docs {
+		if !removed[i] {
+			kept = append(kept, d)
+		}
+	}
+	cd.docs = kept
+	return int64(len(matches)), nil
+}
+
+func filterDoc(filter interface{}) (primitive.D, error) {
+	if filter == nil {
+		return nil, mongo.ErrNilDocument
+	}
+	return toDoc(filter)
+}
+
+func (c *collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	doc, err := toDoc(document)
+	if err != nil {
+		return nil, err
+	}
+	cd, unlock, err := c.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	id, err := cd.insert(c.ns(), doc)
+	if err != nil {
+		return nil, asWriteException(err)
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+func (c *collection) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	if len(documents) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+	ordered := true
+	for _, opt := range opts {
+		if opt != nil && opt.Ordered != nil {
+			ordered = *opt.Ordered
+		}
+	}
+	docs := make([]primitive.D, len(documents))
+	res := &mongo.InsertManyResult{InsertedIDs: make([]interface{}, len(documents))}
+	for i, d := range documents {
+		doc, err := toDoc(d)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = withID(doc)
+		res.InsertedIDs[i], _ = lookupKey(docs[i], "_id")
+	}
+
+	cd, unlock, err := c.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var writeErrors []mongo.BulkWriteError
+	for i, doc := range docs {
+		if _, err := cd.insert(c.ns(), doc); err != nil {
+			oe, ok := err.(*opError)
+			if !ok {
+				return res, err
+			}
+			writeErrors = append(writeErrors, mongo.BulkWriteError{WriteError: oe.writeError(i)})
+			if ordered {
+				break
+			}
+		}
+	}
+	if len(writeErrors) > 0 {
+		return res, mongo.BulkWriteException{WriteErrors: writeErrors}
+	}
+	return res, nil
+}
+
+func (c *collection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.updateWith(ctx, filter, update, false, opts)
+}
+
+func (c *collection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.updateWith(ctx, filter, update, true, opts)
+}
+
+func (c *collection) updateWith(ctx context.Context, filter interface{}, update interface{}, multi bool, opts []*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	upsert := false
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.ArrayFilters != nil {
+			return nil, asWriteException(writeError(codeNotImplemented, "arrayFilters are not supported by memdb"))
+		}
+		if opt.Upsert != nil {
+			upsert = *opt.Upsert
+		}
+	}
+	f, err := filterDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	u, err := parseUpdate(update)
+	if err != nil {
+		return nil, asWriteException(err)
+	}
+
+	cd, unlock, err := c.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	res, err := cd.update(c.ns(), f, updateSpec{update: u}, multi, upsert)
+	if err != nil {
+		return res, asWriteException(err)
+	}
+	return res, nil
+}
+
+func (c *collection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	upsert := false
+	for _, opt := range opts {
+		if opt != nil && opt.Upsert != nil {
+			upsert = *opt.Upsert
+		}
+	}
+	f, err := filterDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseReplacement(replacement)
+	if err != nil {
+		return nil, asWriteException(err)
+	}
+
+	cd, unlock, err := c.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	res, err := cd.update(c.ns(), f, updateSpec{replacement: r}, false, upsert)
+	if err != nil {
+		return res, asWriteException(err)
+	}
+	return res, nil
+}
+
+func (c *collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	if len(models) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+	ordered := true
+	for _, opt := range opts {
+		if opt != nil && opt.Ordered != nil {
+			ordered = *opt.Ordered
+		}
+	}
+
+	cd, unlock, err := c.lock(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	res := &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}
+	var writeErrors []mongo.BulkWriteError
+	for i, model := range models {
+		err := c.applyModel(cd, res, int64(i), model)
+		if err == nil {
+			continue
+		}
+		oe, ok := err.(*opError)
+		if !ok {
+			return res, err
+		}
+		writeErrors = append(writeErrors, mongo.BulkWriteError{WriteError: oe.writeError(i), Request: model})
+		if ordered {
+			break
+		}
+	}
+	if len(writeErrors) > 0 {
+		return res, mongo.BulkWriteException{WriteErrors: writeErrors}
+	}
+	return res, nil
+}
+
+func (c *collection) applyModel(cd *collData, res *mongo.BulkWriteResult, i int64, model mongo.WriteModel) error {
+	var updateRes *mongo.UpdateResult
+	var err error
+	switch m := model.(type) {
+	case *mongo.InsertOneModel:
+		doc, err := toDoc(m.Document)
+		if err != nil {
+			return err
+		}
+		if _, err := cd.insert(c.ns(), doc); err != nil {
+			return err
+		}
+		res.InsertedCount++
+		return nil
+	case *mongo.DeleteOneModel, *mongo.DeleteManyModel:
+		var filter interface{}
+		multi := false
+		if dm, ok := m.(*mongo.DeleteManyModel); ok {
+			filter, multi = dm.Filter, true
+		} else {
+			filter = m.(*mongo.DeleteOneModel).Filter
+		}
+		f, err := filterDoc(filter)
+		if err != nil {
+			return err
+		}
+		n, err := cd.delete(f, multi)
+		res.DeletedCount += n
+		return err
+	case *mongo.UpdateOneModel:
+		updateRes, err = c.modelUpdate(cd, m.Filter, m.Update, m.Upsert, m.ArrayFilters != nil, false)
+	case *mongo.UpdateManyModel:
+		updateRes, err = c.modelUpdate(cd, m.Filter, m.Update, m.Upsert, m.ArrayFilters != nil, true)
+	case *mongo.ReplaceOneModel:
+		f, ferr := filterDoc(m.Filter)
+		if ferr != nil {
+			return ferr
+		}
+		r, rerr := parseReplacement(m.Replacement)
+		if rerr != nil {
+			return rerr
+		}
+		updateRes, err = cd.update(c.ns(), f, updateSpec{replacement: r}, false, m.Upsert != nil && *m.Upsert)
+	default:
+		return writeError(codeNotImplemented, "unsupported write model")
+	}
+	if updateRes != nil {
+		res.MatchedCount += updateRes.MatchedCount
+		res.ModifiedCount += updateRes.ModifiedCount
+		if updateRes.UpsertedID != nil {
+			res.UpsertedCount++
+			res.UpsertedIDs[i] = updateRes.UpsertedID
+		}
+	}
+	return err
+}
+
+func (c *collection) modelUpdate(cd *collData, filter interface{}, update interface{}, upsert *bool, arrayFilters bool, multi bool) (*mongo.UpdateResult, error) {
+	if arrayFilters {
+		return nil, writeError(codeNotImplemented, "arrayFilters are not supported by memdb")
+	}
+	f, err := filterDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	u, err := parseUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+	return cd.update(c.ns(), f, updateSpec{update: u}, multi, upsert != nil && *upsert)
+}
+
+// find return the documents matching filter, sorted, skipped, limited and projected
+func (c *collection) find(ctx context.Context, filter interface{}, spec findSpec) ([]primitive.D, error) {
+	f, err := filterDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	cd, unlock, err := c.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	matches, err := cd.matching(f)
+	if err != nil {
+		return nil, asCommandError(err)
+	}
+	docs := make([]primitive.D, len(matches))
+	for i, m := range matches {
+		docs[i] = copyDoc(cd.docs[m])
+	}
+	docs, err = spec.apply(docs)
+	if err != nil {
+		return nil, asCommandError(err)
+	}
+	return docs, nil
+}
+
+func (c *collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	spec := findSpec{limit: 1}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := spec.setSort(opt.Sort); err != nil {
+			return mongo.NewSingleResultFromDocument(bson.D{}, asCommandError(err), nil)
+		}
+		if err := spec.setProjection(opt.Projection); err != nil {
+			return mongo.NewSingleResultFromDocument(bson.D{}, asCommandError(err), nil)
+		}
+		if opt.Skip != nil {
+			spec.skip = *opt.Skip
+		}
+	}
+	docs, err := c.find(ctx, filter, spec)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	if len(docs) == 0 {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+	return mongo.NewSingleResultFromDocument(docs[0], nil, nil)
+}
+
+func (c *collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	spec := findSpec{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := spec.setSort(opt.Sort); err != nil {
+			return nil, asCommandError(err)
+		}
+		if err := spec.setProjection(opt.Projection); err != nil {
+			return nil, asCommandError(err)
+		}
+		if opt.Skip != nil {
+			spec.skip = *opt.Skip
+		}
+		if opt.Limit != nil {
+			spec.limit = *opt.Limit
+		}
+	}
+	docs, err := c.find(ctx, filter, spec)
+	if err != nil {
+		return nil, err
+	}
+	return newCursor(docs)
+}
+
+func newCursor(docs []primitive.D) (*mongo.Cursor, error) {
+	items := make([]interface{}, len(docs))
+	for i, d := range docs {
+		items[i] = d
+	}
+	return mongo.NewCursorFromDocuments(items, nil, nil)
+}
+
+func (c *collection) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	docs, err := c.find(ctx, filter, findSpec{})
+	if err != nil {
+		return nil, err
+	}
+	ret := []interface{}{}
+	for _, d := range docs {
+		for _, v := range resolve(d, splitPath(fieldName)) {
+			values := []interface{}{v}
+			if arr, ok := v.(primitive.A); ok {
+				values = arr
+			}
+			for _, val := range values {
+				if !containsValue(ret, val) {
+					ret = append(ret, val)
+				}
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (c *collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.deleteWith(ctx, filter, false)
+}
+
+func (c *collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.deleteWith(ctx, filter, true)
+}
+
+func (c *collection) deleteWith(ctx context.Context, filter interface{}, multi bool) (*mongo.DeleteResult, error) {
+	f, err := filterDoc(filter)
+	if err != nil {
+		return nil, err
+	}
+	cd, unlock, err := c.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	n, err := cd.delete(f, multi)
+	if err != nil {
+		return nil, asWriteException(err)
+	}
+	return &mongo.DeleteResult{DeletedCount: n}, nil
+}
+
+func (c *collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	spec := findSpec{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Skip != nil {
+			spec.skip = *opt.Skip
+		}
+		if opt.Limit != nil {
+			spec.limit = *opt.Limit
+		}
+	}
+	docs, err := c.find(ctx, filter, spec)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+func (c *collection) CreateIndex(ctx context.Context, model mongo.IndexModel) (string, error) {
+	idx, err := newIndex(model)
+	if err != nil {
+		return "", asCommandError(err)
+	}
+	cd, unlock, err := c.lock(ctx, true)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	for _, existing := range cd.indexes {
+		if existing.name == idx.name || equal(existing.keys, idx.keys) {
+			if existing.name == idx.name && existing.sameSpec(idx) {
+				return idx.name, nil
+			}
+			return "", asCommandError(writeError(codeIndexOptionsConflict, "An existing index has the same name or keys as the requested index: "+existing.name))
+		}
+	}
+	for i, d := range cd.docs {
+		if key, dup := idx.conflict(cd.docs, d, i); dup {
+			return "", asCommandError(duplicateKeyError(c.ns(), idx, key))
+		}
+	}
+	cd.indexes = append(cd.indexes, idx)
+	return idx.name, nil
+}
+
+func (c *collection) DropIndex(ctx context.Context, name string, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	cd, unlock, err := c.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if cd == nil {
+		return nil, asCommandError(writeError(codeIndexNotFound, "ns not found "+c.ns()))
+	}
+	if name == idIndexName {
+		return nil, asCommandError(writeError(codeInvalidOptions, "cannot drop _id index"))
+	}
+	was := len(cd.indexes)
+	for i, idx := range cd.indexes {
+		if idx.name == name {
+			cd.indexes = append(cd.indexes[:i:i], cd.indexes[i+1:]...)
+			return bson.Marshal(bson.D{{Key: "nIndexesWas", Value: int32(was)}, {Key: "ok", Value: 1.0}})
+		}
+	}
+	return nil, asCommandError(writeError(codeIndexNotFound, "index not found with name ["+name+"]"))
+}
+
+func (c *collection) DropAllIndexes(ctx context.Context, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	cd, unlock, err := c.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if cd == nil {
+		return nil, asCommandError(writeError(codeIndexNotFound, "ns not found "+c.ns()))
+	}
+	was := len(cd.indexes)
+	cd.indexes = []*index{newIDIndex()}
+	return bson.Marshal(bson.D{{Key: "nIndexesWas", Value: int32(was)}, {Key: "msg", Value: "non-_id indexes dropped for collection"}, {Key: "ok", Value: 1.0}})
+}
+
+func (c *collection) ListIndexes(ctx context.Context, opts ...*options.ListIndexesOptions) (*mongo.Cursor, error) {
+	cd, unlock, err := c.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if cd == nil {
+		return nil, asCommandError(writeError(26, "ns does not exist: "+c.ns()))
+	}
+	specs := make([]primitive.D, len(cd.indexes))
+	for i, idx := range cd.indexes {
+		specs[i] = idx.spec()
+	}
+	return newCursor(specs)
+}
@@ -0,0 +1,98 @@
+package memdb
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// server error codes returned by the backend
+const (
+	codeBadValue             = 2
+	codeFailedToParse        = 9
+	codeTypeMismatch         = 14
+	codeIndexNotFound        = 27
+	codePathNotViable        = 28
+	codeImmutableField       = 66
+	codeInvalidOptions       = 72
+	codeIndexOptionsConflict = 85
+	codeNotImplemented       = 238
+	codeDuplicateKey         = 11000
+)
+
+var codeNames = map[int]string{
+	codeBadValue:             "BadValue",
+	codeFailedToParse:        "FailedToParse",
+	codeTypeMismatch:         "TypeMismatch",
+	codeIndexNotFound:        "IndexNotFound",
+	codePathNotViable:        "PathNotViable",
+	codeImmutableField:       "ImmutableField",
+	codeInvalidOptions:       "InvalidOptions",
+	codeIndexOptionsConflict: "IndexOptionsConflict",
+	codeNotImplemented:       "NotImplemented",
+	codeDuplicateKey:         "DuplicateKey",
+}
+
+// opError is a server error raised while running an operation. It is turned into the
+// driver error type of the operation before it is returned.
+type opError struct {
+	code       int
+	msg        string
+	keyPattern primitive.D
+	keyValue   primitive.D
+}
+
+func (e *opError) Error() string {
+	return e.msg
+}
+
+func writeError(code int, msg string) *opError {
+	return &opError{code: code, msg: msg}
+}
+
+func duplicateKeyError(ns string, idx *index, key []interface{}) *opError {
+	keyValue := make(primitive.D, len(idx.keys))
+	keyPattern := make(primitive.D, len(idx.keys))
+	for i, k := range idx.keys {
+		keyValue[i] = primitive.E{Key: k.Key, Value: key[i]}
+		keyPattern[i] = k
+	}
+	return &opError{
+		code:       codeDuplicateKey,
+		msg:        fmt.Sprintf("E11000 duplicate key error collection: %s index: %s dup key: %s", ns, idx.name, formatValue(keyValue)),
+		keyPattern: keyPattern,
+		keyValue:   keyValue,
+	}
+}
+
+// raw return the error document as the server sends it
+func (e *opError) raw(index int) bson.Raw {
+	doc := bson.D{{Key: "index", Value: index}, {Key: "code", Value: e.code}, {Key: "errmsg", Value: e.msg}}
+	if e.keyPattern != nil {
+		doc = append(doc, bson.E{Key: "keyPattern", Value: e.keyPattern}, bson.E{Key: "keyValue", Value: e.keyValue})
+	}
+	raw, _ := bson.Marshal(doc)
+	return raw
+}
+
+func (e *opError) writeError(index int) mongo.WriteError {
+	return mongo.WriteError{Index: index, Code: e.code, Message: e.msg, Raw: e.raw(index)}
+}
+
+// asWriteException turn err into the error of a single document write
+func asWriteException(err error) error {
+	if oe, ok := err.(*opError); ok {
+		return mongo.WriteException{WriteErrors: mongo.WriteErrors{oe.writeError(0)}}
+	}
+	return err
+}
+
+// asCommandError turn err into the error of a command such as find or createIndexes
+func asCommandError(err error) error {
+	if oe, ok := err.(*opError); ok {
+		return mongo.CommandError{Code: int32(oe.code), Message: oe.msg, Name: codeNames[oe.code]}
+	}
+	return err
+}
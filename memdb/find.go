@@ -0,0 +1,208 @@
+package memdb
+
+import (
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// findSpec holds the options shared by find, findOne, count and the write operations
+type findSpec struct {
+	sort       primitive.D
+	projection primitive.D
+	skip       int64
+	limit      int64
+}
+
+func (s *findSpec) setSort(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	doc, err := toDoc(v)
+	if err != nil {
+		return writeError(codeBadValue, "sort must be a document: "+err.Error())
+	}
+	s.sort = doc
+	return nil
+}
+
+func (s *findSpec) setProjection(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	doc, err := toDoc(v)
+	if err != nil {
+		return writeError(codeBadValue, "projection must be a document: "+err.Error())
+	}
+	s.projection = doc
+	return nil
+}
+
+// apply sort, skip, limit and project the matching documents
+func (s *findSpec) apply(docs []primitive.D) ([]primitive.D, error) {
+	if len(s.sort) > 0 {
+		sortDocs(docs, s.sort)
+	}
+	if s.skip > 0 {
+		if s.skip >= int64(len(docs)) {
+			docs = nil
+		} else {
+			docs = docs[s.skip:]
+		}
+	}
+	limit := s.limit
+	if limit < 0 {
+		limit = -limit
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	if len(s.projection) == 0 {
+		return docs, nil
+	}
+	proj, include, err := parseProjection(s.projection)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]primitive.D, len(docs))
+	for i, d := range docs {
+		ret[i] = project(d, proj, include)
+	}
+	return ret, nil
+}
+
+func sortDocs(docs []primitive.D, spec primitive.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, k := range spec {
+			desc := toFloat(k.Value) < 0
+			a, b := sortKey(docs[i], k.Key, desc), sortKey(docs[j], k.Key, desc)
+			c := compare(a, b)
+			if c == 0 {
+				continue
+			}
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// sortKey return the value a document is sorted by. Arrays sort by their smallest element
+// in ascending order and by their largest in descending order.
+func sortKey(doc primitive.D, path string, desc bool) interface{} {
+	values := resolve(doc, splitPath(path))
+	if len(values) == 0 {
+		return nil
+	}
+	var candidates []interface{}
+	for _, v := range values {
+		if arr, ok := v.(primitive.A); ok && len(arr) > 0 {
+			candidates = append(candidates, arr...)
+		} else {
+			candidates = append(candidates, v)
+		}
+	}
+	best := candidates[0]
+	for _, v := range candidates[1:] {
+		c := compare(v, best)
+		if (desc && c > 0) || (!desc && c < 0) {
+			best = v
+		}
+	}
+	return best
+}
+
+// projNode is a projection spec as a tree of field paths. A nil child marks a projected field.
+type projNode map[string]projNode
+
+// parseProjection build the projection tree and tell if it includes or excludes fields
+func parseProjection(spec primitive.D) (projNode, bool, error) {
+	root := projNode{}
+	include := false
+	idIncluded := true
+	for _, e := range spec {
+		if strings.HasPrefix(e.Key, "$") || isOperatorDoc(e.Value) {
+			return nil, false, writeError(codeNotImplemented, "projection operators are not supported by memdb: "+e.Key)
+		}
+		on := truthy(e.Value)
+		if e.Key == "_id" {
+			idIncluded = on
+			continue
+		}
+		if on {
+			include = true
+		}
+		node := root
+		parts := splitPath(e.Key)
+		for i, p := range parts {
+			if i == len(parts)-1 {
+				node[p] = nil
+				break
+			}
+			child, ok := node[p]
+			if !ok || child == nil {
+				child = projNode{}
+				node[p] = child
+			}
+			node = child
+		}
+	}
+
+	for _, e := range spec {
+		if e.Key != "_id" && truthy(e.Value) != include {
+			return nil, false, writeError(codeBadValue, "Cannot do exclusion on field "+e.Key+" in inclusion projection")
+		}
+	}
+	if include && idIncluded {
+		root["_id"] = nil
+	}
+	if !include && !idIncluded {
+		root["_id"] = nil
+	}
+	return root, include, nil
+}
+
+func project(doc primitive.D, proj projNode, include bool) primitive.D {
+	ret := primitive.D{}
+	for _, e := range doc {
+		child, listed := proj[e.Key]
+		switch {
+		case include && !listed:
+		case include && child == nil:
+			ret = append(ret, primitive.E{Key: e.Key, Value: copyValue(e.Value)})
+		case include:
+			if v, ok := projectValue(e.Value, child, include); ok {
+				ret = append(ret, primitive.E{Key: e.Key, Value: v})
+			}
+		case !listed:
+			ret = append(ret, primitive.E{Key: e.Key, Value: copyValue(e.Value)})
+		case child != nil:
+			v, _ := projectValue(e.Value, child, include)
+			ret = append(ret, primitive.E{Key: e.Key, Value: v})
+		}
+	}
+	return ret
+}
+
+// projectValue project the embedded documents of v
+func projectValue(v interface{}, proj projNode, include bool) (interface{}, bool) {
+	switch val := v.(type) {
+	case primitive.D:
+		return project(val, proj, include), true
+	case primitive.A:
+		ret := primitive.A{}
+		for _, el := range val {
+			if p, ok := projectValue(el, proj, include); ok {
+				ret = append(ret, p)
+			}
+		}
+		return ret, true
+	}
+	if include {
+		return nil, false
+	}
+	return v, true
+}
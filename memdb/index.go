@@ -0,0 +1,132 @@
+package memdb
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const idIndexName = "_id_"
+
+type index struct {
+	name   string
+	keys   primitive.D
+	unique bool
+	sparse bool
+}
+
+func newIDIndex() *index {
+	return &index{name: idIndexName, keys: primitive.D{{Key: "_id", Value: int32(1)}}, unique: true}
+}
+
+// newIndex build an index from the model given to CreateIndexSync
+func newIndex(model mongo.IndexModel) (*index, error) {
+	keys, err := toDoc(model.Keys)
+	if err != nil {
+		return nil, writeError(codeBadValue, "index keys must be a document: "+err.Error())
+	}
+	if len(keys) == 0 {
+		return nil, writeError(codeBadValue, "index keys cannot be empty")
+	}
+	idx := &index{keys: keys}
+	if opt := model.Options; opt != nil {
+		if opt.Name != nil {
+			idx.name = *opt.Name
+		}
+		if opt.Unique != nil {
+			idx.unique = *opt.Unique
+		}
+		if opt.Sparse != nil {
+			idx.sparse = *opt.Sparse
+		}
+	}
+	if idx.name == "" {
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%s_%v", k.Key, k.Value)
+		}
+		idx.name = strings.Join(parts, "_")
+	}
+	return idx, nil
+}
+
+func (idx *index) sameSpec(other *index) bool {
+	return equal(idx.keys, other.keys) && idx.unique == other.unique && idx.sparse == other.sparse
+}
+
+// entries return the index keys of doc. A field holding an array gives one key per element.
+// Sparse indexes return nothing for documents missing every indexed field.
+func (idx *index) entries(doc primitive.D) [][]interface{} {
+	fieldValues := make([][]interface{}, len(idx.keys))
+	missing := 0
+	for i, k := range idx.keys {
+		values := resolve(doc, splitPath(k.Key))
+		var flat []interface{}
+		for _, v := range values {
+			if arr, ok := v.(primitive.A); ok && len(arr) > 0 {
+				flat = append(flat, arr...)
+			} else {
+				flat = append(flat, v)
+			}
+		}
+		if len(flat) == 0 {
+			missing++
+			flat = []interface{}{nil}
+		}
+		fieldValues[i] = flat
+	}
+	if idx.sparse && missing == len(idx.keys) {
+		return nil
+	}
+
+	entries := [][]interface{}{{}}
+	for _, values := range fieldValues {
+		var next [][]interface{}
+		for _, prefix := range entries {
+			for _, v := range values {
+				entry := append(append([]interface{}{}, prefix...), v)
+				next = append(next, entry)
+			}
+		}
+		entries = next
+	}
+	return entries
+}
+
+// conflict return the key of doc already used by another document of docs, skipping the document at position skip
+func (idx *index) conflict(docs []primitive.D, doc primitive.D, skip int) ([]interface{}, bool) {
+	if !idx.unique {
+		return nil, false
+	}
+	newEntries := idx.entries(doc)
+	if len(newEntries) == 0 {
+		return nil, false
+	}
+	for i, other := range docs {
+		if i == skip {
+			continue
+		}
+		for _, existing := range idx.entries(other) {
+			for _, entry := range newEntries {
+				if equal(primitive.A(existing), primitive.A(entry)) {
+					return entry, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// spec return the index description returned by ListIndexSync
+func (idx *index) spec() primitive.D {
+	doc := primitive.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: idx.keys}, {Key: "name", Value: idx.name}}
+	if idx.unique && idx.name != idIndexName {
+		doc = append(doc, primitive.E{Key: "unique", Value: true})
+	}
+	if idx.sparse {
+		doc = append(doc, primitive.E{Key: "sparse", Value: true})
+	}
+	return doc
+}
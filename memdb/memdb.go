@@ -0,0 +1,83 @@
+// Package memdb is an in-memory gomongo backend for unit tests. Code written against FindSync, UpdateOneSync and
+// the other gomongo functions runs unchanged on a client from NewClient, without a MongoDB server:
+//
+//	c := memdb.NewClient("test_db")
+//	gomongo.InsertOneSync(c, "users", User{Id: "1000", Name: "name 1"})
+//	res := gomongo.FindOneSync[User](c, "users", bson.M{"id": "1000"})
+//
+// It supports inserts, finds with the common query operators, sort, skip, limit and projection, the common
+// update operators, upserts, deletes, counts, distinct, bulk writes and unique indexes. Errors have the codes and
+// messages of the server, so mongo.IsDuplicateKeyError works on them. Aggregation, transactions, change streams,
+// positional updates and projection operators are not supported.
+package memdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Server holds the databases of the in-memory backend. It is safe for concurrent use.
+type Server struct {
+	mu        sync.Mutex
+	databases map[string]map[string]*collData
+}
+
+type collData struct {
+	docs    []primitive.D
+	indexes []*index
+}
+
+// New return an empty in-memory server
+func New() *Server {
+	return &Server{databases: map[string]map[string]*collData{}}
+}
+
+// NewClient return a gomongo client working on database of a new, empty, in-memory server
+func NewClient(database string, connTimeout ...time.Duration) *gomongo.Client {
+	return New().Client(database, connTimeout...)
+}
+
+// Client return a gomongo client working on database of the server. Clients of the same server see the same data.
+func (s *Server) Client(database string, connTimeout ...time.Duration) *gomongo.Client {
+	return gomongo.NewClientWithBackend(database, s, connTimeout...)
+}
+
+// Collection implements gomongo.Backend
+func (s *Server) Collection(database string, name string) (gomongo.Collection, error) {
+	return &collection{srv: s, database: database, name: name}, nil
+}
+
+// DropDatabase remove a database with all its collections and indexes
+func (s *Server) DropDatabase(database string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.databases, database)
+}
+
+// Reset remove every database of the server
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.databases = map[string]map[string]*collData{}
+}
+
+// data return the collection, creating it when create is set. The caller must hold s.mu.
+func (s *Server) data(database string, name string, create bool) *collData {
+	db, ok := s.databases[database]
+	if !ok {
+		if !create {
+			return nil
+		}
+		db = map[string]*collData{}
+		s.databases[database] = db
+	}
+	cd, ok := db[name]
+	if !ok && create {
+		cd = &collData{indexes: []*index{newIDIndex()}}
+		db[name] = cd
+	}
+	return cd
+}
@@ -0,0 +1,227 @@
+package memdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Address struct {
+	City string `bson:"city"`
+}
+
+type User struct {
+	Id      string   `bson:"id"`
+	Name    string   `bson:"name"`
+	Age     int32    `bson:"age"`
+	Tags    []string `bson:"tags,omitempty"`
+	Address Address  `bson:"address"`
+}
+
+const COLL_NAME_USERS = "users"
+
+func seedUsers(t *testing.T, c *gomongo.Client) {
+	users := []User{
+		{Id: "1", Name: "alice", Age: 30, Tags: []string{"admin", "dev"}, Address: Address{City: "paris"}},
+		{Id: "2", Name: "bob", Age: 25, Tags: []string{"dev"}, Address: Address{City: "london"}},
+		{Id: "3", Name: "carol", Age: 35, Address: Address{City: "paris"}},
+	}
+	res := gomongo.InsertManySync(c, COLL_NAME_USERS, users)
+	if res.Err != nil {
+		t.Fatalf("failed to insert users: %s", res.Err)
+	}
+	if len(res.DbRes.InsertedIDs) != len(users) {
+		t.Fatalf("expected %d inserted ids, got %d", len(users), len(res.DbRes.InsertedIDs))
+	}
+}
+
+func TestMemdbFind(t *testing.T) {
+	c := NewClient("test_db")
+	seedUsers(t, c)
+
+	t.Run("operators", func(t *testing.T) {
+		res := gomongo.FindSync[User](c, COLL_NAME_USERS, bson.M{"age": bson.M{"$gte": 30}, "address.city": "paris"})
+		if res.Err != nil || len(res.Documents) != 2 {
+			t.Fatalf("expected 2 users, got %d (%v)", len(res.Documents), res.Err)
+		}
+		res = gomongo.FindSync[User](c, COLL_NAME_USERS, bson.M{"$or": bson.A{bson.M{"tags": "admin"}, bson.M{"name": bson.M{"$regex": "^b"}}}})
+		if len(res.Documents) != 2 {
+			t.Fatalf("expected 2 users for $or, got %d", len(res.Documents))
+		}
+		res = gomongo.FindSync[User](c, COLL_NAME_USERS, bson.M{"tags": bson.M{"$exists": false}})
+		if len(res.Documents) != 1 || res.Documents[0].Name != "carol" {
+			t.Fatalf("expected carol for $exists, got %v", res.Documents)
+		}
+	})
+
+	t.Run("sort skip limit projection", func(t *testing.T) {
+		opts := options.Find().SetSort(bson.D{{Key: "age", Value: -1}}).SetSkip(1).SetLimit(1).SetProjection(bson.M{"name": 1})
+		res := gomongo.FindSync[bson.M](c, COLL_NAME_USERS, bson.M{}, opts)
+		if res.Err != nil || len(res.Documents) != 1 {
+			t.Fatalf("expected 1 document, got %d (%v)", len(res.Documents), res.Err)
+		}
+		doc := res.Documents[0]
+		if doc["name"] != "alice" || doc["age"] != nil || doc["_id"] == nil {
+			t.Fatalf("unexpected projected document %v", doc)
+		}
+	})
+
+	t.Run("find one", func(t *testing.T) {
+		res := gomongo.FindOneSync[User](c, COLL_NAME_USERS, bson.M{"id": "2"})
+		if !res.Found || res.Document.Name != "bob" {
+			t.Fatalf("expected to find bob, got %v (%v)", res.Document, res.Err)
+		}
+		res = gomongo.FindOneSync[User](c, COLL_NAME_USERS, bson.M{"id": "nope"})
+		if res.Found || res.Err != nil {
+			t.Fatalf("expected not found without error, got %v (%v)", res.Found, res.Err)
+		}
+	})
+
+	t.Run("count and distinct", func(t *testing.T) {
+		count := gomongo.CountDocumentsSync(c, COLL_NAME_USERS, bson.M{"tags": "dev"})
+		if count.Err != nil || count.Count != 2 {
+			t.Fatalf("expected count 2, got %d (%v)", count.Count, count.Err)
+		}
+		cities := gomongo.DistinctSync[string](c, COLL_NAME_USERS, "address.city", bson.M{})
+		if cities.Err != nil || len(cities.Values) != 2 {
+			t.Fatalf("expected 2 cities, got %v (%v)", cities.Values, cities.Err)
+		}
+	})
+}
+
+func TestMemdbUpdate(t *testing.T) {
+	c := NewClient("test_db")
+	seedUsers(t, c)
+
+	res := gomongo.UpdateOneSync(c, COLL_NAME_USERS, bson.M{"id": "1"}, bson.M{"$inc": bson.M{"age": 1}, "$push": bson.M{"tags": "ops"}})
+	if res.Err != nil || res.DbRes.MatchedCount != 1 || res.DbRes.ModifiedCount != 1 {
+		t.Fatalf("unexpected update result %v (%v)", res.DbRes, res.Err)
+	}
+	user := gomongo.FindOneSync[User](c, COLL_NAME_USERS, bson.M{"id": "1"}).Document
+	if user.Age != 31 || len(user.Tags) != 3 {
+		t.Fatalf("update not applied: %v", user)
+	}
+
+	res = gomongo.UpdateManySync(c, COLL_NAME_USERS, bson.M{"address.city": "paris"}, bson.M{"$set": bson.M{"address.city": "lyon"}})
+	if res.Err != nil || res.DbRes.ModifiedCount != 2 {
+		t.Fatalf("expected 2 modified, got %v (%v)", res.DbRes, res.Err)
+	}
+
+	res = gomongo.UpdateOneSync(c, COLL_NAME_USERS, bson.M{"id": "4"}, bson.M{"$set": bson.M{"name": "dave"}}, options.Update().SetUpsert(true))
+	if res.Err != nil || res.DbRes.UpsertedID == nil {
+		t.Fatalf("expected an upsert, got %v (%v)", res.DbRes, res.Err)
+	}
+	user = gomongo.FindOneSync[User](c, COLL_NAME_USERS, bson.M{"id": "4"}).Document
+	if user.Name != "dave" {
+		t.Fatalf("upserted document not found: %v", user)
+	}
+
+	res = gomongo.ReplaceOneSync(c, COLL_NAME_USERS, bson.M{"id": "2"}, User{Id: "2", Name: "robert"})
+	if res.Err != nil || res.DbRes.ModifiedCount != 1 {
+		t.Fatalf("expected a replacement, got %v (%v)", res.DbRes, res.Err)
+	}
+
+	res = gomongo.UpdateOneSync(c, COLL_NAME_USERS, bson.M{"id": "2"}, bson.M{"name": "bad"})
+	if res.Err == nil {
+		t.Fatalf("expected an error for an update without operators")
+	}
+}
+
+func TestMemdbDelete(t *testing.T) {
+	c := NewClient("test_db")
+	seedUsers(t, c)
+
+	res := gomongo.DeleteManySync(c, COLL_NAME_USERS, bson.M{"address.city": "paris"})
+	if res.Err != nil || res.DelCount != 2 {
+		t.Fatalf("expected 2 deleted, got %d (%v)", res.DelCount, res.Err)
+	}
+	res = gomongo.DeleteOneSync(c, COLL_NAME_USERS, bson.M{})
+	if res.Err != nil || res.DelCount != 1 {
+		t.Fatalf("expected 1 deleted, got %d (%v)", res.DelCount, res.Err)
+	}
+	if count := gomongo.CountDocumentsSync(c, COLL_NAME_USERS, bson.M{}); count.Count != 0 {
+		t.Fatalf("expected empty collection, got %d", count.Count)
+	}
+}
+
+func TestMemdbIndexes(t *testing.T) {
+	c := NewClient("test_db")
+	seedUsers(t, c)
+
+	idx := gomongo.CreateIndexSync(c, COLL_NAME_USERS, bson.D{{Key: "id", Value: 1}}, options.Index().SetUnique(true))
+	if idx.Err != nil || idx.IndexName != "id_1" {
+		t.Fatalf("failed to create index: %s (%v)", idx.IndexName, idx.Err)
+	}
+
+	ins := gomongo.InsertOneSync(c, COLL_NAME_USERS, User{Id: "1", Name: "again"})
+	if !mongo.IsDuplicateKeyError(ins.Err) {
+		t.Fatalf("expected a duplicate key error, got %v", ins.Err)
+	}
+	var we mongo.WriteException
+	if !errors.As(ins.Err, &we) || len(we.WriteErrors) != 1 || we.WriteErrors[0].Code != 11000 {
+		t.Fatalf("expected a write exception with code 11000, got %v", ins.Err)
+	}
+
+	many := gomongo.InsertManySync(c, COLL_NAME_USERS, []User{{Id: "5"}, {Id: "1"}, {Id: "6"}}, options.InsertMany().SetOrdered(false))
+	var bwe mongo.BulkWriteException
+	if !errors.As(many.Err, &bwe) || len(bwe.WriteErrors) != 1 || bwe.WriteErrors[0].Index != 1 {
+		t.Fatalf("expected one failed document at index 1, got %v", many.Err)
+	}
+	if count := gomongo.CountDocumentsSync(c, COLL_NAME_USERS, bson.M{}); count.Count != 5 {
+		t.Fatalf("expected 5 documents after unordered insert, got %d", count.Count)
+	}
+
+	list := gomongo.ListIndexSync(c, COLL_NAME_USERS)
+	if list.Err != nil || len(list.Result) != 2 {
+		t.Fatalf("expected 2 indexes, got %v (%v)", list.Result, list.Err)
+	}
+	if drop := gomongo.DropIndexSync(c, COLL_NAME_USERS, "id_1"); drop.Err != nil {
+		t.Fatalf("failed to drop index: %s", drop.Err)
+	}
+	if drop := gomongo.DropIndexSync(c, COLL_NAME_USERS, "id_1"); drop.Err == nil {
+		t.Fatalf("expected an error dropping a missing index")
+	}
+}
+
+func TestMemdbBulkWrite(t *testing.T) {
+	c := NewClient("test_db")
+	seedUsers(t, c)
+
+	models := []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(User{Id: "7", Name: "eve"}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"id": "1"}).SetUpdate(bson.M{"$set": bson.M{"age": 40}}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"id": "8"}).SetUpdate(bson.M{"$set": bson.M{"name": "frank"}}).SetUpsert(true),
+		mongo.NewDeleteManyModel().SetFilter(bson.M{"address.city": "london"}),
+	}
+	res := gomongo.BulkWriteSync(c, COLL_NAME_USERS, models)
+	if res.Err != nil {
+		t.Fatalf("bulk write failed: %s", res.Err)
+	}
+	r := res.DbRes
+	if r.InsertedCount != 1 || r.ModifiedCount != 1 || r.UpsertedCount != 1 || r.DeletedCount != 1 || r.UpsertedIDs[2] == nil {
+		t.Fatalf("unexpected bulk write result %+v", r)
+	}
+}
+
+func TestMemdbSharedServer(t *testing.T) {
+	srv := New()
+	a := srv.Client("db")
+	b := srv.Client("db")
+	other := srv.Client("other")
+
+	gomongo.InsertOneSync(a, COLL_NAME_USERS, User{Id: "1"})
+	if count := gomongo.CountDocumentsSync(b, COLL_NAME_USERS, bson.M{}); count.Count != 1 {
+		t.Fatalf("expected clients of a server to share data, got %d", count.Count)
+	}
+	if count := gomongo.CountDocumentsSync(other, COLL_NAME_USERS, bson.M{}); count.Count != 0 {
+		t.Fatalf("expected databases to be separate, got %d", count.Count)
+	}
+	srv.Reset()
+	if count := gomongo.CountDocumentsSync(a, COLL_NAME_USERS, bson.M{}); count.Count != 0 {
+		t.Fatalf("expected reset to clear data, got %d", count.Count)
+	}
+}
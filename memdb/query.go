@@ -0,0 +1,393 @@
+package memdb
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// match tells if doc satisfies the query filter
+func match(doc primitive.D, filter primitive.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := matchElement(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchElement(doc primitive.D, e primitive.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or", "$nor":
+		clauses, ok := e.Value.(primitive.A)
+		if !ok || len(clauses) == 0 {
+			return false, writeError(codeBadValue, fmt.Sprintf("%s must be a nonempty array", e.Key))
+		}
+		for _, c := range clauses {
+			sub, ok := c.(primitive.D)
+			if !ok {
+				return false, writeError(codeBadValue, fmt.Sprintf("%s argument's entries must be objects", e.Key))
+			}
+			matched, err := match(doc, sub)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case e.Key == "$and" && !matched:
+				return false, nil
+			case e.Key == "$or" && matched:
+				return true, nil
+			case e.Key == "$nor" && matched:
+				return false, nil
+			}
+		}
+		return e.Key != "$or", nil
+	case "$comment":
+		return true, nil
+	}
+	if strings.HasPrefix(e.Key, "$") {
+		return false, writeError(codeBadValue, "unknown top level operator: "+e.Key)
+	}
+	return matchValues(resolve(doc, splitPath(e.Key)), e.Value)
+}
+
+// isOperatorDoc tells if v is a document of query operators such as {$gt: 1}
+func isOperatorDoc(v interface{}) bool {
+	d, ok := v.(primitive.D)
+	return ok && len(d) > 0 && strings.HasPrefix(d[0].Key, "$")
+}
+
+// matchValues apply a field condition to the values found at the field path
+func matchValues(values []interface{}, cond interface{}) (bool, error) {
+	if !isOperatorDoc(cond) {
+		if re, ok := cond.(primitive.Regex); ok {
+			return matchRegex(values, re)
+		}
+		return matchEq(values, cond), nil
+	}
+
+	ops := cond.(primitive.D)
+	for i := 0; i < len(ops); i++ {
+		op := ops[i]
+		var ok bool
+		var err error
+		switch op.Key {
+		case "$eq":
+			ok = matchEq(values, op.Value)
+		case "$ne":
+			ok = !matchEq(values, op.Value)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = matchRange(values, op.Key, op.Value)
+		case "$in":
+			ok, err = matchIn(values, op.Value)
+		case "$nin":
+			ok, err = matchIn(values, op.Value)
+			ok = !ok
+		case "$exists":
+			ok = (len(values) > 0) == truthy(op.Value)
+		case "$regex":
+			re := primitive.Regex{}
+			switch p := op.Value.(type) {
+			case string:
+				re.Pattern = p
+			case primitive.Regex:
+				re = p
+			default:
+				return false, writeError(codeBadValue, "$regex has to be a string")
+			}
+			if i+1 < len(ops) && ops[i+1].Key == "$options" {
+				re.Options, _ = ops[i+1].Value.(string)
+				i++
+			}
+			ok, err = matchRegex(values, re)
+		case "$options":
+			// handled with $regex
+			ok = true
+		case "$size":
+			ok = matchSize(values, op.Value)
+		case "$all":
+			ok, err = matchAll(values, op.Value)
+		case "$elemMatch":
+			ok, err = matchElemMatch(values, op.Value)
+		case "$not":
+			ok, err = matchValues(values, op.Value)
+			ok = !ok
+		case "$type":
+			ok, err = matchType(values, op.Value)
+		case "$mod":
+			ok, err = matchMod(values, op.Value)
+		case "$comment":
+			ok = true
+		default:
+			return false, writeError(codeBadValue, "unknown operator: "+op.Key)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchEq(values []interface{}, target interface{}) bool {
+	if target == nil && len(values) == 0 {
+		return true
+	}
+	for _, v := range expand(values) {
+		if equal(v, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRange(values []interface{}, op string, target interface{}) bool {
+	for _, v := range expand(values) {
+		if typeOrder(v) != typeOrder(target) {
+			continue
+		}
+		c := compare(v, target)
+		if (op == "$gt" && c > 0) || (op == "$gte" && c >= 0) || (op == "$lt" && c < 0) || (op == "$lte" && c <= 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchIn(values []interface{}, list interface{}) (bool, error) {
+	arr, ok := list.(primitive.A)
+	if !ok {
+		return false, writeError(codeBadValue, "$in needs an array")
+	}
+	for _, target := range arr {
+		if re, ok := target.(primitive.Regex); ok {
+			if m, err := matchRegex(values, re); err != nil || m {
+				return m, err
+			}
+			continue
+		}
+		if matchEq(values, target) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchRegex(values []interface{}, re primitive.Regex) (bool, error) {
+	flags := ""
+	for _, o := range re.Options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		}
+	}
+	pattern := re.Pattern
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, writeError(codeBadValue, "Regular expression is invalid: "+err.Error())
+	}
+	for _, v := range expand(values) {
+		switch s := v.(type) {
+		case string:
+			if compiled.MatchString(s) {
+				return true, nil
+			}
+		case primitive.Regex:
+			if s == re {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchSize(values []interface{}, size interface{}) bool {
+	n, ok := toInt64(size)
+	if !ok {
+		f := toFloat(size)
+		if f != math.Trunc(f) {
+			return false
+		}
+		n = int64(f)
+	}
+	for _, v := range values {
+		if arr, ok := v.(primitive.A); ok && int64(len(arr)) == n {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAll(values []interface{}, list interface{}) (bool, error) {
+	arr, ok := list.(primitive.A)
+	if !ok {
+		return false, writeError(codeBadValue, "$all needs an array")
+	}
+	if len(arr) == 0 {
+		return false, nil
+	}
+	for _, target := range arr {
+		var ok bool
+		var err error
+		if isOperatorDoc(target) && target.(primitive.D)[0].Key == "$elemMatch" {
+			ok, err = matchElemMatch(values, target.(primitive.D)[0].Value)
+		} else {
+			ok, err = matchValues(values, target)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchElemMatch(values []interface{}, cond interface{}) (bool, error) {
+	query, ok := cond.(primitive.D)
+	if !ok {
+		return false, writeError(codeBadValue, "$elemMatch needs an Object")
+	}
+	for _, v := range values {
+		arr, ok := v.(primitive.A)
+		if !ok {
+			continue
+		}
+		for _, el := range arr {
+			var matched bool
+			var err error
+			if isOperatorDoc(query) {
+				matched, err = matchValues([]interface{}{el}, query)
+			} else if d, ok := el.(primitive.D); ok {
+				matched, err = match(d, query)
+			}
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+var typeAliases = map[string]int{
+	"double": 1, "string": 2, "object": 3, "array": 4, "binData": 5, "undefined": 6, "objectId": 7, "bool": 8,
+	"date": 9, "null": 10, "regex": 11, "javascript": 13, "symbol": 14, "int": 16, "timestamp": 17, "long": 18,
+	"decimal": 19, "minKey": -1, "maxKey": 127,
+}
+
+func bsonTypeNumber(v interface{}) int {
+	switch v.(type) {
+	case float64:
+		return 1
+	case string:
+		return 2
+	case primitive.D:
+		return 3
+	case primitive.A:
+		return 4
+	case primitive.Binary:
+		return 5
+	case primitive.Undefined:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case nil, primitive.Null:
+		return 10
+	case primitive.Regex:
+		return 11
+	case primitive.JavaScript:
+		return 13
+	case primitive.Symbol:
+		return 14
+	case int32:
+		return 16
+	case primitive.Timestamp:
+		return 17
+	case int64:
+		return 18
+	case primitive.Decimal128:
+		return 19
+	case primitive.MinKey:
+		return -1
+	case primitive.MaxKey:
+		return 127
+	}
+	return 0
+}
+
+func matchType(values []interface{}, spec interface{}) (bool, error) {
+	specs, ok := spec.(primitive.A)
+	if !ok {
+		specs = primitive.A{spec}
+	}
+	for _, s := range specs {
+		for _, v := range expand(values) {
+			switch t := s.(type) {
+			case string:
+				if t == "number" {
+					if isNumber(v) {
+						return true, nil
+					}
+					continue
+				}
+				n, ok := typeAliases[t]
+				if !ok {
+					return false, writeError(codeBadValue, "Unknown type name alias: "+t)
+				}
+				if bsonTypeNumber(v) == n {
+					return true, nil
+				}
+			default:
+				n, ok := toInt64(s)
+				if !ok {
+					n = int64(toFloat(s))
+				}
+				if int64(bsonTypeNumber(v)) == n {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchMod(values []interface{}, spec interface{}) (bool, error) {
+	arr, ok := spec.(primitive.A)
+	if !ok || len(arr) != 2 || !isNumber(arr[0]) || !isNumber(arr[1]) {
+		return false, writeError(codeBadValue, "malformed mod, needs to be an array of two numbers")
+	}
+	divisor, remainder := int64(toFloat(arr[0])), int64(toFloat(arr[1]))
+	if divisor == 0 {
+		return false, writeError(codeBadValue, "divisor cannot be 0")
+	}
+	for _, v := range expand(values) {
+		if isNumber(v) && int64(toFloat(v))%divisor == remainder {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case nil:
+		return false
+	}
+	if isNumber(v) {
+		return toFloat(v) != 0
+	}
+	return true
+}
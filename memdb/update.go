@@ -0,0 +1,319 @@
+package memdb
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parseUpdate normalize an update document and check it only holds update operators
+func parseUpdate(update interface{}) (primitive.D, error) {
+	switch update.(type) {
+	case primitive.A, []interface{}, []primitive.D, []primitive.M:
+		return nil, writeError(codeNotImplemented, "update pipelines are not supported by memdb")
+	}
+	doc, err := toDoc(update)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 {
+		return nil, writeError(codeFailedToParse, "update document must contain key beginning with '$'")
+	}
+	for _, e := range doc {
+		if !strings.HasPrefix(e.Key, "$") {
+			return nil, writeError(codeFailedToParse, "update document must contain key beginning with '$'")
+		}
+		if _, ok := e.Value.(primitive.D); !ok {
+			return nil, writeError(codeFailedToParse, fmt.Sprintf("Modifiers operate on fields but we found type %s instead", typeName(e.Value)))
+		}
+	}
+	return doc, nil
+}
+
+// parseReplacement normalize a replacement document and check it holds no update operator
+func parseReplacement(replacement interface{}) (primitive.D, error) {
+	doc, err := toDoc(replacement)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range doc {
+		if strings.HasPrefix(e.Key, "$") {
+			return nil, writeError(codeBadValue, "replacement document cannot contain keys beginning with '$'")
+		}
+	}
+	return doc, nil
+}
+
+// applyUpdate return a copy of doc with the update operators applied. $setOnInsert is only applied when inserting.
+func applyUpdate(doc primitive.D, update primitive.D, inserting bool) (primitive.D, error) {
+	ret := copyDoc(doc)
+	var container interface{} = ret
+	for _, op := range update {
+		for _, field := range op.Value.(primitive.D) {
+			if field.Key == "_id" || strings.HasPrefix(field.Key, "_id.") {
+				if !inserting && op.Key != "$setOnInsert" {
+					if current, _ := getPath(ret, splitPath(field.Key)); op.Key != "$set" || !equal(current, field.Value) {
+						return nil, writeError(codeImmutableField, "Performing an update on the path '_id' would modify the immutable field '_id'")
+					}
+				}
+			}
+			if strings.Contains(field.Key, "$") {
+				return nil, writeError(codeNotImplemented, "positional update operators are not supported by memdb: "+field.Key)
+			}
+			var err error
+			container, err = applyOperator(container, op.Key, field, inserting)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return container.(primitive.D), nil
+}
+
+func applyOperator(container interface{}, op string, field primitive.E, inserting bool) (interface{}, error) {
+	parts := splitPath(field.Key)
+	current, exists := getPath(container, parts)
+
+	switch op {
+	case "$set":
+		return setPath(container, parts, copyValue(field.Value))
+	case "$setOnInsert":
+		if !inserting {
+			return container, nil
+		}
+		return setPath(container, parts, copyValue(field.Value))
+	case "$unset":
+		return unsetPath(container, parts), nil
+	case "$inc", "$mul":
+		if !isNumber(field.Value) {
+			return nil, writeError(codeTypeMismatch, fmt.Sprintf("Cannot %s with non-numeric argument: {%s: %s}", strings.TrimPrefix(op, "$"), field.Key, formatValue(field.Value)))
+		}
+		if exists && !isNumber(current) {
+			return nil, writeError(codeTypeMismatch, fmt.Sprintf("Cannot apply %s to a value of non-numeric type. {_id: ...} has the field '%s' of non-numeric type %s", op, field.Key, typeName(current)))
+		}
+		if !exists {
+			if op == "$mul" {
+				return setPath(container, parts, multiplyNumbers(int32(0), field.Value))
+			}
+			return setPath(container, parts, field.Value)
+		}
+		if op == "$mul" {
+			return setPath(container, parts, multiplyNumbers(current, field.Value))
+		}
+		return setPath(container, parts, addNumbers(current, field.Value))
+	case "$min", "$max":
+		if !exists {
+			return setPath(container, parts, copyValue(field.Value))
+		}
+		c := compare(field.Value, current)
+		if (op == "$min" && c < 0) || (op == "$max" && c > 0) {
+			return setPath(container, parts, copyValue(field.Value))
+		}
+		return container, nil
+	case "$rename":
+		target, ok := field.Value.(string)
+		if !ok {
+			return nil, writeError(codeBadValue, "The 'to' field for $rename must be a string: "+field.Key)
+		}
+		if !exists {
+			return container, nil
+		}
+		container = unsetPath(container, parts)
+		return setPath(container, splitPath(target), current)
+	case "$currentDate":
+		var now interface{} = primitive.NewDateTimeFromTime(time.Now())
+		if spec, ok := field.Value.(primitive.D); ok {
+			if t, _ := lookupKey(spec, "$type"); t == "timestamp" {
+				now = primitive.Timestamp{T: uint32(time.Now().Unix()), I: 1}
+			}
+		}
+		return setPath(container, parts, now)
+	case "$push", "$addToSet":
+		arr, err := arrayAt(current, exists, op, field.Key)
+		if err != nil {
+			return nil, err
+		}
+		items := primitive.A{field.Value}
+		if spec, ok := field.Value.(primitive.D); ok && len(spec) > 0 && spec[0].Key == "$each" {
+			each, ok := spec[0].Value.(primitive.A)
+			if !ok {
+				return nil, writeError(codeBadValue, "The argument to $each must be an array")
+			}
+			if len(spec) > 1 {
+				return nil, writeError(codeNotImplemented, "memdb supports $each without other modifiers")
+			}
+			items = each
+		}
+		for _, item := range items {
+			if op == "$addToSet" && containsValue(arr, item) {
+				continue
+			}
+			arr = append(arr, copyValue(item))
+		}
+		return setPath(container, parts, arr)
+	case "$pull", "$pullAll":
+		if !exists {
+			return container, nil
+		}
+		arr, err := arrayAt(current, exists, op, field.Key)
+		if err != nil {
+			return nil, err
+		}
+		kept := primitive.A{}
+		for _, item := range arr {
+			remove, err := pullMatches(op, item, field.Value)
+			if err != nil {
+				return nil, err
+			}
+			if !remove {
+				kept = append(kept, item)
+			}
+		}
+		return setPath(container, parts, kept)
+	case "$pop":
+		if !exists {
+			return container, nil
+		}
+		arr, err := arrayAt(current, exists, op, field.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(arr) == 0 {
+			return container, nil
+		}
+		if toFloat(field.Value) < 0 {
+			arr = arr[1:]
+		} else {
+			arr = arr[:len(arr)-1]
+		}
+		return setPath(container, parts, append(primitive.A{}, arr...))
+	}
+	return nil, writeError(codeFailedToParse, "Unknown modifier: "+op)
+}
+
+func arrayAt(current interface{}, exists bool, op string, path string) (primitive.A, error) {
+	if !exists {
+		return primitive.A{}, nil
+	}
+	arr, ok := current.(primitive.A)
+	if !ok {
+		return nil, writeError(codeBadValue, fmt.Sprintf("Cannot apply %s to non-array field '%s'", op, path))
+	}
+	return append(primitive.A{}, arr...), nil
+}
+
+func containsValue(arr primitive.A, v interface{}) bool {
+	for _, e := range arr {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func pullMatches(op string, item interface{}, cond interface{}) (bool, error) {
+	if op == "$pullAll" {
+		list, ok := cond.(primitive.A)
+		if !ok {
+			return false, writeError(codeBadValue, "$pullAll requires an array argument")
+		}
+		return containsValue(list, item), nil
+	}
+	if isOperatorDoc(cond) {
+		return matchValues([]interface{}{item}, cond)
+	}
+	if query, ok := cond.(primitive.D); ok {
+		if doc, ok := item.(primitive.D); ok {
+			return match(doc, query)
+		}
+		return false, nil
+	}
+	return equal(item, cond), nil
+}
+
+func addNumbers(a, b interface{}) interface{} {
+	ai, aInt := toInt64(a)
+	bi, bInt := toInt64(b)
+	if aInt && bInt {
+		sum := ai + bi
+		_, a32 := a.(int32)
+		_, b32 := b.(int32)
+		if a32 && b32 && sum >= math.MinInt32 && sum <= math.MaxInt32 {
+			return int32(sum)
+		}
+		return sum
+	}
+	return toFloat(a) + toFloat(b)
+}
+
+func multiplyNumbers(a, b interface{}) interface{} {
+	ai, aInt := toInt64(a)
+	bi, bInt := toInt64(b)
+	if aInt && bInt {
+		prod := ai * bi
+		_, a32 := a.(int32)
+		_, b32 := b.(int32)
+		if a32 && b32 && prod >= math.MinInt32 && prod <= math.MaxInt32 {
+			return int32(prod)
+		}
+		return prod
+	}
+	return toFloat(a) * toFloat(b)
+}
+
+// upsertSeed build the document inserted by an upsert from the equality conditions of the filter
+func upsertSeed(filter primitive.D) (primitive.D, error) {
+	var container interface{} = primitive.D{}
+	var err error
+	var seed func(f primitive.D) error
+	seed = func(f primitive.D) error {
+		for _, e := range f {
+			if e.Key == "$and" {
+				clauses, _ := e.Value.(primitive.A)
+				for _, c := range clauses {
+					if sub, ok := c.(primitive.D); ok {
+						if err := seed(sub); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(e.Key, "$") {
+				continue
+			}
+			value := e.Value
+			if isOperatorDoc(value) {
+				eq, ok := lookupKey(value.(primitive.D), "$eq")
+				if !ok {
+					continue
+				}
+				value = eq
+			}
+			if _, isRegex := value.(primitive.Regex); isRegex {
+				continue
+			}
+			container, err = setPath(container, splitPath(e.Key), copyValue(value))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := seed(filter); err != nil {
+		return nil, err
+	}
+	return container.(primitive.D), nil
+}
+
+func typeName(v interface{}) string {
+	for name, n := range typeAliases {
+		if n == bsonTypeNumber(v) {
+			return name
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
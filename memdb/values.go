@@ -0,0 +1,428 @@
+package memdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errNilDocument = errors.New("document is nil")
+
+// toDoc convert a struct, map, bson.D or bson.Raw to a primitive.D. Embedded documents become primitive.D and arrays primitive.A.
+func toDoc(v interface{}) (primitive.D, error) {
+	if v == nil {
+		return nil, errNilDocument
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc primitive.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = primitive.D{}
+	}
+	return doc, nil
+}
+
+// toValue normalize a single value the same way toDoc normalize documents
+func toValue(v interface{}) (interface{}, error) {
+	doc, err := toDoc(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return nil, err
+	}
+	return doc[0].Value, nil
+}
+
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case primitive.D:
+		ret := make(primitive.D, len(val))
+		for i, e := range val {
+			ret[i] = primitive.E{Key: e.Key, Value: copyValue(e.Value)}
+		}
+		return ret
+	case primitive.A:
+		ret := make(primitive.A, len(val))
+		for i, e := range val {
+			ret[i] = copyValue(e)
+		}
+		return ret
+	}
+	return v
+}
+
+func copyDoc(doc primitive.D) primitive.D {
+	return copyValue(doc).(primitive.D)
+}
+
+func lookupKey(doc primitive.D, key string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// typeOrder return the rank of the value type in the BSON comparison order
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return 1
+	case nil, primitive.Null, primitive.Undefined:
+		return 2
+	case int32, int64, float64, primitive.Decimal128:
+		return 3
+	case string, primitive.Symbol:
+		return 4
+	case primitive.D:
+		return 5
+	case primitive.A:
+		return 6
+	case primitive.Binary:
+		return 7
+	case primitive.ObjectID:
+		return 8
+	case bool:
+		return 9
+	case primitive.DateTime:
+		return 10
+	case primitive.Timestamp:
+		return 11
+	case primitive.Regex:
+		return 12
+	case primitive.MaxKey:
+		return 14
+	}
+	return 13
+}
+
+func isNumber(v interface{}) bool {
+	return typeOrder(v) == 3
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	}
+	return math.NaN()
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case a == b:
+		return 0
+	}
+	// NaN sorts before every number
+	if math.IsNaN(a) && math.IsNaN(b) {
+		return 0
+	}
+	if math.IsNaN(a) {
+		return -1
+	}
+	return 1
+}
+
+// compare order two values the way the server does
+func compare(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return cmpInt(int64(ta), int64(tb))
+	}
+	switch av := a.(type) {
+	case int32, int64, float64, primitive.Decimal128:
+		ai, aok := toInt64(a)
+		bi, bok := toInt64(b)
+		if aok && bok {
+			return cmpInt(ai, bi)
+		}
+		return cmpFloat(toFloat(a), toFloat(b))
+	case string:
+		return strings.Compare(av, stringOf(b))
+	case primitive.Symbol:
+		return strings.Compare(string(av), stringOf(b))
+	case primitive.D:
+		bv := b.(primitive.D)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := strings.Compare(av[i].Key, bv[i].Key); c != 0 {
+				return c
+			}
+			if c := compare(av[i].Value, bv[i].Value); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(int64(len(av)), int64(len(bv)))
+	case primitive.A:
+		bv := b.(primitive.A)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compare(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(int64(len(av)), int64(len(bv)))
+	case primitive.Binary:
+		bv := b.(primitive.Binary)
+		if len(av.Data) != len(bv.Data) {
+			return cmpInt(int64(len(av.Data)), int64(len(bv.Data)))
+		}
+		if av.Subtype != bv.Subtype {
+			return cmpInt(int64(av.Subtype), int64(bv.Subtype))
+		}
+		return bytes.Compare(av.Data, bv.Data)
+	case primitive.ObjectID:
+		bv := b.(primitive.ObjectID)
+		return bytes.Compare(av[:], bv[:])
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case primitive.DateTime:
+		return cmpInt(int64(av), int64(b.(primitive.DateTime)))
+	case primitive.Timestamp:
+		return primitive.CompareTimestamp(av, b.(primitive.Timestamp))
+	case primitive.Regex:
+		bv := b.(primitive.Regex)
+		if c := strings.Compare(av.Pattern, bv.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(av.Options, bv.Options)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func stringOf(v interface{}) string {
+	if s, ok := v.(primitive.Symbol); ok {
+		return string(s)
+	}
+	s, _ := v.(string)
+	return s
+}
+
+func equal(a, b interface{}) bool {
+	return compare(a, b) == 0
+}
+
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// resolve return the values at path. Arrays met on the way are traversed, so a.b on {a: [{b: 1}, {b: 2}]} gives 1 and 2.
+// A missing path gives no value.
+func resolve(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch cur := v.(type) {
+	case primitive.D:
+		if val, ok := lookupKey(cur, parts[0]); ok {
+			return resolve(val, parts[1:])
+		}
+	case primitive.A:
+		var ret []interface{}
+		if idx, err := strconv.Atoi(parts[0]); err == nil && idx >= 0 {
+			if idx < len(cur) {
+				ret = append(ret, resolve(cur[idx], parts[1:])...)
+			}
+			return ret
+		}
+		for _, el := range cur {
+			if d, ok := el.(primitive.D); ok {
+				ret = append(ret, resolve(d, parts)...)
+			}
+		}
+		return ret
+	}
+	return nil
+}
+
+// expand add the elements of the array values to values
+func expand(values []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		ret = append(ret, v)
+		if arr, ok := v.(primitive.A); ok {
+			ret = append(ret, arr...)
+		}
+	}
+	return ret
+}
+
+// getPath return the value at path without traversing arrays, except through numeric parts
+func getPath(v interface{}, parts []string) (interface{}, bool) {
+	for _, p := range parts {
+		switch cur := v.(type) {
+		case primitive.D:
+			val, ok := lookupKey(cur, p)
+			if !ok {
+				return nil, false
+			}
+			v = val
+		case primitive.A:
+			idx, err := strconv.Atoi(p)
+			if err != nil || idx < 0 || idx >= len(cur) {
+				return nil, false
+			}
+			v = cur[idx]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setPath set the value at path, creating embedded documents on the way, and return the updated container
+func setPath(container interface{}, parts []string, value interface{}) (interface{}, error) {
+	switch cur := container.(type) {
+	case primitive.D:
+		for i, e := range cur {
+			if e.Key != parts[0] {
+				continue
+			}
+			if len(parts) == 1 {
+				cur[i].Value = value
+				return cur, nil
+			}
+			child, err := setPath(e.Value, parts[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			cur[i].Value = child
+			return cur, nil
+		}
+		if len(parts) == 1 {
+			return append(cur, primitive.E{Key: parts[0], Value: value}), nil
+		}
+		child, err := setPath(primitive.D{}, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return append(cur, primitive.E{Key: parts[0], Value: child}), nil
+	case primitive.A:
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil || idx < 0 {
+			return nil, writeError(codePathNotViable, fmt.Sprintf("Cannot create field '%s' in element {%s}", parts[0], formatValue(cur)))
+		}
+		for len(cur) <= idx {
+			cur = append(cur, nil)
+		}
+		if len(parts) == 1 {
+			cur[idx] = value
+			return cur, nil
+		}
+		child := cur[idx]
+		if child == nil {
+			child = primitive.D{}
+		}
+		child, err = setPath(child, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		cur[idx] = child
+		return cur, nil
+	}
+	return nil, writeError(codePathNotViable, fmt.Sprintf("Cannot create field '%s' in element {%s}", parts[0], formatValue(container)))
+}
+
+// unsetPath remove the value at path and return the updated container. Array elements are set to null, as the server does.
+func unsetPath(container interface{}, parts []string) interface{} {
+	switch cur := container.(type) {
+	case primitive.D:
+		for i, e := range cur {
+			if e.Key != parts[0] {
+				continue
+			}
+			if len(parts) == 1 {
+				return append(cur[:i:i], cur[i+1:]...)
+			}
+			cur[i].Value = unsetPath(e.Value, parts[1:])
+			return cur
+		}
+	case primitive.A:
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil || idx < 0 || idx >= len(cur) {
+			return cur
+		}
+		if len(parts) == 1 {
+			cur[idx] = nil
+			return cur
+		}
+		cur[idx] = unsetPath(cur[idx], parts[1:])
+	}
+	return container
+}
+
+// formatValue render a value the way the server shows it in error messages
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(val)
+	case primitive.ObjectID:
+		return fmt.Sprintf("ObjectId('%s')", val.Hex())
+	case primitive.DateTime:
+		return fmt.Sprintf("new Date(%d)", int64(val))
+	case primitive.D:
+		parts := make([]string, len(val))
+		for i, e := range val {
+			parts[i] = e.Key + ": " + formatValue(e.Value)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	case primitive.A:
+		parts := make([]string, len(val))
+		for i, e := range val {
+			parts[i] = formatValue(e)
+		}
+		return "[ " + strings.Join(parts, ", ") + " ]"
+	}
+	return fmt.Sprint(v)
+}
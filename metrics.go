@@ -0,0 +1,70 @@
+package gomongo
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolStats is the state of the connection pool to one server
+type PoolStats struct {
+	Address string
+	// Open is the number of connections in the pool, idle or in use
+	Open int64
+	// InUse is the number of connections checked out by operations
+	InUse int64
+	// CheckoutFailed counts the times an operation could not get a connection
+	CheckoutFailed int64
+}
+
+// MetricsRecorder collects the metrics of a Client. The github.com/sagiforbes/gomongo/metrics package has
+// expvar and Prometheus implementations. Implementations must be safe for concurrent use.
+type MetricsRecorder interface {
+	// RecordOperation is called when an operation ends. op holds the duration, result counts and error.
+	RecordOperation(op *Operation)
+	// RecordPool is called each time the connection pool of a server changes
+	RecordPool(stats PoolStats)
+}
+
+// poolTracker turn driver pool events into PoolStats
+type poolTracker struct {
+	mu       sync.Mutex
+	pools    map[string]*PoolStats
+	recorder MetricsRecorder
+}
+
+func newPoolTracker(recorder MetricsRecorder) *poolTracker {
+	return &poolTracker{pools: map[string]*PoolStats{}, recorder: recorder}
+}
+
+func (p *poolTracker) monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: p.event}
+}
+
+func (p *poolTracker) event(e *event.PoolEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats, ok := p.pools[e.Address]
+	if !ok {
+		stats = &PoolStats{Address: e.Address}
+		p.pools[e.Address] = stats
+	}
+	switch e.Type {
+	case event.ConnectionCreated:
+		stats.Open++
+	case event.ConnectionClosed:
+		stats.Open--
+	case event.GetSucceeded:
+		stats.InUse++
+	case event.ConnectionReturned:
+		stats.InUse--
+	case event.GetFailed:
+		stats.CheckoutFailed++
+	case event.PoolClosedEvent:
+		stats.Open = 0
+		stats.InUse = 0
+	default:
+		return
+	}
+	p.recorder.RecordPool(*stats)
+}
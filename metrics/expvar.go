@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"expvar"
+	"strconv"
+)
+
+// Expvar is a gomongo.MetricsRecorder that publishes the metrics as an expvar variable, served by the
+// /debug/vars handler of the expvar package.
+type Expvar struct {
+	*registry
+}
+
+// NewExpvar publish the metrics under name and return the recorder. Like expvar.Publish it panics when
+// name is already in use. DefaultBuckets are used when no latency buckets are given.
+func NewExpvar(name string, buckets ...float64) *Expvar {
+	e := &Expvar{registry: newRegistry(buckets)}
+	expvar.Publish(name, expvar.Func(e.Value))
+	return e
+}
+
+// ExpvarOperation is the published form of the metrics of one operation on one collection
+type ExpvarOperation struct {
+	Database   string            `json:"database"`
+	Collection string            `json:"collection"`
+	Operation  string            `json:"operation"`
+	Count      uint64            `json:"count"`
+	Errors     uint64            `json:"errors"`
+	Retries    uint64            `json:"retries"`
+	Returned   int64             `json:"returned"`
+	SumSeconds float64           `json:"sum_seconds"`
+	Buckets    map[string]uint64 `json:"buckets"`
+}
+
+// ExpvarPool is the published form of the metrics of one server pool
+type ExpvarPool struct {
+	Address        string `json:"address"`
+	Open           int64  `json:"open"`
+	InUse          int64  `json:"in_use"`
+	CheckoutFailed int64  `json:"checkout_failed"`
+}
+
+// ExpvarCache is the published form of the read cache metrics of one collection
+type ExpvarCache struct {
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+}
+
+// Value return the current metrics, as published to expvar. Histogram buckets are cumulative and keyed by their upper bound in seconds.
+func (e *Expvar) Value() interface{} {
+	keys, ops, pools := e.snapshot()
+
+	retOps := make([]ExpvarOperation, len(keys))
+	for i, k := range keys {
+		buckets := make(map[string]uint64, len(e.buckets)+1)
+		var cumulative uint64
+		for b, bound := range e.buckets {
+			cumulative += ops[i].Buckets[b]
+			buckets[strconv.FormatFloat(bound, 'g', -1, 64)] = cumulative
+		}
+		buckets["+Inf"] = ops[i].Count
+		retOps[i] = ExpvarOperation{
+			Database:   k.Database,
+			Collection: k.Collection,
+			Operation:  k.Operation,
+			Count:      ops[i].Count,
+			Errors:     ops[i].Errors,
+			Retries:    ops[i].Retries,
+			Returned:   ops[i].Returned,
+			SumSeconds: ops[i].Sum,
+			Buckets:    buckets,
+		}
+	}
+
+	retPools := make([]ExpvarPool, len(pools))
+	for i, p := range pools {
+		retPools[i] = ExpvarPool{Address: p.Address, Open: p.Open, InUse: p.InUse, CheckoutFailed: p.CheckoutFailed}
+	}
+
+	cacheKeys, caches := e.cacheSnapshot()
+	retCaches := make([]ExpvarCache, len(cacheKeys))
+	for i, k := range cacheKeys {
+		retCaches[i] = ExpvarCache{Database: k.Database, Collection: k.Collection, Hits: caches[i].Hits, Misses: caches[i].Misses}
+	}
+	return map[string]interface{}{
+		"operations": retOps,
+		"pools":      retPools,
+		"cache":      retCaches,
+	}
+}
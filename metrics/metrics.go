@@ -0,0 +1,167 @@
+// Package metrics holds gomongo.MetricsRecorder implementations that publish client metrics through expvar or
+// as a Prometheus text format endpoint. Neither pulls in dependencies beyond the standard library.
+//
+// Both recorders keep, per database, collection and operation, a latency histogram, error and retry counts and the
+// number of documents returned, plus the open and in use connections of every server pool, and per collection the
+// hits and misses of the read cache.
+package metrics
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sagiforbes/gomongo"
+)
+
+// DefaultBuckets are the latency histogram upper bounds in seconds
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type opKey struct {
+	Database   string
+	Collection string
+	Operation  string
+}
+
+type opStats struct {
+	Count    uint64
+	Errors   uint64
+	Retries  uint64
+	Returned int64
+	Sum      float64
+	// Buckets holds non cumulative counts per bucket, the last one counts values above every bound
+	Buckets []uint64
+}
+
+type cacheKey struct {
+	Database   string
+	Collection string
+}
+
+type cacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// registry aggregate the metrics shared by all recorders
+type registry struct {
+	mu      sync.Mutex
+	buckets []float64
+	ops     map[opKey]*opStats
+	pools   map[string]gomongo.PoolStats
+	caches  map[cacheKey]*cacheStats
+}
+
+func newRegistry(buckets []float64) *registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &registry{
+		buckets: b,
+		ops:     map[opKey]*opStats{},
+		pools:   map[string]gomongo.PoolStats{},
+		caches:  map[cacheKey]*cacheStats{},
+	}
+}
+
+func (r *registry) RecordOperation(op *gomongo.Operation) {
+	key := opKey{Database: op.Database, Collection: op.Collection, Operation: op.Name}
+	seconds := op.Duration.Seconds()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.ops[key]
+	if !ok {
+		stats = &opStats{Buckets: make([]uint64, len(r.buckets)+1)}
+		r.ops[key] = stats
+	}
+	stats.Count++
+	stats.Sum += seconds
+	stats.Returned += op.Returned
+	if op.Err != nil {
+		stats.Errors++
+	}
+	if op.Attempts > 1 {
+		stats.Retries += uint64(op.Attempts - 1)
+	}
+	stats.Buckets[sort.SearchFloat64s(r.buckets, seconds)]++
+}
+
+func (r *registry) RecordPool(stats gomongo.PoolStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pools[stats.Address] = stats
+}
+
+func (r *registry) RecordCache(database string, collName string, hit bool) {
+	key := cacheKey{Database: database, Collection: collName}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.caches[key]
+	if !ok {
+		stats = &cacheStats{}
+		r.caches[key] = stats
+	}
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+}
+
+// cacheSnapshot return a copy of the cache metrics sorted by key
+func (r *registry) cacheSnapshot() ([]cacheKey, []cacheStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]cacheKey, 0, len(r.caches))
+	for k := range r.caches {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Database != keys[j].Database {
+			return keys[i].Database < keys[j].Database
+		}
+		return keys[i].Collection < keys[j].Collection
+	})
+	stats := make([]cacheStats, len(keys))
+	for i, k := range keys {
+		stats[i] = *r.caches[k]
+	}
+	return keys, stats
+}
+
+// snapshot return a copy of the metrics sorted by key, so output is stable
+func (r *registry) snapshot() ([]opKey, []opStats, []gomongo.PoolStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]opKey, 0, len(r.ops))
+	for k := range r.ops {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		if a.Collection != b.Collection {
+			return a.Collection < b.Collection
+		}
+		return a.Operation < b.Operation
+	})
+	ops := make([]opStats, len(keys))
+	for i, k := range keys {
+		ops[i] = *r.ops[k]
+		ops[i].Buckets = append([]uint64(nil), r.ops[k].Buckets...)
+	}
+
+	pools := make([]gomongo.PoolStats, 0, len(r.pools))
+	for _, p := range r.pools {
+		pools = append(pools, p)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Address < pools[j].Address })
+	return keys, ops, pools
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+)
+
+func record(r gomongo.MetricsRecorder) {
+	r.RecordOperation(&gomongo.Operation{Name: gomongo.OpFind, Database: "db", Collection: "users", Duration: 3 * time.Millisecond, Returned: 4})
+	r.RecordOperation(&gomongo.Operation{Name: gomongo.OpFind, Database: "db", Collection: "users", Duration: 2 * time.Second, Returned: 1, Attempts: 3})
+	r.RecordOperation(&gomongo.Operation{Name: gomongo.OpInsertOne, Database: "db", Collection: "users", Duration: time.Millisecond, Err: errors.New("failed")})
+	r.RecordPool(gomongo.PoolStats{Address: "localhost:27017", Open: 3, InUse: 1})
+	cache := r.(gomongo.CacheMetricsRecorder)
+	cache.RecordCache("db", "users", true)
+	cache.RecordCache("db", "users", true)
+	cache.RecordCache("db", "users", false)
+}
+
+func testPrometheus(t *testing.T) {
+	prom := NewPrometheus()
+	record(prom)
+
+	rec := httptest.NewRecorder()
+	prom.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	expected := []string{
+		`gomongo_operation_duration_seconds_bucket{database="db",collection="users",operation="find",le="0.001"} 0`,
+		`gomongo_operation_duration_seconds_bucket{database="db",collection="users",operation="find",le="0.005"} 1`,
+		`gomongo_operation_duration_seconds_bucket{database="db",collection="users",operation="find",le="2.5"} 2`,
+		`gomongo_operation_duration_seconds_bucket{database="db",collection="users",operation="find",le="+Inf"} 2`,
+		`gomongo_operation_duration_seconds_count{database="db",collection="users",operation="find"} 2`,
+		`gomongo_operation_errors_total{database="db",collection="users",operation="insertOne"} 1`,
+		`gomongo_operation_errors_total{database="db",collection="users",operation="find"} 0`,
+		`gomongo_operation_retries_total{database="db",collection="users",operation="find"} 2`,
+		`gomongo_documents_returned_total{database="db",collection="users",operation="find"} 5`,
+		`gomongo_pool_open_connections{address="localhost:27017"} 3`,
+		`gomongo_pool_in_use_connections{address="localhost:27017"} 1`,
+		`gomongo_cache_hits_total{database="db",collection="users"} 2`,
+		`gomongo_cache_misses_total{database="db",collection="users"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing line %s", line)
+		}
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+func testExpvar(t *testing.T) {
+	e := NewExpvar("gomongo_test")
+	record(e)
+
+	value := e.Value().(map[string]interface{})
+	ops := value["operations"].([]ExpvarOperation)
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 operations got %d", len(ops))
+	}
+	find := ops[0]
+	if find.Operation != gomongo.OpFind || find.Count != 2 || find.Returned != 5 || find.Retries != 2 || find.Buckets["0.005"] != 1 || find.Buckets["+Inf"] != 2 {
+		t.Errorf("unexpected find metrics %+v", find)
+	}
+	if ops[1].Errors != 1 {
+		t.Errorf("insert error was not counted")
+	}
+	pools := value["pools"].([]ExpvarPool)
+	if len(pools) != 1 || pools[0].Open != 3 {
+		t.Errorf("unexpected pools %+v", pools)
+	}
+	caches := value["cache"].([]ExpvarCache)
+	if len(caches) != 1 || caches[0].Hits != 2 || caches[0].Misses != 1 {
+		t.Errorf("unexpected cache metrics %+v", caches)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("prometheus", testPrometheus)
+	t.Run("expvar", testExpvar)
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Prometheus is a gomongo.MetricsRecorder that serves the metrics in the Prometheus text exposition format.
+// Mount it on the path scraped by Prometheus:
+//
+//	prom := metrics.NewPrometheus()
+//	gmc := gomongo.NewClient(host, "my_database").SetMetrics(prom)
+//	http.Handle("/metrics", prom)
+type Prometheus struct {
+	*registry
+}
+
+// NewPrometheus return a recorder with the given latency buckets, in seconds. DefaultBuckets are used when none are given.
+func NewPrometheus(buckets ...float64) *Prometheus {
+	return &Prometheus{registry: newRegistry(buckets)}
+}
+
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo write the metrics to w in the Prometheus text format
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	keys, ops, pools := p.snapshot()
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	cw.printf("# HELP gomongo_operation_duration_seconds Duration of gomongo operations.\n")
+	cw.printf("# TYPE gomongo_operation_duration_seconds histogram\n")
+	for i, k := range keys {
+		labels := opLabels(k)
+		var cumulative uint64
+		for b, bound := range p.buckets {
+			cumulative += ops[i].Buckets[b]
+			cw.printf("gomongo_operation_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(bound), cumulative)
+		}
+		cw.printf("gomongo_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, ops[i].Count)
+		cw.printf("gomongo_operation_duration_seconds_sum{%s} %s\n", labels, formatFloat(ops[i].Sum))
+		cw.printf("gomongo_operation_duration_seconds_count{%s} %d\n", labels, ops[i].Count)
+	}
+
+	cw.printf("# HELP gomongo_operation_errors_total Failed gomongo operations.\n")
+	cw.printf("# TYPE gomongo_operation_errors_total counter\n")
+	for i, k := range keys {
+		cw.printf("gomongo_operation_errors_total{%s} %d\n", opLabels(k), ops[i].Errors)
+	}
+
+	cw.printf("# HELP gomongo_operation_retries_total Attempts of gomongo operations beyond the first.\n")
+	cw.printf("# TYPE gomongo_operation_retries_total counter\n")
+	for i, k := range keys {
+		cw.printf("gomongo_operation_retries_total{%s} %d\n", opLabels(k), ops[i].Retries)
+	}
+
+	cw.printf("# HELP gomongo_documents_returned_total Documents returned by gomongo read operations.\n")
+	cw.printf("# TYPE gomongo_documents_returned_total counter\n")
+	for i, k := range keys {
+		cw.printf("gomongo_documents_returned_total{%s} %d\n", opLabels(k), ops[i].Returned)
+	}
+
+	cw.printf("# HELP gomongo_pool_open_connections Connections in the pool of a server.\n")
+	cw.printf("# TYPE gomongo_pool_open_connections gauge\n")
+	for _, pool := range pools {
+		cw.printf("gomongo_pool_open_connections{address=\"%s\"} %d\n", escapeLabel(pool.Address), pool.Open)
+	}
+	cw.printf("# HELP gomongo_pool_in_use_connections Connections checked out of the pool of a server.\n")
+	cw.printf("# TYPE gomongo_pool_in_use_connections gauge\n")
+	for _, pool := range pools {
+		cw.printf("gomongo_pool_in_use_connections{address=\"%s\"} %d\n", escapeLabel(pool.Address), pool.InUse)
+	}
+	cw.printf("# HELP gomongo_pool_checkout_failures_total Failed attempts to check out a connection.\n")
+	cw.printf("# TYPE gomongo_pool_checkout_failures_total counter\n")
+	for _, pool := range pools {
+		cw.printf("gomongo_pool_checkout_failures_total{address=\"%s\"} %d\n", escapeLabel(pool.Address), pool.CheckoutFailed)
+	}
+
+	cacheKeys, caches := p.cacheSnapshot()
+	cw.printf("# HELP gomongo_cache_hits_total Lookups served from the gomongo read cache.\n")
+	cw.printf("# TYPE gomongo_cache_hits_total counter\n")
+	for i, k := range cacheKeys {
+		cw.printf("gomongo_cache_hits_total{%s} %d\n", cacheLabels(k), caches[i].Hits)
+	}
+	cw.printf("# HELP gomongo_cache_misses_total Lookups of the gomongo read cache sent to the database.\n")
+	cw.printf("# TYPE gomongo_cache_misses_total counter\n")
+	for i, k := range cacheKeys {
+		cw.printf("gomongo_cache_misses_total{%s} %d\n", cacheLabels(k), caches[i].Misses)
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func opLabels(k opKey) string {
+	return fmt.Sprintf("database=\"%s\",collection=\"%s\",operation=\"%s\"",
+		escapeLabel(k.Database), escapeLabel(k.Collection), escapeLabel(k.Operation))
+}
+
+func cacheLabels(k cacheKey) string {
+	return fmt.Sprintf("database=\"%s\",collection=\"%s\"", escapeLabel(k.Database), escapeLabel(k.Collection))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countWriter keep the first write error and the number of bytes written
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
@@ -0,0 +1,46 @@
+package gomongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+type poolRecorder struct {
+	last PoolStats
+	ops  []string
+}
+
+func (r *poolRecorder) RecordOperation(op *Operation) {
+	r.ops = append(r.ops, op.Name)
+}
+
+func (r *poolRecorder) RecordPool(stats PoolStats) {
+	r.last = stats
+}
+
+func TestGomongoPoolTracker(t *testing.T) {
+	rec := &poolRecorder{}
+	monitor := newPoolTracker(rec).monitor()
+	for _, typ := range []string{event.ConnectionCreated, event.ConnectionCreated, event.GetSucceeded, event.GetSucceeded, event.ConnectionReturned, event.GetFailed} {
+		monitor.Event(&event.PoolEvent{Type: typ, Address: "localhost:27017"})
+	}
+	if rec.last.Address != "localhost:27017" || rec.last.Open != 2 || rec.last.InUse != 1 || rec.last.CheckoutFailed != 1 {
+		t.Errorf("unexpected pool stats %+v", rec.last)
+	}
+
+	monitor.Event(&event.PoolEvent{Type: event.PoolClosedEvent, Address: "localhost:27017"})
+	if rec.last.Open != 0 || rec.last.InUse != 0 {
+		t.Errorf("closed pool still has connections %+v", rec.last)
+	}
+}
+
+func TestGomongoRecordOperation(t *testing.T) {
+	rec := &poolRecorder{}
+	c := NewClient(HOST, DB_NAME).SetMetrics(rec)
+	c.run(&Operation{Name: OpCountDocuments}, func(ctx context.Context) error { return nil })
+	if len(rec.ops) != 1 || rec.ops[0] != OpCountDocuments {
+		t.Errorf("operation was not recorded %v", rec.ops)
+	}
+}
@@ -5,74 +5,148 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// result is a pointer to a result type. Every result type holds the error of its operation.
+type result[R any] interface {
+	*R
+	setErr(err error)
+	err() error
+}
+
 type ReadOneResult[T any] struct {
 	Found    bool
 	Document T
 	Err      error
 	DbRes    *mongo.SingleResult
+	Attempts int
 }
 
+func (r *ReadOneResult[T]) setErr(err error) { r.Err = err }
+func (r *ReadOneResult[T]) err() error       { return r.Err }
+
 type ReadManyResult[T any] struct {
 	Documents []T
 	Err       error
+	Attempts  int
 }
 
+func (r *ReadManyResult[T]) setErr(err error) { r.Err = err }
+func (r *ReadManyResult[T]) err() error       { return r.Err }
+
 type ReadStreamResult[T any] struct {
 	DocumentStream chan ReadOneResult[T]
 	Err            error
+	Attempts       int
 }
 
+func (r *ReadStreamResult[T]) setErr(err error) { r.Err = err }
+func (r *ReadStreamResult[T]) err() error       { return r.Err }
+
 type DistinctResult[T any] struct {
-	Values []T
-	Err    error
+	Values   []T
+	Err      error
+	Attempts int
+}
+
+func (r *DistinctResult[T]) setErr(err error) { r.Err = err }
+func (r *DistinctResult[T]) err() error       { return r.Err }
+
+// WriteError is a document or model that failed to be written. Index is its position in the slice given to the write
+// and ID the _id of the document, when known. Retryable tell if writing it again may succeed.
+type WriteError struct {
+	Index     int
+	Code      int
+	Message   string
+	ID        interface{}
+	Retryable bool
 }
 
 type WriteManyResult struct {
-	DbRes *mongo.InsertManyResult
-	Err   error
+	DbRes             *mongo.InsertManyResult
+	WriteErrors       []WriteError
+	WriteConcernError *mongo.WriteConcernError
+	Err               error
+	Attempts          int
 }
 
+func (r *WriteManyResult) setErr(err error) { r.Err = err }
+func (r *WriteManyResult) err() error       { return r.Err }
+
 type WriteOneResult struct {
-	DbRes *mongo.InsertOneResult
-	Err   error
+	DbRes    *mongo.InsertOneResult
+	Err      error
+	Attempts int
 }
 
+func (r *WriteOneResult) setErr(err error) { r.Err = err }
+func (r *WriteOneResult) err() error       { return r.Err }
+
 type UpdateResult struct {
-	DbRes *mongo.UpdateResult
-	Err   error
+	DbRes    *mongo.UpdateResult
+	Err      error
+	Attempts int
 }
 
+func (r *UpdateResult) setErr(err error) { r.Err = err }
+func (r *UpdateResult) err() error       { return r.Err }
+
 type BulkWriteResult struct {
-	Err   error
-	DbRes *mongo.BulkWriteResult
+	Err               error
+	DbRes             *mongo.BulkWriteResult
+	WriteErrors       []WriteError
+	WriteConcernError *mongo.WriteConcernError
+	Attempts          int
 }
 
+func (r *BulkWriteResult) setErr(err error) { r.Err = err }
+func (r *BulkWriteResult) err() error       { return r.Err }
+
 type DeleteResult struct {
 	DelCount int64
 	Err      error
+	Attempts int
 }
 
+func (r *DeleteResult) setErr(err error) { r.Err = err }
+func (r *DeleteResult) err() error       { return r.Err }
+
 type CountResult struct {
-	Count int64
-	Err   error
+	Count    int64
+	Err      error
+	Attempts int
 }
 
+func (r *CountResult) setErr(err error) { r.Err = err }
+func (r *CountResult) err() error       { return r.Err }
+
 type CommandResult struct {
 	DbRes *mongo.SingleResult
 	Err   error
 }
 
+func (r *CommandResult) setErr(err error) { r.Err = err }
+func (r *CommandResult) err() error       { return r.Err }
+
 type IndexCreateResult struct {
 	IndexName string
 	Err       error
 }
 
+func (r *IndexCreateResult) setErr(err error) { r.Err = err }
+func (r *IndexCreateResult) err() error       { return r.Err }
+
 type IndexDropResult struct {
 	Doc bson.Raw
 	Err error
 }
 
+func (r *IndexDropResult) setErr(err error) { r.Err = err }
+func (r *IndexDropResult) err() error       { return r.Err }
+
 type IndexListResult struct {
-	Result []interface{}
-	Err    error
+	Result   []interface{}
+	Err      error
+	Attempts int
 }
+
+func (r *IndexListResult) setErr(err error) { r.Err = err }
+func (r *IndexListResult) err() error       { return r.Err }
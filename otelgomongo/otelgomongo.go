@@ -0,0 +1,205 @@
+// Package otelgomongo traces gomongo operations with OpenTelemetry.
+//
+// It is kept apart from gomongo so programs that do not trace do not depend on OpenTelemetry. Register the hook on a client
+// and pass the request context with Client.WithContext so spans join the caller's trace:
+//
+//	gmc := gomongo.NewClient(host, "my_database").AddHook(otelgomongo.NewHook())
+//	res := gomongo.FindSync[User](gmc.WithContext(ctx), "users", bson.M{"id": userId})
+package otelgomongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/sagiforbes/gomongo/otelgomongo"
+
+// Result and error attributes added to every span
+const (
+	MatchedKey   = attribute.Key("db.mongodb.matched_count")
+	ModifiedKey  = attribute.Key("db.mongodb.modified_count")
+	DeletedKey   = attribute.Key("db.mongodb.deleted_count")
+	ReturnedKey  = attribute.Key("db.mongodb.returned_count")
+	ErrorCodeKey = attribute.Key("db.mongodb.error_code")
+	ErrorMsgKey  = attribute.Key("gomongo.error")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	statement      bool
+	comment        bool
+}
+
+// Option configures the hook returned by NewHook
+type Option func(*config)
+
+// WithTracerProvider set the provider used to create the tracer. The global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithoutStatement stop recording the sanitized filter as db.statement
+func WithoutStatement() Option {
+	return func(c *config) {
+		c.statement = false
+	}
+}
+
+// WithoutComment stop sending the trace ID to the server as the operation $comment
+func WithoutComment() Option {
+	return func(c *config) {
+		c.comment = false
+	}
+}
+
+type hook struct {
+	tracer    trace.Tracer
+	statement bool
+	comment   bool
+}
+
+// NewHook return a gomongo.Hook that opens a span for each operation of the client
+func NewHook(opts ...Option) gomongo.Hook {
+	cfg := config{statement: true, comment: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+	return &hook{
+		tracer:    cfg.tracerProvider.Tracer(tracerName),
+		statement: cfg.statement,
+		comment:   cfg.comment,
+	}
+}
+
+func (h *hook) BeforeOperation(ctx context.Context, op *gomongo.Operation) context.Context {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBName(op.Database),
+		semconv.DBOperation(op.Name),
+	}
+	if op.Collection != "" {
+		attrs = append(attrs, semconv.DBMongoDBCollection(op.Collection))
+	}
+	if h.statement && op.Filter != nil {
+		attrs = append(attrs, semconv.DBStatement(Sanitize(op.Filter)))
+	}
+
+	ctx, span := h.tracer.Start(ctx, spanName(op),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	if h.comment && span.SpanContext().HasTraceID() {
+		op.Comment = span.SpanContext().TraceID().String()
+	}
+	return ctx
+}
+
+func (h *hook) AfterOperation(ctx context.Context, op *gomongo.Operation) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		MatchedKey.Int64(op.Matched),
+		ModifiedKey.Int64(op.Modified),
+		DeletedKey.Int64(op.Deleted),
+		ReturnedKey.Int64(op.Returned),
+	)
+	if op.Err != nil {
+		recordError(span, op.Err)
+	}
+	span.End()
+}
+
+func spanName(op *gomongo.Operation) string {
+	if op.Collection == "" {
+		return op.Name
+	}
+	return op.Collection + "." + op.Name
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	var gerr *gomongo.GomongoError
+	if errors.As(err, &gerr) {
+		span.SetAttributes(ErrorMsgKey.String(gerr.Err.Error()))
+		if gerr.Code != 0 {
+			span.SetAttributes(ErrorCodeKey.Int(gerr.Code))
+		}
+	}
+}
+
+// Sanitize render a filter, pipeline or command as JSON with every literal value replaced by "?".
+// Field names and operators are kept so the statement still shows the shape of the query.
+func Sanitize(filter interface{}) string {
+	raw, err := bson.Marshal(bson.D{{Key: "v", Value: filter}})
+	if err != nil {
+		return "?"
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil || len(doc) != 1 {
+		return "?"
+	}
+	var sb strings.Builder
+	writeMasked(&sb, doc[0].Value)
+	return sb.String()
+}
+
+func writeMasked(sb *strings.Builder, v interface{}) {
+	switch val := v.(type) {
+	case primitive.D:
+		sb.WriteByte('{')
+		for i, e := range val {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeKey(sb, e.Key)
+			writeMasked(sb, e.Value)
+		}
+		sb.WriteByte('}')
+	case primitive.A:
+		if !hasDocument(val) {
+			sb.WriteString(`"?"`)
+			return
+		}
+		sb.WriteByte('[')
+		for i, e := range val {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeMasked(sb, e)
+		}
+		sb.WriteByte(']')
+	default:
+		sb.WriteString(`"?"`)
+	}
+}
+
+func writeKey(sb *strings.Builder, key string) {
+	sb.WriteByte('"')
+	sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key))
+	sb.WriteString(`":`)
+}
+
+func hasDocument(arr primitive.A) bool {
+	for _, e := range arr {
+		if _, ok := e.(primitive.D); ok {
+			return true
+		}
+	}
+	return false
+}
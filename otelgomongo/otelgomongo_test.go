@@ -0,0 +1,128 @@
+package otelgomongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func testSpanAttributes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	h := NewHook(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	op := &gomongo.Operation{Name: gomongo.OpUpdateMany, Database: "test_db", Collection: "users", Filter: bson.M{"name": "secret"}}
+	ctx := h.BeforeOperation(context.Background(), op)
+	if op.Comment == "" {
+		t.Errorf("trace id was not set as comment")
+	}
+	op.Matched = 3
+	op.Modified = 2
+	h.AfterOperation(ctx, op)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "users.updateMany" {
+		t.Errorf("unexpected span name %s", span.Name())
+	}
+	if op.Comment != span.SpanContext().TraceID().String() {
+		t.Errorf("comment %s is not the trace id", op.Comment)
+	}
+
+	expected := map[attribute.Key]string{
+		"db.system":             "mongodb",
+		"db.name":               "test_db",
+		"db.mongodb.collection": "users",
+		"db.operation":          gomongo.OpUpdateMany,
+		"db.statement":          `{"name":"?"}`,
+	}
+	for k, v := range expected {
+		val, ok := attrValue(span.Attributes(), k)
+		if !ok || val.AsString() != v {
+			t.Errorf("attribute %s is %q expected %q", k, val.AsString(), v)
+		}
+	}
+	if val, _ := attrValue(span.Attributes(), MatchedKey); val.AsInt64() != 3 {
+		t.Errorf("matched count is %d", val.AsInt64())
+	}
+	if val, _ := attrValue(span.Attributes(), ModifiedKey); val.AsInt64() != 2 {
+		t.Errorf("modified count is %d", val.AsInt64())
+	}
+}
+
+func testSpanError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	h := NewHook(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))), WithoutComment())
+
+	op := &gomongo.Operation{Name: gomongo.OpInsertOne, Collection: "users"}
+	ctx := h.BeforeOperation(context.Background(), op)
+	if op.Comment != "" {
+		t.Errorf("comment should not be set")
+	}
+	op.Err = gomongo.NewError(gomongo.MsgGomongoInsertManyError, mongo.CommandError{Code: 11000, Message: "duplicate key"})
+	h.AfterOperation(ctx, op)
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status is not error")
+	}
+	if val, ok := attrValue(span.Attributes(), ErrorCodeKey); !ok || val.AsInt64() != 11000 {
+		t.Errorf("error code attribute missing")
+	}
+	if _, ok := attrValue(span.Attributes(), ErrorMsgKey); !ok {
+		t.Errorf("gomongo error attribute missing")
+	}
+	if len(span.Events()) == 0 {
+		t.Errorf("error was not recorded")
+	}
+
+	op = &gomongo.Operation{Name: gomongo.OpInsertOne, Collection: "users"}
+	ctx = h.BeforeOperation(context.Background(), op)
+	op.Err = errors.New("plain error")
+	h.AfterOperation(ctx, op)
+	if _, ok := attrValue(recorder.Ended()[1].Attributes(), ErrorCodeKey); ok {
+		t.Errorf("plain error should not have a server code")
+	}
+}
+
+func testSanitize(t *testing.T) {
+	cases := []struct {
+		filter   interface{}
+		expected string
+	}{
+		{bson.D{{Key: "name", Value: "x"}, {Key: "age", Value: bson.M{"$gt": 3}}}, `{"name":"?","age":{"$gt":"?"}}`},
+		{bson.M{"id": bson.M{"$in": []string{"a", "b"}}}, `{"id":{"$in":"?"}}`},
+		{bson.A{bson.M{"$match": bson.M{"a": 1}}, bson.M{"$limit": 5}}, `[{"$match":{"a":"?"}},{"$limit":"?"}]`},
+		{bson.M{"$or": bson.A{bson.M{"a": 1}, bson.M{"b": 2}}}, `{"$or":[{"a":"?"},{"b":"?"}]}`},
+	}
+	for _, c := range cases {
+		if got := Sanitize(c.filter); got != c.expected {
+			t.Errorf("sanitize %v: got %s expected %s", c.filter, got, c.expected)
+		}
+	}
+}
+
+func TestOtelHook(t *testing.T) {
+	t.Run("attributes", testSpanAttributes)
+	t.Run("error", testSpanError)
+	t.Run("sanitize", testSanitize)
+}
@@ -0,0 +1,396 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultPopulateBatchSize is the number of references fetched by each $in query of Populate
+const DefaultPopulateBatchSize = 500
+
+// populateField is the temporary field of the $lookup of PopulatePipeline
+const populateField = "_populated"
+
+// PopulateOptions tell how documents reference the documents of another collection
+type PopulateOptions struct {
+	// LocalField is the path of the reference in the documents, as userId or items.productId. Arrays along the
+	// path are followed, and the reference itself may be an array.
+	LocalField string
+	// From is the collection of the referenced documents
+	From string
+	// ForeignField is the field of the referenced documents matching the reference. Default is _id.
+	ForeignField string
+	// As is the field set with the referenced document, next to the reference: with LocalField items.productId and
+	// As product, each item gets a product. It holds an array of documents when the reference is an array.
+	As string
+	// BatchSize is the number of references fetched per query. Default is DefaultPopulateBatchSize.
+	BatchSize int
+	// Find are the options of the queries of the referenced documents, as a projection
+	Find *options.FindOptions
+}
+
+type PopulateResult struct {
+	// Fetched is the number of referenced documents found
+	Fetched int
+	// Missing are the references with no document
+	Missing []interface{}
+	Err     error
+}
+
+type PopulateMapResult[K comparable, R any] struct {
+	// Documents are the referenced documents by their ForeignField
+	Documents map[K]R
+	Missing   []interface{}
+	Err       error
+}
+
+// Populate fetch the documents referenced by docs with batched $in queries on opt.From and set them in the opt.As
+// field of each document. T must have a field for As that the referenced documents decode into, a slice when the
+// reference is an array.
+//
+//	gomongo.Populate(gmc, addresses, gomongo.PopulateOptions{LocalField: "userId", From: "users", ForeignField: "id", As: "user"})
+func Populate[T any](c *Client, docs []T, opt PopulateOptions) PopulateResult {
+	opt = opt.withDefaults()
+	if opt.As == "" {
+		return PopulateResult{Err: NewError(MsgGomongoPopulateError, errors.New("As is not set"))}
+	}
+	raws, refs, err := populateRefs(docs, opt)
+	if err != nil {
+		return PopulateResult{Err: err}
+	}
+	found, err := fetchRefs(c, opt, refs)
+	if err != nil {
+		return PopulateResult{Err: err}
+	}
+
+	path := strings.Split(opt.LocalField, ".")
+	for i, raw := range raws {
+		var d bson.D
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return PopulateResult{Err: NewError(MsgGomongoPopulateError, err)}
+		}
+		out, err := bson.Marshal(setRefs(d, path, opt.As, found))
+		if err != nil {
+			return PopulateResult{Err: NewError(MsgGomongoPopulateError, err)}
+		}
+		if err := bson.Unmarshal(out, &docs[i]); err != nil {
+			return PopulateResult{Err: NewError(MsgGomongoUnmarshalError, err)}
+		}
+	}
+	return PopulateResult{Fetched: countFound(found), Missing: missingRefs(refs, found)}
+}
+
+// PopulateMap fetch the documents referenced by docs with batched $in queries on opt.From and return them by
+// their opt.ForeignField, of type K. opt.As is not used.
+func PopulateMap[K comparable, R any, T any](c *Client, docs []T, opt PopulateOptions) PopulateMapResult[K, R] {
+	opt = opt.withDefaults()
+	_, refs, err := populateRefs(docs, opt)
+	if err != nil {
+		return PopulateMapResult[K, R]{Err: err}
+	}
+	found, err := fetchRefs(c, opt, refs)
+	if err != nil {
+		return PopulateMapResult[K, R]{Err: err}
+	}
+
+	ret := PopulateMapResult[K, R]{Documents: make(map[K]R, len(found)), Missing: missingRefs(refs, found)}
+	for _, raw := range found {
+		v, err := raw.LookupErr(strings.Split(opt.ForeignField, ".")...)
+		if err != nil {
+			continue
+		}
+		var doc R
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return PopulateMapResult[K, R]{Err: NewError(MsgGomongoUnmarshalError, err)}
+		}
+		for _, kv := range flatten(v) {
+			var key K
+			if err := kv.Unmarshal(&key); err != nil {
+				return PopulateMapResult[K, R]{Err: NewError(MsgGomongoUnmarshalError, err)}
+			}
+			ret.Documents[key] = doc
+		}
+	}
+	return ret
+}
+
+// PopulatePipeline return the aggregation stages that populate opt.As on the server with $lookup, to append to a
+// pipeline. LocalField may have one level of nesting, as items.productId.
+func PopulatePipeline(opts ...PopulateOptions) (mongo.Pipeline, error) {
+	var pipeline mongo.Pipeline
+	for _, opt := range opts {
+		opt = opt.withDefaults()
+		if opt.As == "" || opt.From == "" || opt.LocalField == "" {
+			return nil, NewError(MsgGomongoPopulateError, errors.New("LocalField, From and As must be set"))
+		}
+		path := strings.Split(opt.LocalField, ".")
+		if len(path) > 2 {
+			return nil, NewError(MsgGomongoPopulateError, errors.New("$lookup supports one level of nesting in LocalField"))
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: opt.From},
+			{Key: "localField", Value: opt.LocalField},
+			{Key: "foreignField", Value: opt.ForeignField},
+			{Key: "as", Value: populateField},
+		}}})
+
+		if len(path) == 1 {
+			pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.D{
+				{Key: opt.As, Value: lookupRefs("$"+opt.LocalField, opt.ForeignField)},
+			}}})
+		} else {
+			parent := "$" + path[0]
+			merge := func(item string) bson.D {
+				return bson.D{{Key: "$mergeObjects", Value: bson.A{item, bson.D{
+					{Key: opt.As, Value: lookupRefs(item+"."+path[1], opt.ForeignField)},
+				}}}}
+			}
+			pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.D{{Key: path[0], Value: bson.D{{Key: "$switch", Value: bson.D{
+				{Key: "branches", Value: bson.A{
+					bson.D{{Key: "case", Value: bson.D{{Key: "$isArray", Value: parent}}}, {Key: "then", Value: bson.D{{Key: "$map", Value: bson.D{
+						{Key: "input", Value: parent},
+						{Key: "as", Value: "item"},
+						{Key: "in", Value: merge("$$item")},
+					}}}}},
+					bson.D{{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$type", Value: parent}}, "object"}}}}, {Key: "then", Value: merge(parent)}},
+				}},
+				{Key: "default", Value: parent},
+			}}}}}}})
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.D{{Key: populateField, Value: 0}}}})
+	}
+	return pipeline, nil
+}
+
+// lookupRefs is the expression of the documents of the $lookup matching ref: an array when ref is an array, else
+// the first one
+func lookupRefs(ref string, foreignField string) bson.D {
+	filter := func(op string) bson.D {
+		return bson.D{{Key: "$filter", Value: bson.D{
+			{Key: "input", Value: "$" + populateField},
+			{Key: "as", Value: "ref"},
+			{Key: "cond", Value: bson.D{{Key: op, Value: bson.A{"$$ref." + foreignField, ref}}}},
+		}}}
+	}
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$isArray", Value: ref}},
+		filter("$in"),
+		bson.D{{Key: "$arrayElemAt", Value: bson.A{filter("$eq"), 0}}},
+	}}}
+}
+
+// FindPopulatedSync run an aggregation of the documents of collName matching filter, with the stages of
+// PopulatePipeline, so the references are joined on the server. It needs a MongoDB server.
+func FindPopulatedSync[T any](c *Client, collName string, filter interface{}, opts ...PopulateOptions) ReadManyResult[T] {
+	lookup, err := PopulatePipeline(opts...)
+	if err != nil {
+		return ReadManyResult[T]{Err: err}
+	}
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: emptyIfNil(filter)}}}, lookup...)
+
+	op := &Operation{Name: OpAggregate, Collection: collName, Filter: pipeline, retryable: true}
+	var docs []T
+	err = c.run(op, func(ctx context.Context) error {
+		conn, err := c.GetMongoClient()
+		if err != nil {
+			return NewError(MsgGomongoConnectionError, err)
+		}
+		// aggOpts is built again on each attempt, so every attempt carries the comment
+		aggOpts := options.Aggregate()
+		if op.Comment != "" {
+			aggOpts.SetComment(op.Comment)
+		}
+		cur, err := conn.Database(c.database).Collection(collName).Aggregate(ctx, pipeline, aggOpts)
+		if err != nil {
+			return NewError(MsgGomongoCursorError, err)
+		}
+		docs = nil
+		if err := cur.All(ctx, &docs); err != nil {
+			return NewError(MsgGomongoFetchError, err)
+		}
+		op.Returned = int64(len(docs))
+		return nil
+	})
+	if err != nil {
+		return ReadManyResult[T]{Attempts: op.Attempts, Err: err}
+	}
+	return ReadManyResult[T]{Attempts: op.Attempts, Documents: docs}
+}
+
+func (opt PopulateOptions) withDefaults() PopulateOptions {
+	if opt.ForeignField == "" {
+		opt.ForeignField = "_id"
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = DefaultPopulateBatchSize
+	}
+	return opt
+}
+
+// populateRefs return docs as bson and the references they hold
+func populateRefs[T any](docs []T, opt PopulateOptions) ([]bson.Raw, []bson.RawValue, error) {
+	if opt.LocalField == "" || opt.From == "" {
+		return nil, nil, NewError(MsgGomongoPopulateError, errors.New("LocalField and From must be set"))
+	}
+	path := strings.Split(opt.LocalField, ".")
+	raws := make([]bson.Raw, len(docs))
+	var refs []bson.RawValue
+	for i := range docs {
+		raw, err := bson.Marshal(docs[i])
+		if err != nil {
+			return nil, nil, NewError(MsgGomongoPopulateError, err)
+		}
+		raws[i] = raw
+		refs = collectRefs(bson.RawValue{Type: bson.TypeEmbeddedDocument, Value: raw}, path, refs)
+	}
+	return raws, refs, nil
+}
+
+// collectRefs append to refs the values at path in v, following arrays
+func collectRefs(v bson.RawValue, path []string, refs []bson.RawValue) []bson.RawValue {
+	if len(path) == 0 {
+		for _, ref := range flatten(v) {
+			if ref.Type != bson.TypeNull && ref.Type != bson.TypeUndefined {
+				refs = append(refs, ref)
+			}
+		}
+		return refs
+	}
+	if arr, ok := v.ArrayOK(); ok {
+		values, _ := arr.Values()
+		for _, item := range values {
+			refs = collectRefs(item, path, refs)
+		}
+		return refs
+	}
+	if doc, ok := v.DocumentOK(); ok {
+		if next, err := doc.LookupErr(path[0]); err == nil {
+			refs = collectRefs(next, path[1:], refs)
+		}
+	}
+	return refs
+}
+
+// flatten return the values of an array, or v itself
+func flatten(v bson.RawValue) []bson.RawValue {
+	if arr, ok := v.ArrayOK(); ok {
+		values, _ := arr.Values()
+		return values
+	}
+	return []bson.RawValue{v}
+}
+
+// fetchRefs query the documents of refs in batches, and return them by the rawKey of their ForeignField
+func fetchRefs(c *Client, opt PopulateOptions, refs []bson.RawValue) (map[string]bson.Raw, error) {
+	seen := map[string]bool{}
+	var keys bson.A
+	for _, ref := range refs {
+		k := rawKey(ref)
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, ref)
+		}
+	}
+
+	var findOpts []*options.FindOptions
+	if opt.Find != nil {
+		findOpts = append(findOpts, opt.Find)
+	}
+	foreign := strings.Split(opt.ForeignField, ".")
+	found := map[string]bson.Raw{}
+	for start := 0; start < len(keys); start += opt.BatchSize {
+		batch := keys[start:min(start+opt.BatchSize, len(keys))]
+		filter := bson.D{{Key: opt.ForeignField, Value: bson.D{{Key: "$in", Value: batch}}}}
+		res := FindSync[bson.Raw](c, opt.From, filter, findOpts...)
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		for _, raw := range res.Documents {
+			v, err := raw.LookupErr(foreign...)
+			if err != nil {
+				continue
+			}
+			for _, kv := range flatten(v) {
+				found[rawKey(kv)] = raw
+			}
+		}
+	}
+	return found, nil
+}
+
+// setRefs set as next to the reference at path in d, with the documents found for it
+func setRefs(d bson.D, path []string, as string, found map[string]bson.Raw) bson.D {
+	for i, e := range d {
+		if e.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return setField(d, []string{as}, resolveRef(e.Value, found))
+		}
+		switch v := e.Value.(type) {
+		case bson.D:
+			d[i].Value = setRefs(v, path[1:], as, found)
+		case bson.A:
+			for j, item := range v {
+				if sub, ok := item.(bson.D); ok {
+					v[j] = setRefs(sub, path[1:], as, found)
+				}
+			}
+		}
+		return d
+	}
+	return d
+}
+
+// resolveRef return the document of ref, or the documents of an array of references. Missing documents are nil,
+// or left out of the array.
+func resolveRef(ref interface{}, found map[string]bson.Raw) interface{} {
+	if arr, ok := ref.(bson.A); ok {
+		docs := bson.A{}
+		for _, item := range arr {
+			if doc := resolveRef(item, found); doc != nil {
+				docs = append(docs, doc)
+			}
+		}
+		return docs
+	}
+	k, err := loaderKey(ref)
+	if err != nil {
+		return nil
+	}
+	if doc, ok := found[k]; ok {
+		return doc
+	}
+	return nil
+}
+
+// countFound return the number of distinct documents in found
+func countFound(found map[string]bson.Raw) int {
+	docs := map[string]bool{}
+	for _, raw := range found {
+		docs[string(raw)] = true
+	}
+	return len(docs)
+}
+
+// missingRefs return the distinct references with no document
+func missingRefs(refs []bson.RawValue, found map[string]bson.Raw) []interface{} {
+	var ret []interface{}
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		k := rawKey(ref)
+		if _, ok := found[k]; ok || seen[k] {
+			continue
+		}
+		seen[k] = true
+		var v interface{}
+		ref.Unmarshal(&v)
+		ret = append(ret, v)
+	}
+	return ret
+}
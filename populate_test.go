@@ -0,0 +1,92 @@
+package gomongo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sagiforbes/gomongo"
+	"github.com/sagiforbes/gomongo/memdb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type popUser struct {
+	ID   string `bson:"id"`
+	Name string `bson:"name"`
+}
+
+type popAddress struct {
+	UserID string   `bson:"userId"`
+	User   *popUser `bson:"user,omitempty"`
+}
+
+type popItem struct {
+	ProductID int    `bson:"productId"`
+	Product   bson.M `bson:"product,omitempty"`
+}
+
+type popOrder struct {
+	ID      int       `bson:"_id"`
+	Items   []popItem `bson:"items"`
+	TagIDs  []int     `bson:"tagIds"`
+	Tags    []bson.M  `bson:"tags,omitempty"`
+	private string
+}
+
+func TestGomongoPopulate(t *testing.T) {
+	ops := 0
+	c := memdb.NewClient("db").AddHook(countHook{&ops})
+	gomongo.InsertManySync(c, "users", []popUser{{"1", "a"}, {"2", "b"}})
+	gomongo.InsertManySync(c, "products", []bson.M{{"_id": 10, "name": "pen"}, {"_id": 11, "name": "ink"}})
+	gomongo.InsertManySync(c, "tags", []bson.M{{"_id": 1, "tag": "x"}, {"_id": 2, "tag": "y"}})
+
+	addresses := []popAddress{{UserID: "1"}, {UserID: "2"}, {UserID: "3"}, {UserID: "1"}}
+	ops = 0
+	res := gomongo.Populate(c, addresses, gomongo.PopulateOptions{LocalField: "userId", From: "users", ForeignField: "id", As: "user", BatchSize: 1})
+	if res.Err != nil || res.Fetched != 2 || len(res.Missing) != 1 || res.Missing[0] != "3" || ops != 3 {
+		t.Fatalf("unexpected result %+v after %d queries", res, ops)
+	}
+	if addresses[0].User.Name != "a" || addresses[1].User.Name != "b" || addresses[2].User != nil || addresses[3].User.Name != "a" {
+		t.Fatalf("unexpected addresses %+v", addresses)
+	}
+
+	orders := []popOrder{
+		{ID: 1, Items: []popItem{{ProductID: 10}, {ProductID: 11}}, TagIDs: []int{1, 2, 3}, private: "kept"},
+		{ID: 2, Items: []popItem{{ProductID: 11}}},
+	}
+	res = gomongo.Populate(c, orders, gomongo.PopulateOptions{LocalField: "items.productId", From: "products", As: "product"})
+	if res.Err != nil || res.Fetched != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if orders[0].Items[0].Product["name"] != "pen" || orders[0].Items[1].Product["name"] != "ink" || orders[1].Items[0].Product["name"] != "ink" {
+		t.Fatalf("unexpected items %+v", orders)
+	}
+	res = gomongo.Populate(c, orders, gomongo.PopulateOptions{LocalField: "tagIds", From: "tags", As: "tags"})
+	if res.Err != nil || len(orders[0].Tags) != 2 || orders[0].Tags[1]["tag"] != "y" || orders[0].private != "kept" {
+		t.Fatalf("unexpected tags %+v %+v", orders[0], res)
+	}
+
+	users := gomongo.PopulateMap[string, popUser](c, addresses, gomongo.PopulateOptions{LocalField: "userId", From: "users", ForeignField: "id"})
+	if users.Err != nil || len(users.Documents) != 2 || users.Documents["2"].Name != "b" {
+		t.Fatalf("unexpected map %+v", users)
+	}
+}
+
+func TestGomongoPopulatePipeline(t *testing.T) {
+	pipeline, err := gomongo.PopulatePipeline(gomongo.PopulateOptions{LocalField: "items.productId", From: "products", As: "product"})
+	if err != nil || len(pipeline) != 3 {
+		t.Fatalf("expected $lookup, $addFields and $project stages, got %v %v", pipeline, err)
+	}
+	lookup := pipeline[0].Map()["$lookup"].(bson.D).Map()
+	if lookup["from"] != "products" || lookup["localField"] != "items.productId" || lookup["foreignField"] != "_id" {
+		t.Fatalf("unexpected $lookup %v", lookup)
+	}
+	if _, err := gomongo.PopulatePipeline(gomongo.PopulateOptions{LocalField: "a.b.c", From: "x", As: "y"}); err == nil {
+		t.Fatalf("expected deep paths to be rejected")
+	}
+
+	c := memdb.NewClient("db")
+	res := gomongo.FindPopulatedSync[popAddress](c, "address", nil, gomongo.PopulateOptions{LocalField: "userId", From: "users", ForeignField: "id", As: "user"})
+	if !errors.Is(res.Err, gomongo.ErrNotSupported) {
+		t.Fatalf("expected the memdb client not to support aggregations, got %v", res.Err)
+	}
+}
@@ -0,0 +1,242 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultProcessCheckpointEvery is how many documents are processed between calls to ProcessOptions.Checkpoint
+	DefaultProcessCheckpointEvery = 100
+	// processWindow is the number of documents per worker read ahead of the oldest one not processed yet
+	processWindow = 16
+)
+
+// ProcessOptions of ProcessStream
+type ProcessOptions struct {
+	// Ordered call OnDone in the order of the query. Otherwise it is called as the documents are processed.
+	Ordered bool
+	// ContinueOnError process every document and collect the failures, instead of stopping at the first one
+	ContinueOnError bool
+	// OnDone is called from a single goroutine with each document and the error of fn
+	OnDone func(id interface{}, err error)
+	// Checkpoint is called with the _id of the last document such that it and all the documents before it are
+	// processed, every CheckpointEvery documents and at the end. Save it to resume an interrupted scan with
+	// ResumeAfter. A checkpoint error stops the scan.
+	Checkpoint      func(lastID interface{}) error
+	CheckpointEvery int
+	// ResumeAfter skip the documents up to this _id, as given to Checkpoint
+	ResumeAfter interface{}
+	// Find are the options of the query. The scan is sorted by _id when Checkpoint or ResumeAfter is set.
+	Find *options.FindOptions
+}
+
+// ProcessFailure is a document that fn failed to process
+type ProcessFailure struct {
+	ID  interface{}
+	Err error
+}
+
+type ProcessResult struct {
+	// Processed counts the documents fn processed without error
+	Processed int64
+	Failures  []ProcessFailure
+	// LastID is the _id of the last document such that it and all the documents before it are processed. With
+	// ContinueOnError, documents that failed count as processed.
+	LastID interface{}
+	Err    error
+}
+
+type processItem struct {
+	seq int
+	id  interface{}
+	raw bson.Raw
+}
+
+type processDone struct {
+	seq int
+	id  interface{}
+	err error
+	// interrupted is set when fn was canceled by the failure of another document
+	interrupted bool
+}
+
+// ProcessStream run fn on the documents of collName matching filter, workers documents at a time. It stops at the
+// first error unless ProcessOptions.ContinueOnError is set, in which case Err joins the errors of all the failures.
+//
+//	res := gomongo.ProcessStream(ctx, gmc, "users", bson.M{}, 8, func(ctx context.Context, u User) error {
+//		return sendEmail(ctx, u)
+//	}, gomongo.ProcessOptions{ResumeAfter: saved, Checkpoint: save})
+func ProcessStream[T any](ctx context.Context, c *Client, collName string, filter interface{}, workers int, fn func(ctx context.Context, doc T) error, opts ...ProcessOptions) ProcessResult {
+	var opt ProcessOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	if opt.CheckpointEvery <= 0 {
+		opt.CheckpointEvery = DefaultProcessCheckpointEvery
+	}
+
+	findOpts := options.Find()
+	if opt.Find != nil {
+		findOpts = options.MergeFindOptions(opt.Find)
+	}
+	if opt.Checkpoint != nil || opt.ResumeAfter != nil {
+		findOpts.SetSort(bson.D{{Key: "_id", Value: 1}})
+	}
+	if opt.ResumeAfter != nil {
+		after := bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: opt.ResumeAfter}}}}
+		if filter == nil {
+			filter = after
+		} else {
+			filter = bson.D{{Key: "$and", Value: bson.A{filter, after}}}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan processItem)
+	results := make(chan processDone, workers)
+	// window bound the documents read ahead of the oldest one not processed yet
+	window := make(chan struct{}, workers*processWindow)
+
+	var scanErr error
+	go func() {
+		defer close(jobs)
+		seq := 0
+		for raw, err := range FindIter[bson.Raw](ctx, c, collName, filter, findOpts) {
+			if err != nil {
+				if ctx.Err() == nil {
+					scanErr = err
+				}
+				return
+			}
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			var head struct {
+				ID interface{} `bson:"_id"`
+			}
+			bson.Unmarshal(raw, &head)
+			select {
+			case jobs <- processItem{seq: seq, id: head.ID, raw: raw}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// failed is set by the first failure when the scan stops at it, so the workers stop taking documents at once
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				var doc T
+				err := bson.Unmarshal(item.raw, &doc)
+				if err != nil {
+					err = NewError(MsgGomongoUnmarshalError, err)
+				} else {
+					err = fn(ctx, doc)
+				}
+				done := processDone{seq: item.seq, id: item.id, err: err}
+				if err != nil && !opt.ContinueOnError {
+					if failed.CompareAndSwap(false, true) {
+						cancel()
+					} else {
+						done.interrupted = errors.Is(err, context.Canceled)
+					}
+				}
+				results <- done
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var ret ProcessResult
+	var errs []error
+	pending := map[int]processDone{}
+	next, sinceCheckpoint := 0, 0
+	// stopped is set when a failure or a checkpoint error ends the scan, LastID does not move after it
+	stopped, checkpointFailed := false, false
+	checkpoint := func() {
+		if opt.Checkpoint == nil || sinceCheckpoint == 0 || checkpointFailed {
+			return
+		}
+		sinceCheckpoint = 0
+		if err := opt.Checkpoint(ret.LastID); err != nil {
+			errs = append(errs, err)
+			stopped, checkpointFailed = true, true
+			cancel()
+		}
+	}
+
+	for done := range results {
+		switch {
+		case done.interrupted:
+			// fn was interrupted by the first failure
+		case done.err != nil:
+			ret.Failures = append(ret.Failures, ProcessFailure{ID: done.id, Err: done.err})
+			errs = append(errs, done.err)
+		default:
+			ret.Processed++
+		}
+		if opt.OnDone != nil && !opt.Ordered {
+			opt.OnDone(done.id, done.err)
+		}
+
+		// complete the documents that have no document before them left to process, in the order of the query
+		pending[done.seq] = done
+		for {
+			d, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-window
+			if opt.OnDone != nil && opt.Ordered {
+				opt.OnDone(d.id, d.err)
+			}
+			if stopped || (d.err != nil && !opt.ContinueOnError) {
+				stopped = true
+				continue
+			}
+			ret.LastID = d.id
+			sinceCheckpoint++
+			if sinceCheckpoint >= opt.CheckpointEvery {
+				checkpoint()
+			}
+		}
+	}
+	checkpoint()
+
+	if scanErr != nil {
+		errs = append(errs, scanErr)
+	}
+	if len(errs) == 0 && ctx.Err() != nil {
+		// the parent context ended the scan
+		errs = append(errs, ctx.Err())
+	}
+	ret.Err = errors.Join(errs...)
+	return ret
+}
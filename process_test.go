@@ -0,0 +1,94 @@
+package gomongo_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagiforbes/gomongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGomongoProcessStream(t *testing.T) {
+	c := cursorClient(t, 100)
+	ctx := context.Background()
+
+	var sum atomic.Int64
+	var order []interface{}
+	res := gomongo.ProcessStream(ctx, c, "docs", bson.M{}, 4, func(ctx context.Context, doc cursorDoc) error {
+		// later documents finish first
+		time.Sleep(time.Duration(100-doc.ID) * 10 * time.Microsecond)
+		sum.Add(int64(doc.ID))
+		return nil
+	}, gomongo.ProcessOptions{
+		Ordered:    true,
+		Checkpoint: func(interface{}) error { return nil },
+		OnDone:     func(id interface{}, err error) { order = append(order, id) },
+	})
+	if res.Err != nil || res.Processed != 100 || sum.Load() != 4950 {
+		t.Fatalf("expected 100 documents processed, got %+v sum %d", res, sum.Load())
+	}
+	for i, id := range order {
+		if id != int32(i) {
+			t.Fatalf("expected ordered completion, got %v at %d", id, i)
+		}
+	}
+	if res.LastID != int32(99) {
+		t.Fatalf("expected last id 99, got %v", res.LastID)
+	}
+}
+
+func TestGomongoProcessStreamErrors(t *testing.T) {
+	c := cursorClient(t, 100)
+	ctx := context.Background()
+	errOdd := errors.New("odd")
+	failOdd := func(ctx context.Context, doc cursorDoc) error {
+		if doc.ID%2 == 1 {
+			return errOdd
+		}
+		return nil
+	}
+
+	res := gomongo.ProcessStream(ctx, c, "docs", bson.M{"_id": bson.M{"$lt": 10}}, 3, failOdd, gomongo.ProcessOptions{ContinueOnError: true})
+	if res.Processed != 5 || len(res.Failures) != 5 || !errors.Is(res.Err, errOdd) {
+		t.Fatalf("expected 5 failures collected, got %+v", res)
+	}
+
+	var mu sync.Mutex
+	var checkpoints []interface{}
+	res = gomongo.ProcessStream(ctx, c, "docs", bson.M{}, 1, failOdd, gomongo.ProcessOptions{
+		CheckpointEvery: 1,
+		Checkpoint: func(id interface{}) error {
+			mu.Lock()
+			checkpoints = append(checkpoints, id)
+			mu.Unlock()
+			return nil
+		},
+	})
+	if len(res.Failures) != 1 || res.Failures[0].ID != int32(1) || res.LastID != int32(0) || !errors.Is(res.Err, errOdd) {
+		t.Fatalf("expected to stop at the first failure, got %+v", res)
+	}
+	if len(checkpoints) != 1 || checkpoints[0] != int32(0) {
+		t.Fatalf("unexpected checkpoints %v", checkpoints)
+	}
+
+	// resume after the last checkpoint, skipping the failing documents
+	var seen []int
+	res = gomongo.ProcessStream(ctx, c, "docs", bson.M{"_id": bson.M{"$lt": 5}}, 1, func(ctx context.Context, doc cursorDoc) error {
+		seen = append(seen, doc.ID)
+		return nil
+	}, gomongo.ProcessOptions{ResumeAfter: checkpoints[0]})
+	if res.Err != nil || len(seen) != 4 || seen[0] != 1 {
+		t.Fatalf("expected to resume after _id 0, got %v %v", seen, res.Err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	res = gomongo.ProcessStream(cancelled, c, "docs", bson.M{}, 2, failOdd)
+	if !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected the context error, got %v", res.Err)
+	}
+}
@@ -0,0 +1,150 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 5 * time.Second
+	DefaultRetryMultiplier     = 2
+	DefaultRetryJitter         = 0.5
+)
+
+// RetryPolicy tell how many times an operation that failed on a transient error runs again, and how long to wait
+// between attempts. Only reads are retried, unless Writes is set. A write whose documents partly failed is not
+// retried, use RetryFailedModelsSync for those.
+//
+// The attempts share the time of the operation, retrying stops when its context is done.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times an operation runs, including the first. 1 or less disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt. Default is DefaultRetryInitialBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff cap the wait between attempts. Default is DefaultRetryMaxBackoff.
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after each attempt. Default is DefaultRetryMultiplier.
+	Multiplier float64
+	// Jitter is the part of each wait that is random, between 0 and 1. Default is DefaultRetryJitter, a negative
+	// value waits the exact backoff.
+	Jitter float64
+	// Retryable tell if an error is transient. Default is IsRetryable.
+	Retryable func(err error) bool
+	// Writes also retry the writes that can run again without writing twice: inserts whose documents all have an
+	// _id, ReplaceOneSync and DeleteOneSync with a filter on _id, and BulkWriteSync of such models. Other writes,
+	// such as updates, are never retried as an attempt that failed may have been applied.
+	Writes bool
+}
+
+// SetRetryPolicy set the retry policy of the operations of the client
+func (c *Client) SetRetryPolicy(policy RetryPolicy) *Client {
+	c.retry = &policy
+	return c
+}
+
+// WithRetryPolicy returns a copy of the client whose operations use policy, to override the retry policy of a call:
+//
+//	gomongo.FindOneSync[User](gmc.WithRetryPolicy(gomongo.RetryPolicy{MaxAttempts: 5}), "users", filter)
+func (c *Client) WithRetryPolicy(policy RetryPolicy) *Client {
+	ret := *c
+	ret.retry = &policy
+	return &ret
+}
+
+// backoff return the wait after the given attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	initial, maxBackoff, multiplier, jitter := p.InitialBackoff, p.MaxBackoff, p.Multiplier, p.Jitter
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+	if jitter == 0 {
+		jitter = DefaultRetryJitter
+	}
+
+	wait := math.Min(float64(initial)*math.Pow(multiplier, float64(attempt-1)), float64(maxBackoff))
+	if jitter > 0 {
+		wait -= wait * math.Min(jitter, 1) * rand.Float64()
+	}
+	return time.Duration(wait)
+}
+
+// retriesWrites tell if the retry policy of c retries writes, so that the writes must tell if they are idempotent
+func (c *Client) retriesWrites() bool {
+	return c.retry != nil && c.retry.Writes
+}
+
+// retries tell if op can run again under p
+func (p *RetryPolicy) retries(op *Operation) bool {
+	return op.retryable || (p.Writes && op.idempotent)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if partialWrite(err) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// partialWrite tell if err reports documents of a write that failed while others may have been written
+func partialWrite(err error) bool {
+	var bwe mongo.BulkWriteException
+	return errors.As(err, &bwe) && len(bwe.WriteErrors) > 0
+}
+
+// attempt run fn, and again while it fails on a retryable error of a retryable operation. op.Attempts counts the runs.
+func (c *Client) attempt(ctx context.Context, op *Operation, fn func(ctx context.Context) error) error {
+	for op.Attempts = 1; ; op.Attempts++ {
+		err := fn(ctx)
+		if err == nil || c.retry == nil || !c.retry.retries(op) || op.Attempts >= c.retry.MaxAttempts || !c.retry.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		timer := time.NewTimer(c.retry.backoff(op.Attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// idempotentDocs tell if an insert of documents can run again without writing twice: every document has an _id
+func idempotentDocs(documents []interface{}) bool {
+	for _, doc := range documents {
+		if docID(doc) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// idempotentModels tell if a bulk write of models can run again without writing twice: every model inserts a
+// document with an _id, or replaces or deletes one document by _id
+func idempotentModels(models []mongo.WriteModel) bool {
+	for _, model := range models {
+		switch model.(type) {
+		case *mongo.InsertOneModel, *mongo.ReplaceOneModel, *mongo.DeleteOneModel:
+			if modelID(model) == nil {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package gomongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func runFailing(c *Client, op *Operation, failures int, failErr error) (int, error) {
+	calls := 0
+	err := c.run(op, func(ctx context.Context) error {
+		calls++
+		if calls <= failures {
+			return NewError(MsgGomongoFailedFindError, failErr)
+		}
+		return nil
+	})
+	return calls, err
+}
+
+func TestGomongoRetry(t *testing.T) {
+	stepDown := mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}
+	c := NewClient("localhost", "test_retry").SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	op := &Operation{Name: OpFind, Collection: "users", retryable: true}
+	if calls, err := runFailing(c, op, 2, stepDown); err != nil || calls != 3 || op.Attempts != 3 {
+		t.Fatalf("expected success on the third attempt, got %d calls, %d attempts, %v", calls, op.Attempts, err)
+	}
+
+	op = &Operation{Name: OpFind, Collection: "users", retryable: true}
+	if calls, err := runFailing(c, op, 5, stepDown); err == nil || calls != 3 {
+		t.Fatalf("expected to give up after 3 attempts, got %d calls, %v", calls, err)
+	}
+
+	op = &Operation{Name: OpUpdateMany, Collection: "users"}
+	if calls, _ := runFailing(c, op, 1, stepDown); calls != 1 {
+		t.Fatalf("update many should not be retried, got %d calls", calls)
+	}
+
+	op = &Operation{Name: OpFind, Collection: "users", retryable: true}
+	if calls, _ := runFailing(c, op, 1, mongo.CommandError{Code: 2, Name: "BadValue"}); calls != 1 {
+		t.Fatalf("bad value should not be retried, got %d calls", calls)
+	}
+
+	partial := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: 189}}}}
+	op = &Operation{Name: OpBulkWrite, Collection: "users", idempotent: true}
+	if calls, _ := runFailing(c.WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Writes: true}), op, 1, partial); calls != 1 {
+		t.Fatalf("a partial write should not be retried, got %d calls", calls)
+	}
+
+	op = &Operation{Name: OpInsertOne, Collection: "users", idempotent: true}
+	if calls, _ := runFailing(c, op, 1, stepDown); calls != 1 {
+		t.Fatalf("writes should not be retried by default, got %d calls", calls)
+	}
+	op = &Operation{Name: OpInsertOne, Collection: "users", idempotent: true}
+	if calls, err := runFailing(c.WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Writes: true}), op, 1, stepDown); err != nil || calls != 2 {
+		t.Fatalf("expected an idempotent write to be retried with Writes, got %d calls, %v", calls, err)
+	}
+
+	custom := c.WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Jitter: -1, Retryable: func(err error) bool { return errors.Is(err, ErrNotFound) }})
+	op = &Operation{Name: OpFindOne, Collection: "users", retryable: true}
+	if calls, err := runFailing(custom, op, 1, mongo.ErrNoDocuments); err != nil || calls != 2 {
+		t.Fatalf("expected the custom classifier to retry, got %d calls, %v", calls, err)
+	}
+	if c.retry.MaxAttempts != 3 {
+		t.Fatalf("WithRetryPolicy should not change the client")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	op = &Operation{Name: OpFind, Collection: "users", retryable: true}
+	if calls, _ := runFailing(c.WithContext(ctx), op, 5, stepDown); calls != 1 {
+		t.Fatalf("expected no retry once the context is done, got %d calls", calls)
+	}
+}
+
+func TestGomongoRetryIdempotentModels(t *testing.T) {
+	byID := []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(bson.M{"_id": 1}),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": 2}).SetReplacement(bson.M{"name": "b"}),
+		mongo.NewDeleteOneModel().SetFilter(bson.D{{Key: "_id", Value: 3}}),
+	}
+	if !idempotentModels(byID) {
+		t.Errorf("expected writes by _id to be idempotent")
+	}
+	for _, model := range []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(bson.M{"name": "a"}),
+		mongo.NewReplaceOneModel().SetFilter(bson.M{"name": "a"}).SetReplacement(bson.M{"name": "b"}),
+		mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": bson.M{"$in": bson.A{1, 2}}}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": 1}).SetUpdate(bson.M{"$inc": bson.M{"n": 1}}),
+	} {
+		if idempotentModels(append(byID, model)) {
+			t.Errorf("expected %T %v not to be idempotent", model, model)
+		}
+	}
+}
+
+func TestGomongoRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Jitter: -1}
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for i, e := range expected {
+		if got := p.backoff(i + 1); got != e {
+			t.Errorf("attempt %d: expected %s got %s", i+1, e, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Fatalf("jittered backoff %s out of range", got)
+		}
+	}
+}
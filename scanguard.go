@@ -0,0 +1,146 @@
+package gomongo
+
+import (
+	"fmt"
+	"math/rand"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScanGuard makes the client explain queries before running them and report the ones that do not use an index.
+// It is meant for development and CI runs, where a missing index should fail fast. The guard covers
+// FindSync, FindOneSync, CountDocumentsSync, UpdateManySync and DeleteManySync and their async versions.
+type ScanGuard struct {
+	// SampleRate is the fraction of calls that are explained. Zero or one explain every call.
+	SampleRate float64
+	// MaxExaminedRatio is the highest accepted number of documents examined per document returned. Zero turn the check off.
+	MaxExaminedRatio float64
+	// AllowCollections are collections that may be scanned, usually because they are small
+	AllowCollections []string
+	// OnViolation receive the queries that break the guard. The guard panics with the violation when it is nil.
+	OnViolation func(v ScanViolation)
+}
+
+// ScanViolation is a query reported by ScanGuard
+type ScanViolation struct {
+	Operation  string
+	Collection string
+	Filter     interface{}
+	Reason     string
+	Explain    ExplainResult
+}
+
+func (v ScanViolation) Error() string {
+	return fmt.Sprintf("%s on collection %s %s", v.Operation, v.Collection, v.Reason)
+}
+
+// SetScanGuard set the collection scan guard of the client. Pass nil to remove it.
+func (c *Client) SetScanGuard(guard *ScanGuard) *Client {
+	c.scanGuard = guard
+	return c
+}
+
+// guardScan explain the operation when the guard is on and report a violation. explainFn runs the explain with the
+// client it is given, which does not call the hooks, metrics, logger and circuit breaker: the user did not ask for it.
+func (c *Client) guardScan(opName string, collName string, filter interface{}, explainFn func(c *Client) ExplainResult) {
+	g := c.scanGuard
+	if g == nil {
+		return
+	}
+	for _, allowed := range g.AllowCollections {
+		if allowed == collName {
+			return
+		}
+	}
+	if g.SampleRate > 0 && g.SampleRate < 1 && rand.Float64() >= g.SampleRate {
+		return
+	}
+
+	res := explainFn(c.internal())
+	if res.Err != nil {
+		return
+	}
+	reason := g.check(res)
+	if reason == "" {
+		return
+	}
+
+	v := ScanViolation{Operation: opName, Collection: collName, Filter: filter, Reason: reason, Explain: res}
+	if g.OnViolation == nil {
+		panic(v)
+	}
+	g.OnViolation(v)
+}
+
+// internal return a copy of c for the operations the client runs on its own. They are not seen by the hooks, the
+// metrics, the logger, the cache and the circuit breaker.
+func (c *Client) internal() *Client {
+	ret := *c
+	ret.hooks, ret.metrics, ret.logger, ret.cache, ret.breaker, ret.scanGuard = nil, nil, nil, nil, nil, nil
+	return &ret
+}
+
+// check return why res breaks the guard, or an empty string
+func (g *ScanGuard) check(res ExplainResult) string {
+	if res.CollScan {
+		return "scans the whole collection"
+	}
+	if g.MaxExaminedRatio > 0 {
+		returned := res.Returned
+		if returned < 1 {
+			returned = 1
+		}
+		ratio := float64(res.DocsExamined) / float64(returned)
+		if ratio > g.MaxExaminedRatio {
+			return fmt.Sprintf("examines %d documents to return %d", res.DocsExamined, res.Returned)
+		}
+	}
+	return ""
+}
+
+// findOptionsFromFindOne convert the options of FindOneSync so the query can be explained as a find with limit 1
+func findOptionsFromFindOne(opts []*options.FindOneOptions) *options.FindOptions {
+	ret := options.Find().SetLimit(1)
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			ret.Sort = opt.Sort
+		}
+		if opt.Projection != nil {
+			ret.Projection = opt.Projection
+		}
+		if opt.Hint != nil {
+			ret.Hint = opt.Hint
+		}
+		if opt.Skip != nil {
+			ret.Skip = opt.Skip
+		}
+		if opt.Collation != nil {
+			ret.Collation = opt.Collation
+		}
+		if opt.MaxTime != nil {
+			ret.MaxTime = opt.MaxTime
+		}
+	}
+	return ret
+}
+
+// explainDeleteMany explain the plan of DeleteManySync. The documents are not deleted.
+func explainDeleteMany(c *Client, collName string, filter interface{}, verbosity ExplainVerbosity, opts ...*options.DeleteOptions) ExplainResult {
+	del := bson.D{{Key: "q", Value: emptyIfNil(filter)}, {Key: "limit", Value: 0}}
+	fields := map[string]interface{}{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		setIf(fields, "hint", opt.Hint)
+		if opt.Collation != nil {
+			fields["collation"] = collationDoc(opt.Collation)
+		}
+	}
+	cmd := bson.D{{Key: "delete", Value: collName}, {Key: "deletes", Value: bson.A{appendFields(del, fields)}}}
+	return explain(c, collName, filter, cmd, verbosity)
+}
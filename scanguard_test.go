@@ -0,0 +1,71 @@
+package gomongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func testScanGuardCheck(t *testing.T) {
+	g := &ScanGuard{MaxExaminedRatio: 10}
+	if g.check(ExplainResult{CollScan: true}) == "" {
+		t.Errorf("collection scan was not reported")
+	}
+	if g.check(ExplainResult{DocsExamined: 100, Returned: 5}) == "" {
+		t.Errorf("examined ratio was not reported")
+	}
+	if reason := g.check(ExplainResult{DocsExamined: 10, Returned: 5}); reason != "" {
+		t.Errorf("unexpected violation %s", reason)
+	}
+	if reason := g.check(ExplainResult{DocsExamined: 5, Returned: 0}); reason != "" {
+		t.Errorf("unexpected violation when nothing is returned %s", reason)
+	}
+}
+
+func testScanGuardCallback(t *testing.T) {
+	var violations []ScanViolation
+	c := NewClient(HOST, DB_NAME).SetScanGuard(&ScanGuard{
+		AllowCollections: []string{"small"},
+		OnViolation:      func(v ScanViolation) { violations = append(violations, v) },
+	})
+	scan := func(*Client) ExplainResult { return ExplainResult{CollScan: true} }
+
+	c.guardScan(OpFind, "small", bson.M{}, scan)
+	if len(violations) != 0 {
+		t.Errorf("allowed collection was reported")
+	}
+	c.guardScan(OpFind, COLL_NAME_RESTAURANT, bson.M{"cuisine": "x"}, scan)
+	if len(violations) != 1 || violations[0].Collection != COLL_NAME_RESTAURANT || violations[0].Operation != OpFind {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func testScanGuardPanic(t *testing.T) {
+	c := NewClient(HOST, DB_NAME).SetScanGuard(&ScanGuard{})
+	defer func() {
+		if _, ok := recover().(ScanViolation); !ok {
+			t.Errorf("guard did not panic with a violation")
+		}
+	}()
+	c.guardScan(OpCountDocuments, COLL_NAME_RESTAURANT, nil, func(*Client) ExplainResult { return ExplainResult{CollScan: true} })
+}
+
+func testScanGuardInternal(t *testing.T) {
+	var calls []string
+	c := NewClient(HOST, DB_NAME).AddHook(recordHook{"a", &calls}).SetScanGuard(&ScanGuard{OnViolation: func(v ScanViolation) {}})
+	c.guardScan(OpFind, COLL_NAME_RESTAURANT, bson.M{}, func(c *Client) ExplainResult {
+		c.run(&Operation{Name: OpExplain, Collection: COLL_NAME_RESTAURANT}, func(ctx context.Context) error { return nil })
+		return ExplainResult{}
+	})
+	if len(calls) != 0 {
+		t.Errorf("the explain of the guard reached the hooks %v", calls)
+	}
+}
+
+func TestGomongoScanGuardCheck(t *testing.T) {
+	t.Run("check", testScanGuardCheck)
+	t.Run("callback", testScanGuardCallback)
+	t.Run("panic", testScanGuardPanic)
+	t.Run("internal", testScanGuardInternal)
+}
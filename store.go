@@ -0,0 +1,123 @@
+package gomongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Reader holds the read operations on a collection of T documents
+type Reader[T any] interface {
+	FindOne(filter interface{}, opts ...*options.FindOneOptions) ReadOneResult[T]
+	Find(filter interface{}, opts ...*options.FindOptions) ReadManyResult[T]
+	FindStream(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ReadStreamResult[T]
+	Distinct(fieldName string, filter interface{}, opts ...*options.DistinctOptions) DistinctResult[interface{}]
+	CountDocuments(filter interface{}, opts ...*options.CountOptions) CountResult
+}
+
+// Writer holds the write operations on a collection of T documents
+type Writer[T any] interface {
+	InsertOne(document T, opts ...*options.InsertOneOptions) WriteOneResult
+	InsertMany(documents []T, opts ...*options.InsertManyOptions) WriteManyResult
+	UpdateOne(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) UpdateResult
+	UpdateMany(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) UpdateResult
+	ReplaceOne(filter interface{}, document T, opts ...*options.ReplaceOptions) UpdateResult
+	BulkWrite(writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) BulkWriteResult
+	DeleteOne(filter interface{}, opts ...*options.DeleteOptions) DeleteResult
+	DeleteMany(filter interface{}, opts ...*options.DeleteOptions) DeleteResult
+}
+
+// Indexer holds the index operations on a collection
+type Indexer interface {
+	CreateIndex(indexDef interface{}, idxOpt *options.IndexOptions) IndexCreateResult
+	DropIndex(name string, opts ...*options.DropIndexesOptions) IndexDropResult
+	DropAllIndex(opts ...*options.DropIndexesOptions) IndexDropResult
+	ListIndex(opts ...*options.ListIndexesOptions) IndexListResult
+}
+
+// Store is a collection of T documents. Services that take a Store, or only the Reader, Writer or Indexer
+// part they need, can be tested with the mock of the gomongomock package.
+type Store[T any] interface {
+	Reader[T]
+	Writer[T]
+	Indexer
+}
+
+// NewStore return the Store of collection collName. Its methods call the Sync function of the same name on c.
+func NewStore[T any](c *Client, collName string) Store[T] {
+	return &clientStore[T]{c: c, collName: collName}
+}
+
+type clientStore[T any] struct {
+	c        *Client
+	collName string
+}
+
+func (s *clientStore[T]) FindOne(filter interface{}, opts ...*options.FindOneOptions) ReadOneResult[T] {
+	return FindOneSync[T](s.c, s.collName, filter, opts...)
+}
+
+func (s *clientStore[T]) Find(filter interface{}, opts ...*options.FindOptions) ReadManyResult[T] {
+	return FindSync[T](s.c, s.collName, filter, opts...)
+}
+
+func (s *clientStore[T]) FindStream(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ReadStreamResult[T] {
+	return FindStreamSync[T](ctx, s.c, s.collName, filter, opts...)
+}
+
+func (s *clientStore[T]) Distinct(fieldName string, filter interface{}, opts ...*options.DistinctOptions) DistinctResult[interface{}] {
+	return DistinctSync[interface{}](s.c, s.collName, fieldName, filter, opts...)
+}
+
+func (s *clientStore[T]) CountDocuments(filter interface{}, opts ...*options.CountOptions) CountResult {
+	return CountDocumentsSync(s.c, s.collName, filter, opts...)
+}
+
+func (s *clientStore[T]) InsertOne(document T, opts ...*options.InsertOneOptions) WriteOneResult {
+	return InsertOneSync(s.c, s.collName, document, opts...)
+}
+
+func (s *clientStore[T]) InsertMany(documents []T, opts ...*options.InsertManyOptions) WriteManyResult {
+	return InsertManySync(s.c, s.collName, documents, opts...)
+}
+
+func (s *clientStore[T]) UpdateOne(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) UpdateResult {
+	return UpdateOneSync(s.c, s.collName, filter, instruction, opts...)
+}
+
+func (s *clientStore[T]) UpdateMany(filter interface{}, instruction interface{}, opts ...*options.UpdateOptions) UpdateResult {
+	return UpdateManySync(s.c, s.collName, filter, instruction, opts...)
+}
+
+func (s *clientStore[T]) ReplaceOne(filter interface{}, document T, opts ...*options.ReplaceOptions) UpdateResult {
+	return ReplaceOneSync(s.c, s.collName, filter, document, opts...)
+}
+
+func (s *clientStore[T]) BulkWrite(writeModels []mongo.WriteModel, opts ...*options.BulkWriteOptions) BulkWriteResult {
+	return BulkWriteSync(s.c, s.collName, writeModels, opts...)
+}
+
+func (s *clientStore[T]) DeleteOne(filter interface{}, opts ...*options.DeleteOptions) DeleteResult {
+	return DeleteOneSync(s.c, s.collName, filter, opts...)
+}
+
+func (s *clientStore[T]) DeleteMany(filter interface{}, opts ...*options.DeleteOptions) DeleteResult {
+	return DeleteManySync(s.c, s.collName, filter, opts...)
+}
+
+func (s *clientStore[T]) CreateIndex(indexDef interface{}, idxOpt *options.IndexOptions) IndexCreateResult {
+	return CreateIndexSync(s.c, s.collName, indexDef, idxOpt)
+}
+
+func (s *clientStore[T]) DropIndex(name string, opts ...*options.DropIndexesOptions) IndexDropResult {
+	return DropIndexSync(s.c, s.collName, name, opts...)
+}
+
+func (s *clientStore[T]) DropAllIndex(opts ...*options.DropIndexesOptions) IndexDropResult {
+	return DropAllIndexSync(s.c, s.collName, opts...)
+}
+
+func (s *clientStore[T]) ListIndex(opts ...*options.ListIndexesOptions) IndexListResult {
+	return ListIndexSync(s.c, s.collName, opts...)
+}